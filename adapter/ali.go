@@ -2,6 +2,7 @@
 package adapter
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -162,6 +163,12 @@ func (a *AliAdaptor) GetRequestURL(mode string, config *ProviderConfig) (string,
 
 	switch mode {
 	case ModeChat:
+		if isAliNativeChat(config) {
+			if isAliMultimodalModel(config.Model) {
+				return base + "/api/v1/services/aigc/multimodal-generation/generation", nil
+			}
+			return base + "/api/v1/services/aigc/text-generation/generation", nil
+		}
 		return base + "/compatible-mode/v1/chat/completions", nil
 	case ModeVideo:
 		return base + aliVideoEndpointForModel(config.Model), nil
@@ -212,23 +219,144 @@ func (a *AliAdaptor) SetupHeaders(req *http.Request, config *ProviderConfig, mod
 	return nil
 }
 
+// StreamHeaders enables DashScope SSE output for native streaming requests.
+func (a *AliAdaptor) StreamHeaders(config *ProviderConfig) map[string]string {
+	if !isAliNativeChat(config) {
+		return nil
+	}
+	return map[string]string{"X-DashScope-SSE": "enable"}
+}
+
 // PrepareStreamRequest creates a streaming chat request body.
 func (a *AliAdaptor) PrepareStreamRequest(ctx context.Context, config *ProviderConfig, request *dto.ChatRequest) ([]byte, error) {
 	streamRequest := *request
-	if streamRequest.Options == nil {
-		streamRequest.Options = make(map[string]interface{})
-	}
 	streamRequest.Stream = true
-	streamRequest.Options["stream"] = true
+	if !isAliNativeChat(config) {
+		return (&OpenAIAdaptor{}).PrepareStreamRequest(ctx, config, &streamRequest)
+	}
 	return a.ConvertChatRequest(ctx, config, &streamRequest)
 }
 
 // ParseStreamResponse processes a single streaming chunk.
 func (a *AliAdaptor) ParseStreamResponse(chunk []byte) (string, error) {
-	return (&OpenAIAdaptor{}).ParseStreamResponse(chunk)
+	events, err := a.ParseStreamEvent(chunk)
+	if err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	for _, event := range events {
+		if event.Type == dto.StreamEventError {
+			return "", fmt.Errorf("stream error: %s", event.Error)
+		}
+		builder.WriteString(event.Text)
+	}
+	if builder.Len() == 0 {
+		return "", fmt.Errorf("skip token")
+	}
+	return builder.String(), nil
+}
+
+// ParseStreamEvent decodes a streaming chunk into typed events. Both the native
+// DashScope SSE format (with incremental_output) and compatible-mode chunks are accepted.
+func (a *AliAdaptor) ParseStreamEvent(chunk []byte) ([]dto.StreamEvent, error) {
+	trimmed := bytes.TrimSpace(chunk)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
+	var response aliNativeStreamChunk
+	if err := json.Unmarshal(trimmed, &response); err != nil || (response.Output == nil && response.Code == "") {
+		return (&OpenAIAdaptor{}).ParseStreamEvent(trimmed)
+	}
+	if response.Code != "" {
+		return []dto.StreamEvent{{Type: dto.StreamEventError, Error: response.Code + ": " + response.Message, ErrorCode: response.Code}}, nil
+	}
+
+	var events []dto.StreamEvent
+	output := response.Output
+	if output.Text != "" {
+		events = append(events, dto.StreamEvent{Type: dto.StreamEventText, Text: output.Text})
+	}
+	finishReason := output.FinishReason
+	if len(output.Choices) > 0 {
+		choice := output.Choices[0]
+		if text := messageText(choice.Message.Content); text != "" {
+			events = append(events, dto.StreamEvent{Type: dto.StreamEventText, Text: text})
+		}
+		for i, call := range choice.Message.ToolCalls {
+			index := i
+			if call.Index != nil {
+				index = *call.Index
+			}
+			events = append(events, dto.StreamEvent{
+				Type: dto.StreamEventToolCall,
+				ToolCall: &dto.ToolCallDelta{
+					Index:     index,
+					ID:        call.ID,
+					Name:      call.Function.Name,
+					Arguments: call.Function.Arguments,
+				},
+			})
+		}
+		finishReason = choice.FinishReason
+	}
+	// DashScope reports the literal string "null" until the final chunk.
+	if finishReason != "" && finishReason != "null" {
+		events = append(events, dto.StreamEvent{Type: dto.StreamEventFinish, FinishReason: finishReason})
+	}
+	if response.Usage.TotalTokens > 0 || response.Usage.InputTokens > 0 {
+		events = append(events, dto.StreamEvent{
+			Type: dto.StreamEventUsage,
+			Usage: &dto.Usage{
+				PromptTokens:     response.Usage.InputTokens,
+				CompletionTokens: response.Usage.OutputTokens,
+				TotalTokens:      response.Usage.TotalTokens,
+			},
+		})
+	}
+	return withResponseMetadata(events, response.RequestID, "", 0), nil
+}
+
+// aliNativeStreamChunk is a single SSE payload from the native DashScope API.
+type aliNativeStreamChunk struct {
+	Output *struct {
+		Text         string `json:"text"`
+		FinishReason string `json:"finish_reason"`
+		Choices      []struct {
+			Message struct {
+				Content   interface{} `json:"content"`
+				ToolCalls []struct {
+					Index    *int   `json:"index"`
+					ID       string `json:"id"`
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	} `json:"output"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+	RequestID string `json:"request_id"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+}
+
+// aliNativeParameters lists the chat options forwarded to DashScope's native parameters.
+var aliNativeParameters = []string{
+	"temperature", "top_p", "top_k", "max_tokens", "seed", "stop",
+	"presence_penalty", "repetition_penalty", "enable_search",
+	"tools", "parallel_tool_calls", "response_format", "enable_thinking",
 }
 
-// ConvertChatRequest converts a chat request to DashScope format.
+// ConvertChatRequest converts a chat request to the native DashScope text-generation format.
+// The system prompt is sent as a leading system message and results use the message format.
+// Multimodal models (qwen-vl, qwen-audio, qvq, omni) receive DashScope content arrays.
 func (a *AliAdaptor) ConvertChatRequest(ctx context.Context, config *ProviderConfig, request *dto.ChatRequest) ([]byte, error) {
 	payload := struct {
 		Model string `json:"model"`
@@ -239,25 +367,113 @@ func (a *AliAdaptor) ConvertChatRequest(ctx context.Context, config *ProviderCon
 	}{
 		Model: request.Model,
 	}
-	payload.Input.Messages = request.Messages
+	multimodal := isAliMultimodalModel(request.Model)
+	converted := *request
+	converted.Messages = make([]dto.Message, len(request.Messages))
+	for i, msg := range request.Messages {
+		if parts, ok := msg.Content.([]dto.ContentPart); ok {
+			content, err := aliContentParts(parts, multimodal)
+			if err != nil {
+				return nil, err
+			}
+			msg.Content = content
+		}
+		converted.Messages[i] = msg
+	}
+	messages, err := normalizeMessages(&converted)
+	if err != nil {
+		return nil, err
+	}
+	if multimodal {
+		for i, msg := range messages {
+			if text, ok := msg.Content.(string); ok {
+				messages[i].Content = []map[string]interface{}{{"text": text}}
+			}
+		}
+	}
+	payload.Input.Messages = messages
 
+	parameters := map[string]interface{}{"result_format": "message"}
+	if request.Temperature != 0 {
+		parameters["temperature"] = request.Temperature
+	}
+	if request.MaxTokens != 0 {
+		parameters["max_tokens"] = request.MaxTokens
+	}
+	for _, key := range aliNativeParameters {
+		if value, ok := request.Options[key]; ok {
+			parameters[key] = value
+		}
+	}
+	if _, ok := request.Options["tool_choice"]; ok {
+		if choice := openAIToolChoice(request.Options); choice != nil {
+			parameters["tool_choice"] = choice
+		}
+	}
 	if request.Stream {
-		payload.Parameters = map[string]interface{}{"incremental_output": true}
+		parameters["incremental_output"] = true
 	}
+	payload.Parameters = parameters
 
 	return json.Marshal(payload)
 }
 
+// aliContentParts converts multimodal parts into DashScope content arrays. Text-only
+// models receive the joined text and reject media parts.
+func aliContentParts(parts []dto.ContentPart, multimodal bool) (interface{}, error) {
+	if !multimodal {
+		for _, part := range parts {
+			if part.Type != dto.ContentPartText {
+				return nil, fmt.Errorf("dashscope model does not accept %s input; use a multimodal model such as qwen-vl", part.Type)
+			}
+		}
+		return dto.JoinText(parts), nil
+	}
+
+	converted := make([]map[string]interface{}, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case dto.ContentPartText:
+			converted = append(converted, map[string]interface{}{"text": part.Text})
+		case dto.ContentPartImage:
+			converted = append(converted, map[string]interface{}{"image": part.DataURL()})
+		case dto.ContentPartAudio:
+			converted = append(converted, map[string]interface{}{"audio": part.DataURL()})
+		default:
+			return nil, fmt.Errorf("dashscope does not support %s content parts", part.Type)
+		}
+	}
+	return converted, nil
+}
+
+// isAliMultimodalModel reports whether a model is served by the multimodal-generation endpoint.
+func isAliMultimodalModel(model string) bool {
+	model = strings.ToLower(model)
+	for _, marker := range []string{"-vl", "qvq", "qwen-audio", "omni"} {
+		if strings.Contains(model, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func isAliNativeChat(config *ProviderConfig) bool {
+	return config != nil && strings.EqualFold(config.ChatProtocol, ChatProtocolNative)
+}
+
 // ConvertChatResponse converts a DashScope chat response to the standardized format.
 func (a *AliAdaptor) ConvertChatResponse(ctx context.Context, config *ProviderConfig, body []byte) (*dto.ChatResponse, error) {
 	var response struct {
 		Output struct {
-			Text    string `json:"text"`
-			Choices []struct {
-				Message dto.Message `json:"message"`
+			Text         string `json:"text"`
+			FinishReason string `json:"finish_reason"`
+			Choices      []struct {
+				Message      dto.Message `json:"message"`
+				FinishReason string      `json:"finish_reason"`
 			} `json:"choices"`
 		} `json:"output"`
-		Usage struct {
+		RequestID string `json:"request_id"`
+		Usage     struct {
 			InputTokens  int `json:"input_tokens"`
 			OutputTokens int `json:"output_tokens"`
 			TotalTokens  int `json:"total_tokens"`
@@ -274,14 +490,16 @@ func (a *AliAdaptor) ConvertChatResponse(ctx context.Context, config *ProviderCo
 			Code:     http.StatusBadRequest,
 			Message:  response.Message,
 			Provider: config.Name,
+			Type:     response.Code,
 		}
 	}
 
-	chatResponse := &dto.ChatResponse{}
+	chatResponse := &dto.ChatResponse{ID: response.RequestID, Model: config.Model}
 	if len(response.Output.Choices) > 0 {
 		chatResponse.Choices = []dto.ChatChoice{{
-			Index:   0,
-			Message: response.Output.Choices[0].Message,
+			Index:        0,
+			Message:      response.Output.Choices[0].Message,
+			FinishReason: response.Output.Choices[0].FinishReason,
 		}}
 	} else if response.Output.Text != "" {
 		chatResponse.Choices = []dto.ChatChoice{{
@@ -290,6 +508,7 @@ func (a *AliAdaptor) ConvertChatResponse(ctx context.Context, config *ProviderCo
 				Role:    "assistant",
 				Content: response.Output.Text,
 			},
+			FinishReason: response.Output.FinishReason,
 		}}
 	}
 	chatResponse.Usage = dto.Usage{
@@ -422,6 +641,7 @@ func (a *AliAdaptor) ConvertMediaResponse(ctx context.Context, config *ProviderC
 				Code:     http.StatusBadRequest,
 				Message:  response.Message,
 				Provider: config.Name,
+				Type:     response.Code,
 			}
 		}
 
@@ -440,6 +660,9 @@ func (a *AliAdaptor) ConvertMediaResponse(ctx context.Context, config *ProviderC
 		if result.URL == "" && len(result.Data) > 0 {
 			result.URL = result.Data[0].URL
 		}
+		if response.Usage.ImageCount > 0 {
+			result.Usage = &dto.MediaUsage{ImageCount: response.Usage.ImageCount}
+		}
 		return result, nil
 	}
 	if mode != ModeVideo {
@@ -465,6 +688,7 @@ func (a *AliAdaptor) ConvertMediaResponse(ctx context.Context, config *ProviderC
 			Code:     http.StatusBadRequest,
 			Message:  response.Message,
 			Provider: config.Name,
+			Type:     response.Code,
 		}
 	}
 
@@ -511,6 +735,7 @@ func (a *AliAdaptor) ConvertTaskStatusResponse(ctx context.Context, config *Prov
 		Usage struct {
 			VideoDuration int `json:"video_duration"`
 			VideoCount    int `json:"video_count"`
+			ImageCount    int `json:"image_count"`
 			SR            int `json:"SR"`
 		} `json:"usage"`
 		Code    string `json:"code"`
@@ -525,6 +750,7 @@ func (a *AliAdaptor) ConvertTaskStatusResponse(ctx context.Context, config *Prov
 			Code:     http.StatusBadRequest,
 			Message:  response.Message,
 			Provider: config.Name,
+			Type:     response.Code,
 		}
 	}
 
@@ -544,10 +770,11 @@ func (a *AliAdaptor) ConvertTaskStatusResponse(ctx context.Context, config *Prov
 		},
 	}
 
-	if response.Usage.VideoDuration != 0 || response.Usage.VideoCount != 0 || response.Usage.SR != 0 {
+	if response.Usage.VideoDuration != 0 || response.Usage.VideoCount != 0 || response.Usage.ImageCount != 0 || response.Usage.SR != 0 {
 		result.Usage = &dto.TaskStatusUsage{
 			VideoDuration: response.Usage.VideoDuration,
 			VideoCount:    response.Usage.VideoCount,
+			ImageCount:    response.Usage.ImageCount,
 			SR:            response.Usage.SR,
 		}
 	}
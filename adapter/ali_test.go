@@ -0,0 +1,97 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/YspCoder/omnigo/dto"
+)
+
+func TestAliNativeChatRequestCarriesOptions(t *testing.T) {
+	config := &ProviderConfig{ChatProtocol: ChatProtocolNative}
+	request := &dto.ChatRequest{
+		Model:    "qwen-plus",
+		Messages: []dto.Message{{Role: "user", Content: "hi"}},
+		Options: map[string]interface{}{
+			"system_prompt":     "be brief",
+			"temperature":       0.3,
+			"top_p":             0.8,
+			"max_tokens":        256,
+			"seed":              7,
+			"frequency_penalty": 0.5,
+			"tool_choice":       "required",
+		},
+	}
+
+	body, err := (&AliAdaptor{}).PrepareStreamRequest(context.Background(), config, request)
+	if err != nil {
+		t.Fatalf("PrepareStreamRequest returned error: %v", err)
+	}
+
+	var payload struct {
+		Input struct {
+			Messages []dto.Message `json:"messages"`
+		} `json:"input"`
+		Parameters map[string]interface{} `json:"parameters"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+
+	if len(payload.Input.Messages) != 2 || payload.Input.Messages[0].Role != "system" || payload.Input.Messages[0].Content != "be brief" {
+		t.Fatalf("expected leading system message, got %+v", payload.Input.Messages)
+	}
+	params := payload.Parameters
+	if params["temperature"] != 0.3 || params["top_p"] != 0.8 || params["max_tokens"] != float64(256) || params["seed"] != float64(7) {
+		t.Fatalf("unexpected sampling parameters: %+v", params)
+	}
+	if params["incremental_output"] != true || params["result_format"] != "message" || params["tool_choice"] != "required" {
+		t.Fatalf("unexpected stream parameters: %+v", params)
+	}
+	if _, ok := params["frequency_penalty"]; ok {
+		t.Fatalf("unsupported option should not be forwarded: %+v", params)
+	}
+
+	url, err := (&AliAdaptor{}).GetRequestURL(ModeChat, config)
+	if err != nil || url != "https://dashscope.aliyuncs.com/api/v1/services/aigc/text-generation/generation" {
+		t.Fatalf("unexpected native URL %q (%v)", url, err)
+	}
+	if headers := (&AliAdaptor{}).StreamHeaders(config); headers["X-DashScope-SSE"] != "enable" {
+		t.Fatalf("expected SSE header, got %+v", headers)
+	}
+	if headers := (&AliAdaptor{}).StreamHeaders(&ProviderConfig{}); len(headers) != 0 {
+		t.Fatalf("compatible mode should not add headers, got %+v", headers)
+	}
+}
+
+func TestAliParseNativeStreamEvent(t *testing.T) {
+	adaptor := &AliAdaptor{}
+
+	events, err := adaptor.ParseStreamEvent([]byte(`{"output":{"choices":[{"message":{"role":"assistant","content":"Hel"},"finish_reason":"null"}]},"usage":{"input_tokens":5,"output_tokens":1,"total_tokens":6},"request_id":"r1"}`))
+	if err != nil {
+		t.Fatalf("ParseStreamEvent returned error: %v", err)
+	}
+	if len(events) != 2 || events[0].Text != "Hel" || events[1].Type != dto.StreamEventUsage {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+
+	events, err = adaptor.ParseStreamEvent([]byte(`{"output":{"choices":[{"message":{"role":"assistant","content":"","tool_calls":[{"index":0,"id":"call_1","function":{"name":"lookup","arguments":"{}"}}]},"finish_reason":"tool_calls"}]}}`))
+	if err != nil {
+		t.Fatalf("ParseStreamEvent returned error: %v", err)
+	}
+	if len(events) != 2 || events[0].ToolCall == nil || events[0].ToolCall.Name != "lookup" || events[1].FinishReason != "tool_calls" {
+		t.Fatalf("unexpected tool events: %+v", events)
+	}
+
+	events, err = adaptor.ParseStreamEvent([]byte(`{"code":"InvalidParameter","message":"bad input","request_id":"r2"}`))
+	if err != nil || len(events) != 1 || events[0].Type != dto.StreamEventError {
+		t.Fatalf("expected error event, got %+v (%v)", events, err)
+	}
+
+	// Compatible-mode chunks still use the OpenAI format.
+	events, err = adaptor.ParseStreamEvent([]byte(`{"choices":[{"index":0,"delta":{"content":"hi"}}]}`))
+	if err != nil || len(events) != 1 || events[0].Text != "hi" {
+		t.Fatalf("unexpected compatible-mode events: %+v (%v)", events, err)
+	}
+}
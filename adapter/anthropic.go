@@ -14,8 +14,35 @@ import (
 )
 
 type anthropicMessageContent struct {
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text,omitempty"`
+	Source       *anthropicSource       `json:"source,omitempty"`
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+	ID           string                 `json:"id,omitempty"`
+	Name         string                 `json:"name,omitempty"`
+	Input        json.RawMessage        `json:"input,omitempty"`
+	ToolUseID    string                 `json:"tool_use_id,omitempty"`
+	Content      string                 `json:"content,omitempty"`
+}
+
+// anthropicCacheControl marks a prompt caching breakpoint on a block.
+type anthropicCacheControl struct {
 	Type string `json:"type"`
-	Text string `json:"text,omitempty"`
+}
+
+// anthropicSystemBlock is a system prompt block, used when caching is requested.
+type anthropicSystemBlock struct {
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text"`
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+// anthropicSource is the source of an image or document block.
+type anthropicSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
 }
 
 type anthropicMessage struct {
@@ -23,35 +50,57 @@ type anthropicMessage struct {
 	Content []anthropicMessageContent `json:"content"`
 }
 
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
 type anthropicRequest struct {
-	Model       string             `json:"model"`
-	Messages    []anthropicMessage `json:"messages"`
-	System      string             `json:"system,omitempty"`
-	MaxTokens   int                `json:"max_tokens"`
-	Temperature float64            `json:"temperature,omitempty"`
-	Stream      bool               `json:"stream,omitempty"`
+	Model       string                 `json:"model"`
+	Messages    []anthropicMessage     `json:"messages"`
+	System      interface{}            `json:"system,omitempty"`
+	MaxTokens   int                    `json:"max_tokens"`
+	Temperature float64                `json:"temperature,omitempty"`
+	Stream      bool                   `json:"stream,omitempty"`
+	Tools       []anthropicTool        `json:"tools,omitempty"`
+	ToolChoice  map[string]interface{} `json:"tool_choice,omitempty"`
 }
 
 type anthropicResponse struct {
-	ID         string `json:"id"`
-	Type       string `json:"type"`
-	Role       string `json:"role"`
-	Model      string `json:"model"`
-	Content    []struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
-	} `json:"content"`
-	StopReason string `json:"stop_reason"`
-	Usage      struct {
-		InputTokens  int `json:"input_tokens"`
-		OutputTokens int `json:"output_tokens"`
-	} `json:"usage"`
-	Error *struct {
+	ID         string                    `json:"id"`
+	Type       string                    `json:"type"`
+	Role       string                    `json:"role"`
+	Model      string                    `json:"model"`
+	Content    []anthropicMessageContent `json:"content"`
+	StopReason string                    `json:"stop_reason"`
+	Usage      anthropicUsage            `json:"usage"`
+	Error      *struct {
 		Type    string `json:"type"`
 		Message string `json:"message"`
 	} `json:"error,omitempty"`
 }
 
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// toDTO converts Anthropic usage. Anthropic reports cached prompt tokens separately
+// from input_tokens, so they are added back to PromptTokens to match other providers.
+func (u anthropicUsage) toDTO() dto.Usage {
+	prompt := u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+	return dto.Usage{
+		PromptTokens:        prompt,
+		CompletionTokens:    u.OutputTokens,
+		TotalTokens:         prompt + u.OutputTokens,
+		CachedTokens:        u.CacheReadInputTokens,
+		CacheCreationTokens: u.CacheCreationInputTokens,
+	}
+}
+
 // AnthropicAdaptor converts requests and responses for Anthropic APIs.
 type AnthropicAdaptor struct {
 	BaseURL string
@@ -118,32 +167,76 @@ func (a *AnthropicAdaptor) ConvertChatRequest(ctx context.Context, config *Provi
 		Messages: make([]anthropicMessage, 0, len(messages)),
 	}
 
-	systemParts := make([]string, 0, 2)
+	systemParts := make([]anthropicSystemBlock, 0, 2)
 	if systemPrompt, ok := request.Options["system_prompt"].(string); ok && systemPrompt != "" {
-		systemParts = append(systemParts, systemPrompt)
+		cacheType, _ := request.Options["system_cache_type"].(string)
+		systemParts = append(systemParts, anthropicSystemBlock{Type: "text", Text: systemPrompt, CacheControl: anthropicCache(cacheType)})
 	}
 
 	for _, msg := range messages {
-		content := strings.TrimSpace(fmt.Sprint(msg.Content))
-		if content == "" {
-			continue
-		}
+		content := strings.TrimSpace(messageText(msg.Content))
 		role := strings.ToLower(msg.Role)
 		switch role {
-		case "assistant", "user":
+		case "system":
+			if content != "" {
+				systemParts = append(systemParts, anthropicSystemBlock{Type: "text", Text: content, CacheControl: anthropicCache(msg.CacheType)})
+			}
+		case "tool":
+			// Consecutive tool results must be sent back in a single user turn.
+			block := anthropicMessageContent{
+				Type:         "tool_result",
+				ToolUseID:    msg.ToolCallID,
+				Content:      content,
+				CacheControl: anthropicCache(msg.CacheType),
+			}
+			if last := len(payload.Messages) - 1; last >= 0 && payload.Messages[last].Role == "user" && isAnthropicToolResult(payload.Messages[last]) {
+				payload.Messages[last].Content = append(payload.Messages[last].Content, block)
+				continue
+			}
 			payload.Messages = append(payload.Messages, anthropicMessage{
-				Role: role,
-				Content: []anthropicMessageContent{
-					{Type: "text", Text: content},
-				},
+				Role:    "user",
+				Content: []anthropicMessageContent{block},
+			})
+		case "assistant":
+			blocks := make([]anthropicMessageContent, 0, len(msg.ToolCalls)+1)
+			if content != "" {
+				blocks = append(blocks, anthropicMessageContent{Type: "text", Text: content})
+			}
+			for _, call := range msg.ToolCalls {
+				blocks = append(blocks, anthropicMessageContent{
+					Type:  "tool_use",
+					ID:    call.ID,
+					Name:  call.Function.Name,
+					Input: toolArguments(call.Function.Arguments),
+				})
+			}
+			if len(blocks) == 0 {
+				continue
+			}
+			blocks[len(blocks)-1].CacheControl = anthropicCache(msg.CacheType)
+			payload.Messages = append(payload.Messages, anthropicMessage{
+				Role:    role,
+				Content: blocks,
 			})
-		case "system":
-			systemParts = append(systemParts, content)
 		default:
+			if parts, ok := msg.Content.([]dto.ContentPart); ok {
+				blocks, err := anthropicContentBlocks(parts)
+				if err != nil {
+					return nil, err
+				}
+				if len(blocks) > 0 {
+					blocks[len(blocks)-1].CacheControl = anthropicCache(msg.CacheType)
+					payload.Messages = append(payload.Messages, anthropicMessage{Role: "user", Content: blocks})
+				}
+				continue
+			}
+			if content == "" {
+				continue
+			}
 			payload.Messages = append(payload.Messages, anthropicMessage{
 				Role: "user",
 				Content: []anthropicMessageContent{
-					{Type: "text", Text: content},
+					{Type: "text", Text: content, CacheControl: anthropicCache(msg.CacheType)},
 				},
 			})
 		}
@@ -154,7 +247,7 @@ func (a *AnthropicAdaptor) ConvertChatRequest(ctx context.Context, config *Provi
 	}
 
 	if len(systemParts) > 0 {
-		payload.System = strings.Join(systemParts, "\n\n")
+		payload.System = anthropicSystem(systemParts)
 	}
 
 	if request.MaxTokens > 0 {
@@ -181,9 +274,42 @@ func (a *AnthropicAdaptor) ConvertChatRequest(ctx context.Context, config *Provi
 		payload.Stream = true
 	}
 
+	for _, tool := range extractTools(request.Options) {
+		schema := tool.Function.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		payload.Tools = append(payload.Tools, anthropicTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: schema,
+		})
+	}
+	if len(payload.Tools) > 0 {
+		switch mode, name := normalizeToolChoice(request.Options); mode {
+		case toolChoiceAuto:
+			payload.ToolChoice = map[string]interface{}{"type": "auto"}
+		case toolChoiceNone:
+			payload.ToolChoice = map[string]interface{}{"type": "none"}
+		case toolChoiceRequired:
+			payload.ToolChoice = map[string]interface{}{"type": "any"}
+		case toolChoiceFunction:
+			payload.ToolChoice = map[string]interface{}{"type": "tool", "name": name}
+		}
+	}
+
 	return json.Marshal(payload)
 }
 
+func isAnthropicToolResult(msg anthropicMessage) bool {
+	for _, block := range msg.Content {
+		if block.Type != "tool_result" {
+			return false
+		}
+	}
+	return len(msg.Content) > 0
+}
+
 // ConvertChatResponse unmarshals the Anthropic chat response.
 func (a *AnthropicAdaptor) ConvertChatResponse(ctx context.Context, config *ProviderConfig, body []byte) (*dto.ChatResponse, error) {
 	_ = ctx
@@ -198,13 +324,27 @@ func (a *AnthropicAdaptor) ConvertChatResponse(ctx context.Context, config *Prov
 			Code:     http.StatusBadRequest,
 			Message:  response.Error.Message,
 			Provider: config.Name,
+			Type:     response.Error.Type,
 		}
 	}
 
 	textParts := make([]string, 0, len(response.Content))
+	var toolCalls []dto.ToolCall
 	for _, block := range response.Content {
-		if block.Type == "text" && block.Text != "" {
-			textParts = append(textParts, block.Text)
+		switch block.Type {
+		case "text":
+			if block.Text != "" {
+				textParts = append(textParts, block.Text)
+			}
+		case "tool_use":
+			toolCalls = append(toolCalls, dto.ToolCall{
+				ID:   block.ID,
+				Type: "function",
+				Function: dto.ToolCallFunction{
+					Name:      block.Name,
+					Arguments: string(toolArguments(string(block.Input))),
+				},
+			})
 		}
 	}
 
@@ -215,14 +355,10 @@ func (a *AnthropicAdaptor) ConvertChatResponse(ctx context.Context, config *Prov
 		Model:  response.Model,
 		Choices: []dto.ChatChoice{{
 			Index:        0,
-			Message:      dto.Message{Role: "assistant", Content: content},
+			Message:      dto.Message{Role: "assistant", Content: content, ToolCalls: toolCalls},
 			FinishReason: response.StopReason,
 		}},
-		Usage: dto.Usage{
-			PromptTokens:     response.Usage.InputTokens,
-			CompletionTokens: response.Usage.OutputTokens,
-			TotalTokens:      response.Usage.InputTokens + response.Usage.OutputTokens,
-		},
+		Usage: response.Usage.toDTO(),
 	}
 	return chatResp, nil
 }
@@ -299,3 +435,152 @@ func (a *AnthropicAdaptor) ParseStreamResponse(chunk []byte) (string, error) {
 		return "", fmt.Errorf("skip token")
 	}
 }
+
+// anthropicSystem returns the system prompt as a plain string, or as blocks when any
+// part requests caching so the cache_control breakpoints are preserved.
+func anthropicSystem(parts []anthropicSystemBlock) interface{} {
+	texts := make([]string, 0, len(parts))
+	cached := false
+	for _, part := range parts {
+		texts = append(texts, part.Text)
+		cached = cached || part.CacheControl != nil
+	}
+	if cached {
+		return parts
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// anthropicCache returns the cache_control marker for a cache type, or nil.
+func anthropicCache(cacheType string) *anthropicCacheControl {
+	if cacheType == "" {
+		return nil
+	}
+	return &anthropicCacheControl{Type: cacheType}
+}
+
+// anthropicContentBlocks converts multimodal parts into Anthropic content blocks.
+// Images map to image blocks and files (PDF) to document blocks.
+func anthropicContentBlocks(parts []dto.ContentPart) ([]anthropicMessageContent, error) {
+	blocks := make([]anthropicMessageContent, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case dto.ContentPartText:
+			if strings.TrimSpace(part.Text) != "" {
+				blocks = append(blocks, anthropicMessageContent{Type: "text", Text: part.Text})
+			}
+		case dto.ContentPartImage:
+			blocks = append(blocks, anthropicMessageContent{Type: "image", Source: anthropicMediaSource(part)})
+		case dto.ContentPartFile:
+			blocks = append(blocks, anthropicMessageContent{Type: "document", Source: anthropicMediaSource(part)})
+		default:
+			return nil, fmt.Errorf("anthropic does not support %s content parts", part.Type)
+		}
+	}
+	return blocks, nil
+}
+
+func anthropicMediaSource(part dto.ContentPart) *anthropicSource {
+	if part.IsInline() {
+		return &anthropicSource{Type: "base64", MediaType: part.MIMEType, Data: part.Data}
+	}
+	return &anthropicSource{Type: "url", URL: part.URL}
+}
+
+// ParseStreamEvent decodes a single Anthropic streaming event into typed events.
+// Tool-use blocks are reported as tool-call deltas keyed by their content block index,
+// with input_json_delta fragments carrying the arguments.
+func (a *AnthropicAdaptor) ParseStreamEvent(chunk []byte) ([]dto.StreamEvent, error) {
+	if len(strings.TrimSpace(string(chunk))) == 0 {
+		return nil, nil
+	}
+
+	var event struct {
+		Type    string `json:"type"`
+		Index   int    `json:"index"`
+		Message struct {
+			ID    string         `json:"id"`
+			Model string         `json:"model"`
+			Usage anthropicUsage `json:"usage"`
+		} `json:"message"`
+		Delta struct {
+			Type        string `json:"type"`
+			Text        string `json:"text"`
+			PartialJSON string `json:"partial_json"`
+			StopReason  string `json:"stop_reason"`
+		} `json:"delta"`
+		ContentBlock struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"content_block"`
+		Usage struct {
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+		Error *struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error,omitempty"`
+	}
+	if err := json.Unmarshal(chunk, &event); err != nil {
+		return nil, fmt.Errorf("malformed response: %w", err)
+	}
+
+	if event.Error != nil && event.Error.Message != "" {
+		return []dto.StreamEvent{{Type: dto.StreamEventError, Error: event.Error.Message, ErrorCode: event.Error.Type}}, nil
+	}
+
+	switch event.Type {
+	case "message_start":
+		var events []dto.StreamEvent
+		if usage := event.Message.Usage.toDTO(); usage.PromptTokens > 0 || usage.CompletionTokens > 0 {
+			events = append(events, dto.StreamEvent{Type: dto.StreamEventUsage, Usage: &usage})
+		}
+		return withResponseMetadata(events, event.Message.ID, event.Message.Model, 0), nil
+	case "content_block_start":
+		switch event.ContentBlock.Type {
+		case "text":
+			if event.ContentBlock.Text != "" {
+				return []dto.StreamEvent{{Type: dto.StreamEventText, Text: event.ContentBlock.Text}}, nil
+			}
+		case "tool_use":
+			return []dto.StreamEvent{{
+				Type: dto.StreamEventToolCall,
+				ToolCall: &dto.ToolCallDelta{
+					Index: event.Index,
+					ID:    event.ContentBlock.ID,
+					Name:  event.ContentBlock.Name,
+				},
+			}}, nil
+		}
+		return nil, nil
+	case "content_block_delta":
+		switch event.Delta.Type {
+		case "text_delta":
+			return []dto.StreamEvent{{Type: dto.StreamEventText, Text: event.Delta.Text}}, nil
+		case "input_json_delta":
+			return []dto.StreamEvent{{
+				Type:     dto.StreamEventToolCall,
+				ToolCall: &dto.ToolCallDelta{Index: event.Index, Arguments: event.Delta.PartialJSON},
+			}}, nil
+		}
+		return nil, nil
+	case "message_delta":
+		var events []dto.StreamEvent
+		if event.Delta.StopReason != "" {
+			events = append(events, dto.StreamEvent{Type: dto.StreamEventFinish, FinishReason: event.Delta.StopReason})
+		}
+		if event.Usage.OutputTokens > 0 {
+			events = append(events, dto.StreamEvent{
+				Type:  dto.StreamEventUsage,
+				Usage: &dto.Usage{CompletionTokens: event.Usage.OutputTokens},
+			})
+		}
+		return events, nil
+	case "message_stop":
+		return nil, io.EOF
+	default:
+		return nil, nil
+	}
+}
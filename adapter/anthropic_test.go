@@ -0,0 +1,130 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/YspCoder/omnigo/dto"
+	"github.com/YspCoder/omnigo/utils"
+)
+
+func TestAnthropicConvertChatRequestToolRoundTrip(t *testing.T) {
+	request := &dto.ChatRequest{
+		Model: "claude-3-5-sonnet",
+		Messages: []dto.Message{
+			{Role: "user", Content: "weather in Paris and Rome?"},
+			{Role: "assistant", ToolCalls: []dto.ToolCall{
+				{ID: "toolu_1", Type: "function", Function: dto.ToolCallFunction{Name: "weather", Arguments: `{"city":"Paris"}`}},
+				{ID: "toolu_2", Type: "function", Function: dto.ToolCallFunction{Name: "weather", Arguments: `{"city":"Rome"}`}},
+			}},
+			{Role: "tool", ToolCallID: "toolu_1", Content: "sunny"},
+			{Role: "tool", ToolCallID: "toolu_2", Content: "rainy"},
+		},
+		Options: map[string]interface{}{
+			"tools": []utils.Tool{{
+				Type:     "function",
+				Function: utils.Function{Name: "weather", Parameters: map[string]interface{}{"type": "object"}},
+			}},
+			"tool_choice": map[string]interface{}{"type": "required"},
+		},
+	}
+
+	body, err := (&AnthropicAdaptor{}).ConvertChatRequest(context.Background(), &ProviderConfig{}, request)
+	if err != nil {
+		t.Fatalf("ConvertChatRequest returned error: %v", err)
+	}
+
+	var payload anthropicRequest
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+
+	if len(payload.Messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(payload.Messages))
+	}
+	if got := payload.Messages[1].Content[1]; got.Type != "tool_use" || got.ID != "toolu_2" || string(got.Input) != `{"city":"Rome"}` {
+		t.Fatalf("unexpected tool_use block: %+v", got)
+	}
+	results := payload.Messages[2]
+	if results.Role != "user" || len(results.Content) != 2 || results.Content[1].ToolUseID != "toolu_2" {
+		t.Fatalf("expected tool results merged into one user turn, got %+v", results)
+	}
+	if len(payload.Tools) != 1 || payload.ToolChoice["type"] != "any" {
+		t.Fatalf("unexpected tools %+v / tool_choice %+v", payload.Tools, payload.ToolChoice)
+	}
+}
+
+func TestAnthropicConvertChatResponseToolUse(t *testing.T) {
+	body := []byte(`{
+		"id": "msg_1",
+		"type": "message",
+		"model": "claude-3-5-sonnet",
+		"content": [
+			{"type": "text", "text": "Checking."},
+			{"type": "tool_use", "id": "toolu_1", "name": "weather", "input": {"city": "Paris"}}
+		],
+		"stop_reason": "tool_use"
+	}`)
+
+	resp, err := (&AnthropicAdaptor{}).ConvertChatResponse(context.Background(), &ProviderConfig{}, body)
+	if err != nil {
+		t.Fatalf("ConvertChatResponse returned error: %v", err)
+	}
+
+	calls := resp.Choices[0].Message.ToolCalls
+	if len(calls) != 1 || calls[0].ID != "toolu_1" || calls[0].Function.Name != "weather" {
+		t.Fatalf("unexpected tool calls: %+v", calls)
+	}
+	if calls[0].Function.Arguments != `{"city": "Paris"}` {
+		t.Fatalf("unexpected arguments: %s", calls[0].Function.Arguments)
+	}
+}
+
+func TestAnthropicPromptCaching(t *testing.T) {
+	request := &dto.ChatRequest{
+		Model: "claude-3-5-sonnet",
+		Messages: []dto.Message{
+			{Role: "user", Content: "long document", CacheType: "ephemeral"},
+			{Role: "user", Content: "summarize it"},
+		},
+		Options: map[string]interface{}{
+			"system_prompt":     "You are terse.",
+			"system_cache_type": "ephemeral",
+		},
+	}
+
+	body, err := (&AnthropicAdaptor{}).ConvertChatRequest(context.Background(), &ProviderConfig{}, request)
+	if err != nil {
+		t.Fatalf("ConvertChatRequest returned error: %v", err)
+	}
+	var payload struct {
+		System   []anthropicSystemBlock `json:"system"`
+		Messages []anthropicMessage     `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if len(payload.System) != 1 || payload.System[0].CacheControl == nil || payload.System[0].CacheControl.Type != "ephemeral" {
+		t.Fatalf("expected cached system block, got %+v", payload.System)
+	}
+	if len(payload.Messages) != 2 || payload.Messages[0].Content[0].CacheControl == nil || payload.Messages[1].Content[0].CacheControl != nil {
+		t.Fatalf("expected cache_control on the first message only, got %s", body)
+	}
+
+	response := []byte(`{
+		"id": "msg_1",
+		"type": "message",
+		"content": [{"type": "text", "text": "ok"}],
+		"stop_reason": "end_turn",
+		"usage": {"input_tokens": 10, "cache_creation_input_tokens": 100, "cache_read_input_tokens": 900, "output_tokens": 5}
+	}`)
+	resp, err := (&AnthropicAdaptor{}).ConvertChatResponse(context.Background(), &ProviderConfig{}, response)
+	if err != nil {
+		t.Fatalf("ConvertChatResponse returned error: %v", err)
+	}
+	want := dto.Usage{PromptTokens: 1010, CompletionTokens: 5, TotalTokens: 1015, CachedTokens: 900, CacheCreationTokens: 100}
+	if resp.Usage != want {
+		t.Fatalf("expected usage %+v, got %+v", want, resp.Usage)
+	}
+}
@@ -0,0 +1,75 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/YspCoder/omnigo/dto"
+)
+
+func multimodalRequest(model string) *dto.ChatRequest {
+	return &dto.ChatRequest{
+		Model: model,
+		Messages: []dto.Message{{
+			Role: "user",
+			Content: []dto.ContentPart{
+				{Type: dto.ContentPartText, Text: "describe"},
+				{Type: dto.ContentPartImage, URL: "https://example.com/cat.png"},
+				{Type: dto.ContentPartImage, MIMEType: "image/jpeg", Data: "QUJD"},
+			},
+		}},
+	}
+}
+
+func TestMultimodalContentMapping(t *testing.T) {
+	ctx := context.Background()
+
+	body, err := (&OpenAIAdaptor{}).ConvertChatRequest(ctx, &ProviderConfig{}, multimodalRequest("gpt-4o"))
+	if err != nil {
+		t.Fatalf("openai conversion failed: %v", err)
+	}
+	if !strings.Contains(string(body), `{"image_url":{"url":"https://example.com/cat.png"},"type":"image_url"}`) ||
+		!strings.Contains(string(body), `"url":"data:image/jpeg;base64,QUJD"`) {
+		t.Fatalf("unexpected openai payload: %s", body)
+	}
+
+	body, err = (&AnthropicAdaptor{}).ConvertChatRequest(ctx, &ProviderConfig{}, multimodalRequest("claude-3-5-sonnet"))
+	if err != nil {
+		t.Fatalf("anthropic conversion failed: %v", err)
+	}
+	var anthropicPayload anthropicRequest
+	if err := json.Unmarshal(body, &anthropicPayload); err != nil {
+		t.Fatalf("failed to decode anthropic payload: %v", err)
+	}
+	blocks := anthropicPayload.Messages[0].Content
+	if len(blocks) != 3 || blocks[1].Type != "image" || blocks[1].Source.Type != "url" ||
+		blocks[2].Source.Type != "base64" || blocks[2].Source.MediaType != "image/jpeg" {
+		t.Fatalf("unexpected anthropic blocks: %+v", blocks)
+	}
+
+	body, err = (&GoogleAdaptor{}).ConvertChatRequest(ctx, &ProviderConfig{}, multimodalRequest("gemini-2.0-flash"))
+	if err != nil {
+		t.Fatalf("google conversion failed: %v", err)
+	}
+	if !strings.Contains(string(body), `"file_data":{"mime_type":"image/png","file_uri":"https://example.com/cat.png"}`) ||
+		!strings.Contains(string(body), `"inline_data":{"mime_type":"image/jpeg","data":"QUJD"}`) {
+		t.Fatalf("unexpected google payload: %s", body)
+	}
+
+	native := &ProviderConfig{ChatProtocol: ChatProtocolNative, Model: "qwen-vl-max"}
+	body, err = (&AliAdaptor{}).ConvertChatRequest(ctx, native, multimodalRequest("qwen-vl-max"))
+	if err != nil {
+		t.Fatalf("ali conversion failed: %v", err)
+	}
+	if !strings.Contains(string(body), `"content":[{"text":"describe"},{"image":"https://example.com/cat.png"},{"image":"data:image/jpeg;base64,QUJD"}]`) {
+		t.Fatalf("unexpected ali payload: %s", body)
+	}
+	if url, _ := (&AliAdaptor{}).GetRequestURL(ModeChat, native); !strings.HasSuffix(url, "/multimodal-generation/generation") {
+		t.Fatalf("expected multimodal endpoint, got %q", url)
+	}
+	if _, err := (&AliAdaptor{}).ConvertChatRequest(ctx, native, multimodalRequest("qwen-plus")); err == nil {
+		t.Fatal("expected text-only dashscope model to reject image parts")
+	}
+}
@@ -0,0 +1,97 @@
+package adapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// providerErrorBody covers the error envelopes used by the supported providers:
+// OpenAI and Anthropic nest an "error" object, Gemini adds a status to it,
+// DashScope and Jimeng put code and message at the top level, and other
+// Volcengine APIs report errors under ResponseMetadata.
+type providerErrorBody struct {
+	Error            json.RawMessage `json:"error"`
+	Code             json.RawMessage `json:"code"`
+	Message          string          `json:"message"`
+	ResponseMetadata struct {
+		Error struct {
+			Code    string `json:"Code"`
+			Message string `json:"Message"`
+		} `json:"Error"`
+	} `json:"ResponseMetadata"`
+}
+
+type providerErrorDetail struct {
+	Type    string          `json:"type"`
+	Code    json.RawMessage `json:"code"`
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+}
+
+// ParseErrorBody extracts the provider error code and message from an error response body.
+// The code is the most specific identifier the provider reports, e.g. "context_length_exceeded",
+// "overloaded_error", "RESOURCE_EXHAUSTED", "Throttling.RateQuota" or "50429".
+// Both values are empty when the body is not a recognized error envelope.
+func ParseErrorBody(body []byte) (code string, message string) {
+	body = bytes.TrimSpace(body)
+	// Gemini streaming endpoints wrap the error in an array.
+	if len(body) > 0 && body[0] == '[' {
+		var items []json.RawMessage
+		if err := json.Unmarshal(body, &items); err != nil || len(items) == 0 {
+			return "", ""
+		}
+		body = items[0]
+	}
+
+	var envelope providerErrorBody
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return "", ""
+	}
+
+	if len(envelope.Error) > 0 && string(envelope.Error) != "null" {
+		var detail providerErrorDetail
+		if err := json.Unmarshal(envelope.Error, &detail); err != nil {
+			var text string
+			if json.Unmarshal(envelope.Error, &text) == nil {
+				return "", text
+			}
+			return "", ""
+		}
+		return detailCode(detail), detail.Message
+	}
+	if metadata := envelope.ResponseMetadata.Error; metadata.Code != "" {
+		return metadata.Code, metadata.Message
+	}
+	return rawCode(envelope.Code), envelope.Message
+}
+
+func detailCode(detail providerErrorDetail) string {
+	if detail.Status != "" {
+		return detail.Status
+	}
+	code := rawCode(detail.Code)
+	if code != "" {
+		if _, err := strconv.Atoi(code); err != nil {
+			return code
+		}
+	}
+	if detail.Type != "" {
+		return detail.Type
+	}
+	return code
+}
+
+// rawCode renders a JSON string or number code as text.
+func rawCode(raw json.RawMessage) string {
+	value := strings.TrimSpace(string(raw))
+	if value == "" || value == "null" {
+		return ""
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	return value
+}
@@ -13,7 +13,50 @@ import (
 
 // Google Gemini REST API structures
 type googleGeminiPart struct {
-	Text string `json:"text,omitempty"`
+	Text             string                        `json:"text,omitempty"`
+	Thought          bool                          `json:"thought,omitempty"`
+	InlineData       *googleGeminiBlob             `json:"inline_data,omitempty"`
+	FileData         *googleGeminiFileData         `json:"file_data,omitempty"`
+	FunctionCall     *googleGeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *googleGeminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type googleGeminiBlob struct {
+	MimeType string `json:"mime_type"`
+	Data     string `json:"data"`
+}
+
+type googleGeminiFileData struct {
+	MimeType string `json:"mime_type,omitempty"`
+	FileURI  string `json:"file_uri"`
+}
+
+type googleGeminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type googleGeminiFunctionResponse struct {
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+type googleGeminiFunctionDeclaration struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+type googleGeminiTool struct {
+	FunctionDeclarations []googleGeminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type googleGeminiToolConfig struct {
+	FunctionCallingConfig struct {
+		Mode                 string   `json:"mode"`
+		AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+	} `json:"functionCallingConfig"`
 }
 
 type googleGeminiContent struct {
@@ -30,9 +73,28 @@ type googleGeminiGenerationConfig struct {
 }
 
 type googleGeminiChatRequest struct {
-	Contents         []googleGeminiContent        `json:"contents"`
-	SystemInstruction *googleGeminiContent        `json:"system_instruction,omitempty"`
-	GenerationConfig *googleGeminiGenerationConfig `json:"generationConfig,omitempty"`
+	Contents          []googleGeminiContent         `json:"contents"`
+	SystemInstruction *googleGeminiContent          `json:"system_instruction,omitempty"`
+	GenerationConfig  *googleGeminiGenerationConfig `json:"generationConfig,omitempty"`
+	Tools             []googleGeminiTool            `json:"tools,omitempty"`
+	ToolConfig        *googleGeminiToolConfig       `json:"toolConfig,omitempty"`
+	CachedContent     string                        `json:"cachedContent,omitempty"`
+}
+
+type googleGeminiUsage struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	TotalTokenCount         int `json:"totalTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount"`
+}
+
+func (u googleGeminiUsage) toDTO() dto.Usage {
+	return dto.Usage{
+		PromptTokens:     u.PromptTokenCount,
+		CompletionTokens: u.CandidatesTokenCount,
+		TotalTokens:      u.TotalTokenCount,
+		CachedTokens:     u.CachedContentTokenCount,
+	}
 }
 
 type googleGeminiResponse struct {
@@ -41,13 +103,38 @@ type googleGeminiResponse struct {
 			Parts []googleGeminiPart `json:"parts"`
 			Role  string             `json:"role"`
 		} `json:"content"`
-		FinishReason string `json:"finishReason"`
+		FinishReason  string                     `json:"finishReason"`
+		SafetyRatings []googleGeminiSafetyRating `json:"safetyRatings,omitempty"`
 	} `json:"candidates"`
-	UsageMetadata struct {
-		PromptTokenCount     int `json:"promptTokenCount"`
-		CandidatesTokenCount int `json:"candidatesTokenCount"`
-		TotalTokenCount      int `json:"totalTokenCount"`
-	} `json:"usageMetadata"`
+	PromptFeedback struct {
+		BlockReason        string                     `json:"blockReason,omitempty"`
+		BlockReasonMessage string                     `json:"blockReasonMessage,omitempty"`
+		SafetyRatings      []googleGeminiSafetyRating `json:"safetyRatings,omitempty"`
+	} `json:"promptFeedback"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error,omitempty"`
+	UsageMetadata googleGeminiUsage `json:"usageMetadata"`
+	ModelVersion  string            `json:"modelVersion,omitempty"`
+	ResponseID    string            `json:"responseId,omitempty"`
+}
+
+type googleGeminiSafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+// googleBlockedFinishReasons are finish reasons meaning the candidate was withheld.
+var googleBlockedFinishReasons = map[string]bool{
+	"SAFETY":             true,
+	"RECITATION":         true,
+	"BLOCKLIST":          true,
+	"PROHIBITED_CONTENT": true,
+	"SPII":               true,
+	"IMAGE_SAFETY":       true,
 }
 
 // GoogleAdaptor converts requests and responses for Google Gemini API.
@@ -57,6 +144,21 @@ type GoogleAdaptor struct {
 
 // GetRequestURL returns the Google Gemini endpoint for the given mode.
 func (a *GoogleAdaptor) GetRequestURL(mode string, config *ProviderConfig) (string, error) {
+	action := "generateContent"
+	if mode == ModeImage || mode == ModeVideo {
+		// Image and Video generation often use the predict endpoint for Imagen/Veo
+		action = "predict"
+	}
+	return a.modelURL(config, action, ""), nil
+}
+
+// GetStreamRequestURL returns the SSE streaming endpoint (streamGenerateContent with alt=sse).
+func (a *GoogleAdaptor) GetStreamRequestURL(config *ProviderConfig) (string, error) {
+	return a.modelURL(config, "streamGenerateContent", "alt=sse"), nil
+}
+
+// modelURL formats models/{model}:{action}?{query}&key={api_key}.
+func (a *GoogleAdaptor) modelURL(config *ProviderConfig, action, query string) string {
 	base := strings.TrimRight(config.BaseURL, "/")
 	if base == "" {
 		base = strings.TrimRight(a.BaseURL, "/")
@@ -65,21 +167,18 @@ func (a *GoogleAdaptor) GetRequestURL(mode string, config *ProviderConfig) (stri
 		base = "https://generativelanguage.googleapis.com/v1beta"
 	}
 
-	action := "generateContent"
-	if mode == ModeChat && config.Headers["X-Stream"] == "true" {
-		action = "streamGenerateContent"
+	params := make([]string, 0, 2)
+	if query != "" {
+		params = append(params, query)
 	}
-	if mode == ModeImage || mode == ModeVideo {
-		// Image and Video generation often use the predict endpoint for Imagen/Veo
-		action = "predict"
+	if config.APIKey != "" {
+		params = append(params, "key="+config.APIKey)
 	}
-
-	// Format: models/{model}:{action}?key={api_key}
 	url := fmt.Sprintf("%s/models/%s:%s", base, config.Model, action)
-	if config.APIKey != "" {
-		url += "?key=" + config.APIKey
+	if len(params) > 0 {
+		url += "?" + strings.Join(params, "&")
 	}
-	return url, nil
+	return url
 }
 
 // SetupHeaders sets Google-specific headers.
@@ -92,6 +191,7 @@ func (a *GoogleAdaptor) SetupHeaders(req *http.Request, config *ProviderConfig,
 // ConvertChatRequest marshals the Google Gemini chat request.
 func (a *GoogleAdaptor) ConvertChatRequest(ctx context.Context, config *ProviderConfig, request *dto.ChatRequest) ([]byte, error) {
 	contents := make([]googleGeminiContent, 0, len(request.Messages))
+	toolNames := make(map[string]string)
 	for _, m := range request.Messages {
 		role := m.Role
 		if role == "assistant" {
@@ -101,11 +201,44 @@ func (a *GoogleAdaptor) ConvertChatRequest(ctx context.Context, config *Provider
 			// System prompt is handled separately in Gemini v1beta
 			continue
 		}
+		if role == "tool" {
+			name := m.Name
+			if name == "" {
+				name = toolNames[m.ToolCallID]
+			}
+			part := googleGeminiPart{
+				FunctionResponse: &googleGeminiFunctionResponse{
+					Name:     name,
+					Response: googleFunctionResponse(messageText(m.Content)),
+				},
+			}
+			// Gemini expects all responses to one model turn in a single content.
+			if last := len(contents) - 1; last >= 0 && contents[last].Role == "user" && isGoogleFunctionResponse(contents[last]) {
+				contents[last].Parts = append(contents[last].Parts, part)
+				continue
+			}
+			contents = append(contents, googleGeminiContent{Role: "user", Parts: []googleGeminiPart{part}})
+			continue
+		}
+
+		parts := make([]googleGeminiPart, 0, len(m.ToolCalls)+1)
+		if contentParts, ok := m.Content.([]dto.ContentPart); ok {
+			parts = append(parts, googleContentParts(contentParts)...)
+		} else if text := messageText(m.Content); text != "" || len(m.ToolCalls) == 0 {
+			parts = append(parts, googleGeminiPart{Text: text})
+		}
+		for _, call := range m.ToolCalls {
+			toolNames[call.ID] = call.Function.Name
+			parts = append(parts, googleGeminiPart{
+				FunctionCall: &googleGeminiFunctionCall{
+					Name: call.Function.Name,
+					Args: toolArguments(call.Function.Arguments),
+				},
+			})
+		}
 		contents = append(contents, googleGeminiContent{
-			Role: role,
-			Parts: []googleGeminiPart{
-				{Text: fmt.Sprint(m.Content)},
-			},
+			Role:  role,
+			Parts: parts,
 		})
 	}
 
@@ -131,10 +264,97 @@ func (a *GoogleAdaptor) ConvertChatRequest(ctx context.Context, config *Provider
 	if topK, ok := request.Options["top_k"].(int); ok {
 		payload.GenerationConfig.TopK = topK
 	}
+	// cached_content references a context cache created through the cachedContents API.
+	if cachedContent, ok := request.Options["cached_content"].(string); ok {
+		payload.CachedContent = cachedContent
+	}
+
+	if tools := extractTools(request.Options); len(tools) > 0 {
+		declarations := make([]googleGeminiFunctionDeclaration, 0, len(tools))
+		for _, tool := range tools {
+			declarations = append(declarations, googleGeminiFunctionDeclaration{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				Parameters:  cleanSchemaForGoogle(tool.Function.Parameters),
+			})
+		}
+		payload.Tools = []googleGeminiTool{{FunctionDeclarations: declarations}}
+
+		mode, name := normalizeToolChoice(request.Options)
+		if mode != "" {
+			payload.ToolConfig = &googleGeminiToolConfig{}
+			switch mode {
+			case toolChoiceNone:
+				payload.ToolConfig.FunctionCallingConfig.Mode = "NONE"
+			case toolChoiceRequired:
+				payload.ToolConfig.FunctionCallingConfig.Mode = "ANY"
+			case toolChoiceFunction:
+				payload.ToolConfig.FunctionCallingConfig.Mode = "ANY"
+				payload.ToolConfig.FunctionCallingConfig.AllowedFunctionNames = []string{name}
+			default:
+				payload.ToolConfig.FunctionCallingConfig.Mode = "AUTO"
+			}
+		}
+	}
 
 	return json.Marshal(payload)
 }
 
+func isGoogleFunctionResponse(content googleGeminiContent) bool {
+	for _, part := range content.Parts {
+		if part.FunctionResponse == nil {
+			return false
+		}
+	}
+	return len(content.Parts) > 0
+}
+
+// googleFunctionResponse wraps a tool result into the object Gemini requires.
+func googleFunctionResponse(content string) json.RawMessage {
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		return json.RawMessage(trimmed)
+	}
+	wrapped, err := json.Marshal(map[string]interface{}{"result": content})
+	if err != nil {
+		return json.RawMessage("{}")
+	}
+	return wrapped
+}
+
+// cleanSchemaForGoogle drops JSON Schema keywords that Gemini's OpenAPI subset rejects.
+func cleanSchemaForGoogle(schema map[string]interface{}) map[string]interface{} {
+	if schema == nil {
+		return nil
+	}
+	result := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		switch key {
+		case "$schema", "$id", "additionalProperties":
+			continue
+		}
+		switch typed := value.(type) {
+		case map[string]interface{}:
+			if key == "properties" {
+				props := make(map[string]interface{}, len(typed))
+				for name, prop := range typed {
+					if propMap, ok := prop.(map[string]interface{}); ok {
+						props[name] = cleanSchemaForGoogle(propMap)
+					} else {
+						props[name] = prop
+					}
+				}
+				result[key] = props
+			} else {
+				result[key] = cleanSchemaForGoogle(typed)
+			}
+		default:
+			result[key] = value
+		}
+	}
+	return result
+}
+
 // ConvertChatResponse unmarshals the Google Gemini chat response.
 func (a *GoogleAdaptor) ConvertChatResponse(ctx context.Context, config *ProviderConfig, body []byte) (*dto.ChatResponse, error) {
 	var gResp googleGeminiResponse
@@ -142,37 +362,92 @@ func (a *GoogleAdaptor) ConvertChatResponse(ctx context.Context, config *Provide
 		return nil, err
 	}
 
+	if code, message := googleBlockError(&gResp); message != "" {
+		return nil, &dto.LLMError{
+			Code:     http.StatusBadRequest,
+			Message:  message,
+			Provider: config.Name,
+			Type:     code,
+		}
+	}
 	if len(gResp.Candidates) == 0 {
 		return nil, fmt.Errorf("no candidates in google response")
 	}
 
 	candidate := gResp.Candidates[0]
-	var content string
-	if len(candidate.Content.Parts) > 0 {
-		content = candidate.Content.Parts[0].Text
+	var content strings.Builder
+	var toolCalls []dto.ToolCall
+	for i, part := range candidate.Content.Parts {
+		if !part.Thought {
+			content.WriteString(part.Text)
+		}
+		if part.FunctionCall != nil {
+			toolCalls = append(toolCalls, googleToolCall(part.FunctionCall, i))
+		}
 	}
 
 	resp := &dto.ChatResponse{
+		ID:    gResp.ResponseID,
+		Model: gResp.ModelVersion,
 		Choices: []dto.ChatChoice{
 			{
 				Index: 0,
 				Message: dto.Message{
-					Role:    "assistant",
-					Content: content,
+					Role:      "assistant",
+					Content:   content.String(),
+					ToolCalls: toolCalls,
 				},
 				FinishReason: candidate.FinishReason,
 			},
 		},
-		Usage: dto.Usage{
-			PromptTokens:     gResp.UsageMetadata.PromptTokenCount,
-			CompletionTokens: gResp.UsageMetadata.CandidatesTokenCount,
-			TotalTokens:      gResp.UsageMetadata.TotalTokenCount,
-		},
+		Usage: gResp.UsageMetadata.toDTO(),
 	}
 
 	return resp, nil
 }
 
+// googleContentParts converts multimodal parts into Gemini parts: inline data is sent
+// as inline_data and URLs (File API or Cloud Storage URIs) as file_data.
+func googleContentParts(parts []dto.ContentPart) []googleGeminiPart {
+	converted := make([]googleGeminiPart, 0, len(parts))
+	for _, part := range parts {
+		switch {
+		case part.Type == dto.ContentPartText:
+			converted = append(converted, googleGeminiPart{Text: part.Text})
+		case part.IsInline():
+			converted = append(converted, googleGeminiPart{
+				InlineData: &googleGeminiBlob{MimeType: part.MIMEType, Data: part.Data},
+			})
+		default:
+			mimeType := part.MIMEType
+			if mimeType == "" {
+				mimeType = guessMIMEType(part.URL, "")
+			}
+			converted = append(converted, googleGeminiPart{
+				FileData: &googleGeminiFileData{MimeType: mimeType, FileURI: part.URL},
+			})
+		}
+	}
+	return converted
+}
+
+// googleToolCall converts a Gemini function call part into a tool call.
+// Gemini does not always assign call IDs, so one is derived from the part index.
+func googleToolCall(call *googleGeminiFunctionCall, index int) dto.ToolCall {
+	id := call.ID
+	if id == "" {
+		id = fmt.Sprintf("call_%d", index)
+	}
+	return dto.ToolCall{
+		ID:   id,
+		Type: "function",
+		Function: dto.ToolCallFunction{
+			Name:      call.Name,
+			Arguments: string(toolArguments(string(call.Args))),
+		},
+	}
+}
+
 // ConvertMediaRequest marshals the Google Media request (Imagen/Video).
 func (a *GoogleAdaptor) ConvertMediaRequest(ctx context.Context, config *ProviderConfig, mode string, request *dto.MediaRequest) ([]byte, error) {
 	// For Imagen 3 / Veo via predict
@@ -308,28 +583,108 @@ func (a *GoogleAdaptor) ConvertTaskStatusResponse(ctx context.Context, config *P
 }
 
 // PrepareStreamRequest creates a streaming chat request body.
+// The stream endpoint is selected by GetStreamRequestURL, so the body is the same
+// as for a regular generateContent call.
 func (a *GoogleAdaptor) PrepareStreamRequest(ctx context.Context, config *ProviderConfig, request *dto.ChatRequest) ([]byte, error) {
-	if config.Headers == nil {
-		config.Headers = make(map[string]string)
-	}
-	config.Headers["X-Stream"] = "true"
 	return a.ConvertChatRequest(ctx, config, request)
 }
 
-// ParseStreamResponse processes a single streaming chunk for Google.
-// Note: Google's stream is a JSON array of objects, or individual objects depending on the endpoint.
+// ParseStreamResponse processes a single SSE chunk for Google, joining all text parts.
 func (a *GoogleAdaptor) ParseStreamResponse(chunk []byte) (string, error) {
-	// Google v1beta streamGenerateContent returns a JSON array of candidates.
-	// However, usually it's wrapped in a response object.
+	events, err := a.ParseStreamEvent(chunk)
+	if err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	for _, event := range events {
+		if event.Type == dto.StreamEventError {
+			return "", fmt.Errorf("stream error: %s", event.Error)
+		}
+		builder.WriteString(event.Text)
+	}
+	return builder.String(), nil
+}
+
+// ParseStreamEvent decodes a Gemini SSE chunk into typed events.
+// Gemini sends complete function calls, so each one is reported as a single
+// tool-call delta carrying the full arguments. Blocked prompts and candidates
+// withheld by safety filters are reported as error events.
+func (a *GoogleAdaptor) ParseStreamEvent(chunk []byte) ([]dto.StreamEvent, error) {
+	if len(strings.TrimSpace(string(chunk))) == 0 {
+		return nil, nil
+	}
+
 	var gResp googleGeminiResponse
 	if err := json.Unmarshal(chunk, &gResp); err != nil {
-		// Might be a partial chunk or SSE format
-		return "", fmt.Errorf("malformed chunk: %w", err)
+		return nil, fmt.Errorf("malformed chunk: %w", err)
+	}
+
+	var events []dto.StreamEvent
+	if len(gResp.Candidates) > 0 {
+		candidate := gResp.Candidates[0]
+		for i, part := range candidate.Content.Parts {
+			if part.Text != "" && !part.Thought {
+				events = append(events, dto.StreamEvent{Type: dto.StreamEventText, Text: part.Text})
+			}
+			if part.FunctionCall != nil {
+				call := googleToolCall(part.FunctionCall, i)
+				events = append(events, dto.StreamEvent{
+					Type: dto.StreamEventToolCall,
+					ToolCall: &dto.ToolCallDelta{
+						Index:     i,
+						ID:        part.FunctionCall.ID,
+						Name:      call.Function.Name,
+						Arguments: call.Function.Arguments,
+					},
+				})
+			}
+		}
+		if candidate.FinishReason != "" {
+			events = append(events, dto.StreamEvent{Type: dto.StreamEventFinish, FinishReason: candidate.FinishReason})
+		}
+	}
+	if gResp.UsageMetadata.TotalTokenCount > 0 {
+		usage := gResp.UsageMetadata.toDTO()
+		events = append(events, dto.StreamEvent{Type: dto.StreamEventUsage, Usage: &usage})
 	}
+	if code, message := googleBlockError(&gResp); message != "" {
+		events = append(events, dto.StreamEvent{Type: dto.StreamEventError, Error: message, ErrorCode: code})
+	}
+	return withResponseMetadata(events, gResp.ResponseID, gResp.ModelVersion, 0), nil
+}
 
-	if len(gResp.Candidates) > 0 && len(gResp.Candidates[0].Content.Parts) > 0 {
-		return gResp.Candidates[0].Content.Parts[0].Text, nil
+// googleBlockError describes an API error, a blocked prompt or a candidate withheld
+// by safety filters, together with the provider status or block reason. The message
+// is empty when the response is usable.
+func googleBlockError(gResp *googleGeminiResponse) (code string, message string) {
+	if gResp.Error != nil && gResp.Error.Message != "" {
+		return gResp.Error.Status, gResp.Error.Message
+	}
+	if reason := gResp.PromptFeedback.BlockReason; reason != "" {
+		message := "gemini blocked the prompt: " + reason
+		if gResp.PromptFeedback.BlockReasonMessage != "" {
+			message += " (" + gResp.PromptFeedback.BlockReasonMessage + ")"
+		}
+		return reason, message + googleBlockedCategories(gResp.PromptFeedback.SafetyRatings)
 	}
+	if len(gResp.Candidates) > 0 {
+		candidate := gResp.Candidates[0]
+		if googleBlockedFinishReasons[candidate.FinishReason] {
+			return candidate.FinishReason, "gemini blocked the response: " + candidate.FinishReason + googleBlockedCategories(candidate.SafetyRatings)
+		}
+	}
+	return "", ""
+}
 
-	return "", nil
+func googleBlockedCategories(ratings []googleGeminiSafetyRating) string {
+	var categories []string
+	for _, rating := range ratings {
+		if rating.Blocked {
+			categories = append(categories, rating.Category)
+		}
+	}
+	if len(categories) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(categories, ", ") + "]"
 }
@@ -0,0 +1,73 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/YspCoder/omnigo/dto"
+)
+
+func TestGoogleStreamRequestUsesSSEWithoutMutatingConfig(t *testing.T) {
+	adaptor := &GoogleAdaptor{}
+	config := &ProviderConfig{Model: "gemini-2.0-flash", APIKey: "k", Headers: map[string]string{}}
+
+	if _, err := adaptor.PrepareStreamRequest(context.Background(), config, &dto.ChatRequest{Prompt: "hi"}); err != nil {
+		t.Fatalf("PrepareStreamRequest returned error: %v", err)
+	}
+	if len(config.Headers) != 0 {
+		t.Fatalf("expected config headers to be untouched, got %+v", config.Headers)
+	}
+
+	url, err := adaptor.GetStreamRequestURL(config)
+	if err != nil {
+		t.Fatalf("GetStreamRequestURL returned error: %v", err)
+	}
+	expected := "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:streamGenerateContent?alt=sse&key=k"
+	if url != expected {
+		t.Fatalf("unexpected stream URL %q", url)
+	}
+	url, _ = adaptor.GetRequestURL(ModeChat, config)
+	if url != "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:generateContent?key=k" {
+		t.Fatalf("unexpected chat URL %q", url)
+	}
+}
+
+func TestGoogleParseStreamEvent(t *testing.T) {
+	adaptor := &GoogleAdaptor{}
+
+	events, err := adaptor.ParseStreamEvent([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"thinking","thought":true},{"text":"Hello "},{"text":"world"},{"functionCall":{"name":"lookup","args":{"q":"go"}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":6,"totalTokenCount":10}}`))
+	if err != nil {
+		t.Fatalf("ParseStreamEvent returned error: %v", err)
+	}
+	if len(events) != 5 {
+		t.Fatalf("expected 5 events, got %+v", events)
+	}
+	if events[0].Text != "Hello " || events[1].Text != "world" {
+		t.Fatalf("unexpected text events: %+v", events[:2])
+	}
+	if events[2].ToolCall == nil || events[2].ToolCall.Name != "lookup" || events[2].ToolCall.Arguments != `{"q":"go"}` {
+		t.Fatalf("unexpected tool call event: %+v", events[2])
+	}
+	if events[3].FinishReason != "STOP" || events[4].Usage.TotalTokens != 10 {
+		t.Fatalf("unexpected trailing events: %+v", events[3:])
+	}
+
+	events, err = adaptor.ParseStreamEvent([]byte(`{"candidates":[{"content":{"parts":[]},"finishReason":"SAFETY","safetyRatings":[{"category":"HARM_CATEGORY_HARASSMENT","probability":"HIGH","blocked":true}]}]}`))
+	if err != nil {
+		t.Fatalf("ParseStreamEvent returned error: %v", err)
+	}
+	last := events[len(events)-1]
+	if last.Type != dto.StreamEventError || last.Error != "gemini blocked the response: SAFETY [HARM_CATEGORY_HARASSMENT]" {
+		t.Fatalf("expected safety error event, got %+v", events)
+	}
+}
+
+func TestGoogleConvertChatResponseBlockedPrompt(t *testing.T) {
+	_, err := (&GoogleAdaptor{}).ConvertChatResponse(context.Background(), &ProviderConfig{Name: "google"},
+		[]byte(`{"promptFeedback":{"blockReason":"SAFETY"}}`))
+	var llmErr *dto.LLMError
+	if !errors.As(err, &llmErr) || llmErr.Message != "gemini blocked the prompt: SAFETY" {
+		t.Fatalf("expected blocked prompt error, got %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/YspCoder/omnigo/dto"
+	"github.com/YspCoder/omnigo/ratelimit"
 )
 
 const (
@@ -16,6 +17,13 @@ const (
 	ModeTask  = "task"
 )
 
+// Chat protocols understood by the relay. OpenAI-compatible providers share the
+// OpenAI conversion; native providers use their own adaptor for chat payloads.
+const (
+	ChatProtocolOpenAI = "openai"
+	ChatProtocolNative = "native"
+)
+
 // ProviderConfig holds configuration for a specific provider.
 type ProviderConfig struct {
 	Name         string
@@ -29,6 +37,8 @@ type ProviderConfig struct {
 	HTTPClient   *http.Client
 	Timeout      time.Duration
 	ChatProtocol string
+	Region       string             // Signing region of providers that sign requests, e.g. Jimeng
+	Limiter      *ratelimit.Limiter // Client-side rate limiter; nil disables limiting
 }
 
 // Adaptor defines the interface for provider-specific conversions and routing.
@@ -54,6 +64,18 @@ type StreamAdaptor interface {
 	ParseStreamResponse(chunk []byte) (string, error)
 }
 
+// StreamEventParser is implemented by stream adaptors that decode chunks into typed
+// events (text, tool-call deltas, usage, finish, error). It returns io.EOF when the
+// stream is complete and may return no events for chunks that carry nothing useful.
+type StreamEventParser interface {
+	ParseStreamEvent(chunk []byte) ([]dto.StreamEvent, error)
+}
+
+// StreamURLProvider allows adaptors to use a dedicated endpoint for streaming requests.
+type StreamURLProvider interface {
+	GetStreamRequestURL(config *ProviderConfig) (string, error)
+}
+
 // StreamHeadersProvider allows adaptors to inject extra headers for streaming requests.
 type StreamHeadersProvider interface {
 	StreamHeaders(config *ProviderConfig) map[string]string
@@ -69,3 +91,10 @@ type TaskAdaptor interface {
 type TaskRequestAdaptor interface {
 	PrepareTaskStatusRequest(ctx context.Context, config *ProviderConfig, taskID string) (method string, body []byte, err error)
 }
+
+// RequestSigner is implemented by adaptors whose provider authenticates requests with
+// a signature over the final request. The relay calls SignRequest right before sending,
+// after middlewares have run, with the body that will be sent.
+type RequestSigner interface {
+	SignRequest(req *http.Request, config *ProviderConfig, body []byte) error
+}
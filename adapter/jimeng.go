@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/YspCoder/omnigo/dto"
@@ -52,10 +53,22 @@ type JimengGetResultResponse struct {
 }
 
 // JimengAdaptor converts requests and responses for Jimeng APIs.
+//
+// Requests are signed with Volcengine V4 signing when credentials are available:
+// either AccessKeyID and SecretAccessKey, or an API key of the form
+// "<AccessKeyID>:<SecretAccessKey>". Any other API key is sent as a bearer token,
+// for deployments behind a gateway that signs requests itself. The region set in
+// ProviderConfig takes precedence over Region.
 type JimengAdaptor struct {
-	BaseURL string
+	BaseURL         string
+	AccessKeyID     string
+	SecretAccessKey string
+	Region          string // Signing region; defaults to cn-north-1
 }
 
+// jimengService is the Volcengine service name for visual APIs.
+const jimengService = "cv"
+
 // GetRequestURL returns the Jimeng endpoint for the given mode.
 func (a *JimengAdaptor) GetRequestURL(mode string, config *ProviderConfig) (string, error) {
 	base := strings.TrimRight(config.BaseURL, "/")
@@ -74,17 +87,48 @@ func (a *JimengAdaptor) GetRequestURL(mode string, config *ProviderConfig) (stri
 	}
 }
 
-// SetupHeaders sets Jimeng headers.
+// SetupHeaders sets Jimeng headers. Signed requests get their Authorization header
+// from SignRequest instead.
 func (a *JimengAdaptor) SetupHeaders(req *http.Request, config *ProviderConfig, mode string) error {
-	if config.APIKey != "" {
-		// Note: Volcengine usually requires complex signing.
-		// For now, we assume a simplified API key or a gateway that handles signing.
+	if a.signer(config) == nil && config.APIKey != "" {
 		req.Header.Set("Authorization", "Bearer "+config.APIKey)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	return nil
 }
 
+// SignRequest signs the request with Volcengine V4 signing when credentials are set.
+func (a *JimengAdaptor) SignRequest(req *http.Request, config *ProviderConfig, body []byte) error {
+	signer := a.signer(config)
+	if signer == nil {
+		return nil
+	}
+	return signer.Sign(req, body)
+}
+
+// signer returns the Volcengine signer for the configured credentials, or nil
+// when only a bearer token is available.
+func (a *JimengAdaptor) signer(config *ProviderConfig) *VolcengineSigner {
+	accessKeyID, secretAccessKey := a.AccessKeyID, a.SecretAccessKey
+	if accessKeyID == "" || secretAccessKey == "" {
+		var ok bool
+		accessKeyID, secretAccessKey, ok = strings.Cut(config.APIKey, ":")
+		if !ok || accessKeyID == "" || secretAccessKey == "" {
+			return nil
+		}
+	}
+	region := config.Region
+	if region == "" {
+		region = a.Region
+	}
+	return &VolcengineSigner{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		Region:          region,
+		Service:         jimengService,
+	}
+}
+
 // ConvertChatRequest is not supported for Jimeng video generation.
 func (a *JimengAdaptor) ConvertChatRequest(ctx context.Context, config *ProviderConfig, request *dto.ChatRequest) ([]byte, error) {
 	return nil, fmt.Errorf("chat mode not supported for Jimeng")
@@ -156,6 +200,7 @@ func (a *JimengAdaptor) ConvertMediaResponse(ctx context.Context, config *Provid
 			Code:     http.StatusBadRequest,
 			Message:  response.Message,
 			Provider: config.Name,
+			Type:     strconv.Itoa(response.Code),
 		}
 	}
 
@@ -207,6 +252,7 @@ func (a *JimengAdaptor) ConvertTaskStatusResponse(ctx context.Context, config *P
 			Code:     http.StatusBadRequest,
 			Message:  response.Message,
 			Provider: config.Name,
+			Type:     strconv.Itoa(response.Code),
 		}
 	}
 
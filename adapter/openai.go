@@ -78,9 +78,13 @@ func (a *OpenAIAdaptor) SetupHeaders(req *http.Request, config *ProviderConfig,
 
 // ConvertChatRequest marshals the OpenAI chat request.
 func (a *OpenAIAdaptor) ConvertChatRequest(ctx context.Context, config *ProviderConfig, request *dto.ChatRequest) ([]byte, error) {
+	messages, err := normalizeMessages(request)
+	if err != nil {
+		return nil, err
+	}
 	payload := map[string]interface{}{
 		"model":    request.Model,
-		"messages": normalizeMessages(request),
+		"messages": messages,
 	}
 	if request.Stream {
 		payload["stream"] = true
@@ -98,6 +102,13 @@ func (a *OpenAIAdaptor) ConvertChatRequest(ctx context.Context, config *Provider
 		}
 		payload[key] = value
 	}
+	if _, ok := payload["tool_choice"]; ok {
+		if choice := openAIToolChoice(request.Options); choice != nil {
+			payload["tool_choice"] = choice
+		} else {
+			delete(payload, "tool_choice")
+		}
+	}
 
 	if request.Schema != nil {
 		schema, err := normalizeSchema(request.Schema)
@@ -132,7 +143,7 @@ func (a *OpenAIAdaptor) ConvertChatResponse(ctx context.Context, config *Provide
 	return &response, nil
 }
 
-func normalizeMessages(request *dto.ChatRequest) []dto.Message {
+func normalizeMessages(request *dto.ChatRequest) ([]dto.Message, error) {
 	messages := request.Messages
 	if len(messages) == 0 && request.Prompt != "" {
 		messages = []dto.Message{{Role: "user", Content: request.Prompt}}
@@ -146,12 +157,82 @@ func normalizeMessages(request *dto.ChatRequest) []dto.Message {
 		messages = withSystem
 	}
 
-	return messages
+	normalized := make([]dto.Message, 0, len(messages))
+	for _, msg := range messages {
+		if len(msg.ToolCalls) > 0 && messageText(msg.Content) == "" {
+			msg.Content = nil
+		}
+		if parts, ok := msg.Content.([]dto.ContentPart); ok {
+			content, err := openAIContentParts(parts)
+			if err != nil {
+				return nil, err
+			}
+			msg.Content = content
+		}
+		normalized = append(normalized, msg)
+	}
+	return normalized, nil
+}
+
+// openAIContentParts converts multimodal parts into OpenAI content array entries.
+func openAIContentParts(parts []dto.ContentPart) ([]map[string]interface{}, error) {
+	converted := make([]map[string]interface{}, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case dto.ContentPartText:
+			converted = append(converted, map[string]interface{}{"type": "text", "text": part.Text})
+		case dto.ContentPartImage:
+			imageURL := map[string]interface{}{"url": part.DataURL()}
+			if part.Detail != "" {
+				imageURL["detail"] = part.Detail
+			}
+			converted = append(converted, map[string]interface{}{"type": "image_url", "image_url": imageURL})
+		case dto.ContentPartAudio:
+			if !part.IsInline() {
+				return nil, fmt.Errorf("openai audio input requires inline data")
+			}
+			converted = append(converted, map[string]interface{}{
+				"type": "input_audio",
+				"input_audio": map[string]interface{}{
+					"data":   part.Data,
+					"format": audioFormat(part.MIMEType),
+				},
+			})
+		case dto.ContentPartFile:
+			if !part.IsInline() {
+				return nil, fmt.Errorf("openai file input requires inline data")
+			}
+			file := map[string]interface{}{"file_data": part.DataURL()}
+			if part.Filename != "" {
+				file["filename"] = part.Filename
+			}
+			converted = append(converted, map[string]interface{}{"type": "file", "file": file})
+		default:
+			return nil, fmt.Errorf("unsupported content part type: %s", part.Type)
+		}
+	}
+	return converted, nil
+}
+
+// openAIToolChoice converts the tool_choice option into the OpenAI wire format.
+func openAIToolChoice(options map[string]interface{}) interface{} {
+	mode, name := normalizeToolChoice(options)
+	switch mode {
+	case toolChoiceAuto, toolChoiceNone, toolChoiceRequired:
+		return mode
+	case toolChoiceFunction:
+		return map[string]interface{}{
+			"type":     "function",
+			"function": map[string]interface{}{"name": name},
+		}
+	default:
+		return nil
+	}
 }
 
 func shouldSkipOption(key string) bool {
 	switch key {
-	case "system_prompt", "structured_messages":
+	case "system_prompt", "system_cache_type", "structured_messages":
 		return true
 	default:
 		return false
@@ -340,6 +421,77 @@ func (a *OpenAIAdaptor) ParseStreamResponse(chunk []byte) (string, error) {
 	return response.Choices[0].Delta.Content, nil
 }
 
+// ParseStreamEvent decodes a streaming chunk into typed events, including
+// tool-call deltas, the finish reason and the trailing usage chunk.
+func (a *OpenAIAdaptor) ParseStreamEvent(chunk []byte) ([]dto.StreamEvent, error) {
+	trimmed := bytes.TrimSpace(chunk)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+	if bytes.Equal(trimmed, []byte("[DONE]")) {
+		return nil, io.EOF
+	}
+
+	var response struct {
+		ID      string `json:"id"`
+		Model   string `json:"model"`
+		Created int64  `json:"created"`
+		Choices []struct {
+			Index int `json:"index"`
+			Delta struct {
+				Content   string `json:"content"`
+				ToolCalls []struct {
+					Index    int    `json:"index"`
+					ID       string `json:"id"`
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"delta"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage *dto.Usage           `json:"usage"`
+		Error *providerErrorDetail `json:"error"`
+	}
+	if err := json.Unmarshal(trimmed, &response); err != nil {
+		return nil, fmt.Errorf("malformed response: %w", err)
+	}
+
+	var events []dto.StreamEvent
+	if response.Error != nil {
+		events = append(events, dto.StreamEvent{Type: dto.StreamEventError, Error: response.Error.Message, ErrorCode: detailCode(*response.Error)})
+		return events, nil
+	}
+	for _, choice := range response.Choices {
+		if choice.Index != 0 {
+			continue
+		}
+		if choice.Delta.Content != "" {
+			events = append(events, dto.StreamEvent{Type: dto.StreamEventText, Text: choice.Delta.Content})
+		}
+		for _, call := range choice.Delta.ToolCalls {
+			events = append(events, dto.StreamEvent{
+				Type: dto.StreamEventToolCall,
+				ToolCall: &dto.ToolCallDelta{
+					Index:     call.Index,
+					ID:        call.ID,
+					Name:      call.Function.Name,
+					Arguments: call.Function.Arguments,
+				},
+			})
+		}
+		if choice.FinishReason != "" {
+			events = append(events, dto.StreamEvent{Type: dto.StreamEventFinish, FinishReason: choice.FinishReason})
+		}
+	}
+	if response.Usage != nil && (response.Usage.TotalTokens > 0 || response.Usage.PromptTokens > 0) {
+		usage := *response.Usage
+		events = append(events, dto.StreamEvent{Type: dto.StreamEventUsage, Usage: &usage})
+	}
+	return withResponseMetadata(events, response.ID, response.Model, response.Created), nil
+}
+
 func buildOpenAIRequestURL(base, mode string) (string, error) {
 	suffix, err := openAISuffix(mode)
 	if err != nil {
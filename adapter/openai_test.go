@@ -0,0 +1,22 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOpenAIConvertChatResponseCachedTokens(t *testing.T) {
+	body := []byte(`{
+		"id": "chatcmpl-1",
+		"choices": [{"index": 0, "message": {"role": "assistant", "content": "ok"}, "finish_reason": "stop"}],
+		"usage": {"prompt_tokens": 2006, "completion_tokens": 300, "total_tokens": 2306, "prompt_tokens_details": {"cached_tokens": 1920}}
+	}`)
+
+	resp, err := (&OpenAIAdaptor{}).ConvertChatResponse(context.Background(), &ProviderConfig{}, body)
+	if err != nil {
+		t.Fatalf("ConvertChatResponse returned error: %v", err)
+	}
+	if resp.Usage.PromptTokens != 2006 || resp.Usage.CachedTokens != 1920 {
+		t.Fatalf("unexpected usage: %+v", resp.Usage)
+	}
+}
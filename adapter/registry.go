@@ -25,9 +25,20 @@ type ProviderSpec struct {
 	RequiredHeaders   map[string]string
 	SupportsSchema    bool
 	SupportsStreaming bool
+	MediaModes        []string // Media modes (ModeImage, ModeVideo) the provider can generate
 	AdaptorFactory    func() Adaptor
 }
 
+// SupportsMode reports whether the provider can generate media in the given mode.
+func (s ProviderSpec) SupportsMode(mode string) bool {
+	for _, supported := range s.MediaModes {
+		if supported == mode {
+			return true
+		}
+	}
+	return false
+}
+
 // Registry manages adaptor registration.
 type Registry struct {
 	mu    sync.RWMutex
@@ -50,6 +61,7 @@ func NewRegistry(providerNames ...string) *Registry {
 			RequiredHeaders:   map[string]string{"Content-Type": "application/json"},
 			SupportsSchema:    true,
 			SupportsStreaming: true,
+			MediaModes:        []string{ModeImage, ModeVideo},
 		},
 		"groq": {
 			Name:              "groq",
@@ -80,6 +92,7 @@ func NewRegistry(providerNames ...string) *Registry {
 			RequiredHeaders:   map[string]string{"Content-Type": "application/json"},
 			SupportsSchema:    true,
 			SupportsStreaming: true,
+			MediaModes:        []string{ModeImage},
 		},
 		"custom-openai": {
 			Name:              "custom-openai",
@@ -112,7 +125,8 @@ func NewRegistry(providerNames ...string) *Registry {
 			AuthPrefix:        "Bearer ",
 			RequiredHeaders:   map[string]string{"Content-Type": "application/json"},
 			SupportsSchema:    false,
-			SupportsStreaming: false,
+			SupportsStreaming: true,
+			MediaModes:        []string{ModeImage, ModeVideo},
 			AdaptorFactory: func() Adaptor {
 				return &AliAdaptor{}
 			},
@@ -126,6 +140,7 @@ func NewRegistry(providerNames ...string) *Registry {
 			RequiredHeaders:   map[string]string{"Content-Type": "application/json"},
 			SupportsSchema:    false,
 			SupportsStreaming: false,
+			MediaModes:        []string{ModeVideo},
 			AdaptorFactory: func() Adaptor {
 				return &JimengAdaptor{}
 			},
@@ -139,6 +154,7 @@ func NewRegistry(providerNames ...string) *Registry {
 			RequiredHeaders:   map[string]string{"Content-Type": "application/json"},
 			SupportsSchema:    false,
 			SupportsStreaming: true,
+			MediaModes:        []string{ModeImage, ModeVideo},
 			AdaptorFactory: func() Adaptor {
 				return &GoogleAdaptor{}
 			},
@@ -2,6 +2,11 @@ package adapter
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/YspCoder/omnigo/dto"
+	"github.com/YspCoder/omnigo/utils"
 )
 
 func getStringExtra(extra map[string]interface{}, key string) string {
@@ -51,3 +56,169 @@ func marshalPayloadWithFallback(payload map[string]interface{}, fallback interfa
 	}
 	return json.Marshal(fallback)
 }
+
+// extractTools reads the "tools" option in whatever shape the caller supplied it.
+func extractTools(options map[string]interface{}) []utils.Tool {
+	raw, ok := options["tools"]
+	if !ok || raw == nil {
+		return nil
+	}
+	if tools, ok := raw.([]utils.Tool); ok {
+		return tools
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var tools []utils.Tool
+	if err := json.Unmarshal(b, &tools); err != nil {
+		return nil
+	}
+	return tools
+}
+
+// Canonical tool choice modes shared by all adaptors.
+const (
+	toolChoiceAuto     = "auto"
+	toolChoiceNone     = "none"
+	toolChoiceRequired = "required"
+	toolChoiceFunction = "function"
+)
+
+// normalizeToolChoice reduces the "tool_choice" option to a canonical mode and,
+// when a specific function is forced, its name. It accepts the OpenAI string form,
+// the OpenAI object form, the Anthropic object form and the {"type": choice} map
+// produced by llm.WithToolChoice. Any other type value is treated as a function name.
+func normalizeToolChoice(options map[string]interface{}) (mode string, name string) {
+	raw, ok := options["tool_choice"]
+	if !ok || raw == nil {
+		return "", ""
+	}
+
+	var choiceType string
+	switch value := raw.(type) {
+	case string:
+		choiceType = value
+	case map[string]interface{}:
+		choiceType, _ = value["type"].(string)
+		if n, ok := value["name"].(string); ok && n != "" {
+			name = n
+		}
+		if fn, ok := value["function"].(map[string]interface{}); ok {
+			if n, ok := fn["name"].(string); ok && n != "" {
+				name = n
+			}
+		}
+	default:
+		return "", ""
+	}
+
+	switch strings.ToLower(choiceType) {
+	case "", "auto":
+		if name != "" {
+			return toolChoiceFunction, name
+		}
+		return toolChoiceAuto, ""
+	case "none":
+		return toolChoiceNone, ""
+	case "required", "any":
+		return toolChoiceRequired, ""
+	case "function", "tool":
+		if name == "" {
+			return toolChoiceRequired, ""
+		}
+		return toolChoiceFunction, name
+	default:
+		return toolChoiceFunction, choiceType
+	}
+}
+
+// toolArguments returns the tool call arguments as a JSON object, defaulting to {}.
+func toolArguments(arguments string) json.RawMessage {
+	trimmed := strings.TrimSpace(arguments)
+	if trimmed == "" || !json.Valid([]byte(trimmed)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(trimmed)
+}
+
+// messageText flattens a message content value into plain text.
+func messageText(content interface{}) string {
+	switch value := content.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case []dto.ContentPart:
+		return dto.JoinText(value)
+	case []interface{}:
+		// Content arrays as returned by OpenAI-style or DashScope multimodal APIs.
+		var builder strings.Builder
+		for _, item := range value {
+			if part, ok := item.(map[string]interface{}); ok {
+				if text, ok := part["text"].(string); ok {
+					builder.WriteString(text)
+				}
+			}
+		}
+		return builder.String()
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// audioFormat maps an audio MIME type to the short format name used by providers.
+func audioFormat(mimeType string) string {
+	switch strings.ToLower(mimeType) {
+	case "audio/wav", "audio/x-wav", "audio/wave":
+		return "wav"
+	case "audio/mpeg", "audio/mp3":
+		return "mp3"
+	}
+	if _, format, ok := strings.Cut(mimeType, "/"); ok {
+		return format
+	}
+	return mimeType
+}
+
+// guessMIMEType infers a media MIME type from a URL's file extension.
+func guessMIMEType(url string, fallback string) string {
+	path := strings.ToLower(url)
+	if index := strings.IndexAny(path, "?#"); index >= 0 {
+		path = path[:index]
+	}
+	switch {
+	case strings.HasSuffix(path, ".png"):
+		return "image/png"
+	case strings.HasSuffix(path, ".jpg"), strings.HasSuffix(path, ".jpeg"):
+		return "image/jpeg"
+	case strings.HasSuffix(path, ".webp"):
+		return "image/webp"
+	case strings.HasSuffix(path, ".gif"):
+		return "image/gif"
+	case strings.HasSuffix(path, ".pdf"):
+		return "application/pdf"
+	case strings.HasSuffix(path, ".mp3"):
+		return "audio/mpeg"
+	case strings.HasSuffix(path, ".wav"):
+		return "audio/wav"
+	default:
+		return fallback
+	}
+}
+
+// withResponseMetadata sets the response ID, model and creation time reported by a
+// stream chunk on its events. A chunk without other events yields a metadata event,
+// so the values are not lost.
+func withResponseMetadata(events []dto.StreamEvent, id, model string, created int64) []dto.StreamEvent {
+	if id == "" && model == "" && created == 0 {
+		return events
+	}
+	if len(events) == 0 {
+		return []dto.StreamEvent{{Type: dto.StreamEventMetadata, ID: id, Model: model, Created: created}}
+	}
+	for i := range events {
+		events[i].ID, events[i].Model, events[i].Created = id, model, created
+	}
+	return events
+}
@@ -0,0 +1,104 @@
+package adapter
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVolcengineSignerVectors(t *testing.T) {
+	signer := &VolcengineSigner{
+		AccessKeyID:     "AKLTtestaccesskey",
+		SecretAccessKey: "dGVzdHNlY3JldGtleQ==",
+		Service:         "cv",
+		Now:             func() time.Time { return time.Date(2024, 1, 15, 8, 30, 0, 0, time.UTC) },
+	}
+	tests := []struct {
+		name          string
+		method        string
+		url           string
+		contentType   string
+		body          string
+		payloadHash   string
+		authorization string
+	}{
+		{
+			name:          "submit task",
+			method:        http.MethodPost,
+			url:           "https://visual.volcengineapi.com?Action=CVSync2AsyncSubmitTask&Version=2022-08-31",
+			contentType:   "application/json",
+			body:          `{"req_key":"jimeng_ti2v_v30_pro","prompt":"a cat"}`,
+			payloadHash:   "4a83268f0172e1154643390b6254cf60d2395bf594daef24c3c64c74db45d912",
+			authorization: "HMAC-SHA256 Credential=AKLTtestaccesskey/20240115/cn-north-1/cv/request, SignedHeaders=content-type;host;x-content-sha256;x-date, Signature=d4845c648caef1d06a057d3a253f4b11dba473c344e6f9956fe55853e4415403",
+		},
+		{
+			name:          "unsorted query without body",
+			method:        http.MethodGet,
+			url:           "https://visual.volcengineapi.com/?Version=2022-08-31&Filter=a+b&Action=CVSync2AsyncGetResult",
+			payloadHash:   "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			authorization: "HMAC-SHA256 Credential=AKLTtestaccesskey/20240115/cn-north-1/cv/request, SignedHeaders=host;x-content-sha256;x-date, Signature=285e87a1efb4a8abb825eaec455ace63646c5ea10dd608e23bf34ca5be9838af",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.url, bytes.NewReader([]byte(tt.body)))
+			if err != nil {
+				t.Fatalf("NewRequest returned error: %v", err)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if err := signer.Sign(req, []byte(tt.body)); err != nil {
+				t.Fatalf("Sign returned error: %v", err)
+			}
+			if got := req.Header.Get("X-Date"); got != "20240115T083000Z" {
+				t.Fatalf("X-Date = %q", got)
+			}
+			if got := req.Header.Get("X-Content-Sha256"); got != tt.payloadHash {
+				t.Fatalf("X-Content-Sha256 = %q, want %q", got, tt.payloadHash)
+			}
+			if got := req.Header.Get("Authorization"); got != tt.authorization {
+				t.Fatalf("Authorization = %q\nwant %q", got, tt.authorization)
+			}
+		})
+	}
+}
+
+func TestJimengAdaptorCredentials(t *testing.T) {
+	adaptor := &JimengAdaptor{}
+	req, _ := http.NewRequest(http.MethodPost, "https://visual.volcengineapi.com?Action=CVSync2AsyncSubmitTask&Version=2022-08-31", nil)
+
+	config := &ProviderConfig{APIKey: "gateway-token"}
+	if err := adaptor.SetupHeaders(req, config, ModeVideo); err != nil {
+		t.Fatalf("SetupHeaders returned error: %v", err)
+	}
+	if err := adaptor.SignRequest(req, config, nil); err != nil {
+		t.Fatalf("SignRequest returned error: %v", err)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer gateway-token" {
+		t.Fatalf("expected a bearer token without signing credentials, got %q", got)
+	}
+
+	req.Header.Del("Authorization")
+	config.APIKey = "AKLTtestaccesskey:dGVzdHNlY3JldGtleQ=="
+	if err := adaptor.SetupHeaders(req, config, ModeVideo); err != nil {
+		t.Fatalf("SetupHeaders returned error: %v", err)
+	}
+	if err := adaptor.SignRequest(req, config, nil); err != nil {
+		t.Fatalf("SignRequest returned error: %v", err)
+	}
+	if got := req.Header.Get("Authorization"); !strings.HasPrefix(got, "HMAC-SHA256 Credential=AKLTtestaccesskey/") || !strings.Contains(got, "/cn-north-1/cv/") {
+		t.Fatalf("expected a request signed for cn-north-1, got %q", got)
+	}
+
+	// The configured region replaces the default.
+	config.Region = "ap-singapore-1"
+	if err := adaptor.SignRequest(req, config, nil); err != nil {
+		t.Fatalf("SignRequest returned error: %v", err)
+	}
+	if got := req.Header.Get("Authorization"); !strings.Contains(got, "/ap-singapore-1/cv/") {
+		t.Fatalf("expected a request signed for the configured region, got %q", got)
+	}
+}
@@ -0,0 +1,31 @@
+// Package omnigo provides response caching for Language Learning Model requests.
+// This file re-exports the cache types and constructors from the cache package.
+package omnigo
+
+import (
+	"github.com/YspCoder/omnigo/cache"
+)
+
+// Re-export cache types
+type (
+	// Cache stores serialized chat responses by key. Implement it to plug in
+	// an external store and install it with SetCache.
+	Cache = cache.Cache
+
+	// MemoryCache is the default in-memory LRU cache with a TTL.
+	MemoryCache = cache.MemoryCache
+
+	// DiskCache stores cached responses as files in a directory.
+	DiskCache = cache.DiskCache
+)
+
+var (
+	// NewMemoryCache creates an in-memory LRU cache with the given capacity and TTL.
+	NewMemoryCache = cache.NewMemoryCache
+
+	// NewDiskCache creates a file-backed cache in the given directory.
+	NewDiskCache = cache.NewDiskCache
+
+	// CacheKey returns the canonical cache key for a provider and chat request.
+	CacheKey = cache.Key
+)
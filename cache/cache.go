@@ -0,0 +1,65 @@
+// Package cache provides response caching for chat requests.
+// Responses are stored as serialized bytes under a key derived from a canonical
+// hash of the request, so any backend that can store bytes can implement Cache.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/YspCoder/omnigo/dto"
+)
+
+// Cache stores serialized chat responses by key.
+type Cache interface {
+	// Get returns the cached value for key. ok is false on a miss or expired entry.
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+
+	// Set stores value under key, replacing any existing entry.
+	Set(ctx context.Context, key string, value []byte) error
+}
+
+// Key returns a canonical hash of the request for the given provider.
+// The model, messages, prompt, options and schema all contribute to the key;
+// map keys are serialized in sorted order so equivalent requests hash equally.
+//
+// Parameters:
+//   - provider: Provider name, so identical requests to different providers do not collide
+//   - request: The chat request to hash
+//
+// Returns:
+//   - Hex-encoded SHA-256 key
+//   - An error if the request cannot be serialized
+func Key(provider string, request *dto.ChatRequest) (string, error) {
+	if request == nil {
+		return "", fmt.Errorf("request is required")
+	}
+	canonical := struct {
+		Provider    string                 `json:"provider"`
+		Model       string                 `json:"model"`
+		Messages    []dto.Message          `json:"messages"`
+		Prompt      string                 `json:"prompt"`
+		Temperature float64                `json:"temperature"`
+		MaxTokens   int                    `json:"max_tokens"`
+		Options     map[string]interface{} `json:"options"`
+		Schema      interface{}            `json:"schema"`
+	}{
+		Provider:    provider,
+		Model:       request.Model,
+		Messages:    request.Messages,
+		Prompt:      request.Prompt,
+		Temperature: request.Temperature,
+		MaxTokens:   request.MaxTokens,
+		Options:     request.Options,
+		Schema:      request.Schema,
+	}
+	data, err := json.Marshal(canonical)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize request for cache key: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
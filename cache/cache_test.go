@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/YspCoder/omnigo/dto"
+)
+
+func TestKeyIsCanonical(t *testing.T) {
+	first := &dto.ChatRequest{Model: "m", Messages: []dto.Message{{Role: "user", Content: "hi"}},
+		Options: map[string]interface{}{"temperature": 0.2, "max_tokens": 10}}
+	second := &dto.ChatRequest{Model: "m", Messages: []dto.Message{{Role: "user", Content: "hi"}},
+		Options: map[string]interface{}{"max_tokens": 10, "temperature": 0.2}}
+
+	a, err := Key("openai", first)
+	if err != nil {
+		t.Fatalf("Key returned error: %v", err)
+	}
+	b, _ := Key("openai", second)
+	if a != b {
+		t.Fatalf("expected equal keys for equivalent requests")
+	}
+	if c, _ := Key("anthropic", first); c == a {
+		t.Fatalf("expected provider to change the key")
+	}
+}
+
+func TestMemoryCacheEvictsAndExpires(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(2, time.Minute)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	_ = c.Set(ctx, "a", []byte("1"))
+	_ = c.Set(ctx, "b", []byte("2"))
+	if _, ok, _ := c.Get(ctx, "a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	_ = c.Set(ctx, "c", []byte("3"))
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Fatal("expected least recently used entry b to be evicted")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Fatal("expected a to expire")
+	}
+}
+
+func TestDiskCacheRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDiskCache(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("NewDiskCache returned error: %v", err)
+	}
+	if _, ok, err := c.Get(ctx, "missing"); ok || err != nil {
+		t.Fatalf("expected clean miss, got ok=%v err=%v", ok, err)
+	}
+	if err := c.Set(ctx, "k", []byte(`{"id":"x"}`)); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	value, ok, err := c.Get(ctx, "k")
+	if err != nil || !ok || string(value) != `{"id":"x"}` {
+		t.Fatalf("unexpected entry %q ok=%v err=%v", value, ok, err)
+	}
+}
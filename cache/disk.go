@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DiskCache stores entries as files in a directory, one file per key.
+// Entries older than the TTL (by file modification time) are treated as misses and removed.
+type DiskCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// NewDiskCache creates a cache rooted at dir, creating the directory if needed.
+//
+// Parameters:
+//   - dir: Directory holding cache files
+//   - ttl: Lifetime of each entry; zero keeps entries indefinitely
+//
+// Returns:
+//   - The disk cache
+//   - An error if the directory cannot be created
+func NewDiskCache(dir string, ttl time.Duration) (*DiskCache, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("cache directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &DiskCache{dir: dir, ttl: ttl}, nil
+}
+
+// Get reads the entry for key from disk.
+func (c *DiskCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	path := c.path(key)
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if c.ttl > 0 && time.Since(info.ModTime()) > c.ttl {
+		_ = os.Remove(path)
+		return nil, false, nil
+	}
+	value, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set writes the entry atomically by renaming a temporary file into place.
+func (c *DiskCache) Set(ctx context.Context, key string, value []byte) error {
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+func (c *DiskCache) path(key string) string {
+	return filepath.Join(c.dir, filepath.Base(key)+".json")
+}
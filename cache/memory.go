@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultCapacity is the number of entries kept by a MemoryCache when none is given.
+const DefaultCapacity = 1000
+
+// MemoryCache is an in-memory LRU cache with an optional per-entry TTL.
+// It is safe for concurrent use.
+type MemoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	entries  map[string]*list.Element
+	order    *list.List
+	now      func() time.Time
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemoryCache creates an LRU cache holding at most capacity entries.
+//
+// Parameters:
+//   - capacity: Maximum number of entries; DefaultCapacity if not positive
+//   - ttl: Lifetime of each entry; zero keeps entries until evicted
+func NewMemoryCache(capacity int, ttl time.Duration) *MemoryCache {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &MemoryCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// Get returns the cached value and marks it as recently used.
+func (c *MemoryCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && c.now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+// Set stores the value, evicting the least recently used entry when full.
+func (c *MemoryCache) Set(ctx context.Context, key string, value []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expiresAt time.Time
+	if c.ttl > 0 {
+		expiresAt = c.now().Add(c.ttl)
+	}
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*memoryEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(&memoryEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryEntry).key)
+	}
+	return nil
+}
+
+// Len returns the number of entries currently held, including expired ones not yet evicted.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
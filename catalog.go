@@ -0,0 +1,28 @@
+// Package omnigo provides model metadata for Language Learning Models.
+// This file re-exports the model catalog with context limits, features and prices.
+package omnigo
+
+import (
+	"github.com/YspCoder/omnigo/catalog"
+)
+
+// Re-export model catalog types
+type (
+	// ModelInfo describes a model's context window, output limit, features and prices.
+	ModelInfo = catalog.Model
+
+	// ModelCatalog is a set of models keyed by provider and name.
+	ModelCatalog = catalog.Catalog
+
+	// ModelCatalogFile is the layout of a JSON or YAML catalog file.
+	ModelCatalogFile = catalog.File
+)
+
+// Re-export model catalog constructors
+var (
+	// NewModelCatalog creates a catalog containing the given models.
+	NewModelCatalog = catalog.New
+
+	// DefaultModelCatalog returns the process-wide catalog of known models.
+	DefaultModelCatalog = catalog.Default
+)
@@ -0,0 +1,184 @@
+// Package catalog describes individual models: their context window, output limit,
+// supported features and list prices. The default catalog covers the models of the
+// built-in providers and can be extended or overridden from a JSON or YAML file.
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Model describes a single model. Zero limits and prices mean unknown.
+type Model struct {
+	Provider        string  `json:"provider" yaml:"provider"`
+	Name            string  `json:"name" yaml:"name"` // Also matches dated variants such as gpt-4o-2024-08-06
+	ContextWindow   int     `json:"context_window,omitempty" yaml:"context_window,omitempty"`
+	MaxOutputTokens int     `json:"max_output_tokens,omitempty" yaml:"max_output_tokens,omitempty"`
+	Vision          bool    `json:"vision,omitempty" yaml:"vision,omitempty"`
+	Tools           bool    `json:"tools,omitempty" yaml:"tools,omitempty"`
+	JSONSchema      bool    `json:"json_schema,omitempty" yaml:"json_schema,omitempty"`
+	Streaming       bool    `json:"streaming,omitempty" yaml:"streaming,omitempty"`
+	Reasoning       bool    `json:"reasoning,omitempty" yaml:"reasoning,omitempty"`
+	InputPrice      float64 `json:"input_price,omitempty" yaml:"input_price,omitempty"`         // USD per million input tokens
+	OutputPrice     float64 `json:"output_price,omitempty" yaml:"output_price,omitempty"`       // USD per million output tokens
+	ImagePrice      float64 `json:"image_price,omitempty" yaml:"image_price,omitempty"`         // USD per generated image
+	VideoPrice      float64 `json:"video_price,omitempty" yaml:"video_price,omitempty"`         // USD per second of generated video
+	PerVideoPrice   float64 `json:"per_video_price,omitempty" yaml:"per_video_price,omitempty"` // USD per generated video, for providers billing by clip
+}
+
+// File is the layout of a catalog file.
+type File struct {
+	Models []Model `json:"models" yaml:"models"`
+}
+
+// Catalog is a set of models keyed by provider and name. It is safe for concurrent use.
+type Catalog struct {
+	mu     sync.RWMutex
+	models map[string]Model
+}
+
+// New creates a catalog containing the given models.
+func New(models ...Model) *Catalog {
+	c := &Catalog{models: make(map[string]Model, len(models))}
+	for _, model := range models {
+		c.Register(model)
+	}
+	return c
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultCatalog *Catalog
+)
+
+// Default returns the process-wide catalog, pre-populated with the known models.
+// Models registered on it are seen by every LLM that does not set its own catalog.
+func Default() *Catalog {
+	defaultOnce.Do(func() {
+		defaultCatalog = New(knownModels...)
+	})
+	return defaultCatalog
+}
+
+func key(provider, name string) string {
+	return strings.ToLower(provider) + "/" + strings.ToLower(name)
+}
+
+// Register adds a model or replaces the entry with the same provider and name.
+func (c *Catalog) Register(model Model) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.models[key(model.Provider, model.Name)] = model
+}
+
+// Models returns all entries.
+func (c *Catalog) Models() []Model {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	models := make([]Model, 0, len(c.models))
+	for _, model := range c.models {
+		models = append(models, model)
+	}
+	return models
+}
+
+// Clone returns an independent copy of the catalog.
+func (c *Catalog) Clone() *Catalog {
+	return New(c.Models()...)
+}
+
+// Lookup finds the entry for a model. Names match exactly or as the prefix of a
+// dated or suffixed variant ("gpt-4o" matches "gpt-4o-2024-08-06"); the longest
+// match wins. Entries of other providers are searched when the provider has no
+// match, so OpenAI-compatible providers such as azure-openai find OpenAI models.
+//
+// Parameters:
+//   - provider: Provider name, e.g. "openai"
+//   - model: Model name as sent to the provider
+//
+// Returns:
+//   - The model entry
+//   - Whether an entry was found
+func (c *Catalog) Lookup(provider, model string) (Model, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if found, ok := c.lookup(strings.ToLower(provider), strings.ToLower(model)); ok {
+		return found, true
+	}
+	return c.lookup("", strings.ToLower(model))
+}
+
+// lookup returns the longest matching entry of provider, or of any provider when
+// provider is empty.
+func (c *Catalog) lookup(provider, model string) (Model, bool) {
+	var best Model
+	bestLen := -1
+	for k, entry := range c.models {
+		entryProvider, name, _ := strings.Cut(k, "/")
+		if provider != "" && entryProvider != provider {
+			continue
+		}
+		if model != name && !strings.HasPrefix(model, name+"-") {
+			continue
+		}
+		// Ties between providers resolve by name so lookups are deterministic.
+		if len(name) > bestLen || (len(name) == bestLen && k < key(best.Provider, best.Name)) {
+			best, bestLen = entry, len(name)
+		}
+	}
+	return best, bestLen >= 0
+}
+
+// Load reads models in the File layout and registers them, replacing entries
+// with the same provider and name.
+//
+// Parameters:
+//   - r: The catalog data
+//   - format: "json" or "yaml"
+//
+// Returns:
+//   - An error if the data cannot be decoded or an entry lacks a provider or name
+func (c *Catalog) Load(r io.Reader, format string) error {
+	var file File
+	switch strings.ToLower(format) {
+	case "json":
+		if err := json.NewDecoder(r).Decode(&file); err != nil {
+			return err
+		}
+	case "yaml", "yml":
+		if err := yaml.NewDecoder(r).Decode(&file); err != nil && err != io.EOF {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported catalog format %q", format)
+	}
+	for i, model := range file.Models {
+		if model.Provider == "" || model.Name == "" {
+			return fmt.Errorf("model %d: provider and name are required", i)
+		}
+	}
+	for _, model := range file.Models {
+		c.Register(model)
+	}
+	return nil
+}
+
+// LoadFile loads a .json, .yaml or .yml catalog file.
+func (c *Catalog) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := c.Load(file, strings.TrimPrefix(filepath.Ext(path), ".")); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package catalog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLookupAndLoad(t *testing.T) {
+	c := Default().Clone()
+
+	model, ok := c.Lookup("openai", "gpt-4o-mini-2024-07-18")
+	if !ok || model.Name != "gpt-4o-mini" {
+		t.Fatalf("Lookup matched %q, %v; want gpt-4o-mini", model.Name, ok)
+	}
+	if model, ok := c.Lookup("azure-openai", "gpt-4o"); !ok || model.Provider != "openai" {
+		t.Fatalf("OpenAI-compatible provider should fall back to OpenAI entries, got %+v", model)
+	}
+	if _, ok := c.Lookup("openai", "gpt-4oo"); ok {
+		t.Fatal("names must match whole segments")
+	}
+
+	err := c.Load(strings.NewReader(`
+models:
+  - provider: openai
+    name: gpt-4o
+    context_window: 1000
+    input_price: 1
+  - provider: local
+    name: llama3
+    tools: true
+`), "yaml")
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if model, _ := c.Lookup("openai", "gpt-4o"); model.ContextWindow != 1000 || model.Vision {
+		t.Fatalf("file entry should replace the built-in one, got %+v", model)
+	}
+	if model, ok := c.Lookup("local", "llama3"); !ok || !model.Tools {
+		t.Fatalf("file entry not registered: %+v", model)
+	}
+	if model, _ := Default().Lookup("openai", "gpt-4o"); model.ContextWindow != 128000 {
+		t.Fatal("loading into a clone must not change the default catalog")
+	}
+	if err := c.Load(strings.NewReader(`{"models":[{"name":"x"}]}`), "json"); err == nil {
+		t.Fatal("expected an error for an entry without provider")
+	}
+}
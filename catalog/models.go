@@ -0,0 +1,55 @@
+package catalog
+
+// knownModels are the pre-populated entries. Prices are list prices in USD per
+// million tokens, or per standard-size image, at the time of writing; override them with a catalog file when
+// they change or when a contract price applies.
+var knownModels = []Model{
+	// OpenAI
+	{Provider: "openai", Name: "gpt-4o", ContextWindow: 128000, MaxOutputTokens: 16384, Vision: true, Tools: true, JSONSchema: true, Streaming: true, InputPrice: 2.50, OutputPrice: 10.00},
+	{Provider: "openai", Name: "gpt-4o-mini", ContextWindow: 128000, MaxOutputTokens: 16384, Vision: true, Tools: true, JSONSchema: true, Streaming: true, InputPrice: 0.15, OutputPrice: 0.60},
+	{Provider: "openai", Name: "gpt-4.1", ContextWindow: 1047576, MaxOutputTokens: 32768, Vision: true, Tools: true, JSONSchema: true, Streaming: true, InputPrice: 2.00, OutputPrice: 8.00},
+	{Provider: "openai", Name: "gpt-4.1-mini", ContextWindow: 1047576, MaxOutputTokens: 32768, Vision: true, Tools: true, JSONSchema: true, Streaming: true, InputPrice: 0.40, OutputPrice: 1.60},
+	{Provider: "openai", Name: "gpt-4.1-nano", ContextWindow: 1047576, MaxOutputTokens: 32768, Vision: true, Tools: true, JSONSchema: true, Streaming: true, InputPrice: 0.10, OutputPrice: 0.40},
+	{Provider: "openai", Name: "gpt-4-turbo", ContextWindow: 128000, MaxOutputTokens: 4096, Vision: true, Tools: true, Streaming: true, InputPrice: 10.00, OutputPrice: 30.00},
+	{Provider: "openai", Name: "gpt-4", ContextWindow: 8192, MaxOutputTokens: 8192, Tools: true, Streaming: true, InputPrice: 30.00, OutputPrice: 60.00},
+	{Provider: "openai", Name: "gpt-3.5-turbo", ContextWindow: 16385, MaxOutputTokens: 4096, Tools: true, Streaming: true, InputPrice: 0.50, OutputPrice: 1.50},
+	{Provider: "openai", Name: "o1", ContextWindow: 200000, MaxOutputTokens: 100000, Vision: true, Tools: true, JSONSchema: true, Streaming: true, Reasoning: true, InputPrice: 15.00, OutputPrice: 60.00},
+	{Provider: "openai", Name: "o1-mini", ContextWindow: 128000, MaxOutputTokens: 65536, Streaming: true, Reasoning: true, InputPrice: 1.10, OutputPrice: 4.40},
+	{Provider: "openai", Name: "o3", ContextWindow: 200000, MaxOutputTokens: 100000, Vision: true, Tools: true, JSONSchema: true, Streaming: true, Reasoning: true, InputPrice: 2.00, OutputPrice: 8.00},
+	{Provider: "openai", Name: "o3-mini", ContextWindow: 200000, MaxOutputTokens: 100000, Tools: true, JSONSchema: true, Streaming: true, Reasoning: true, InputPrice: 1.10, OutputPrice: 4.40},
+	{Provider: "openai", Name: "o4-mini", ContextWindow: 200000, MaxOutputTokens: 100000, Vision: true, Tools: true, JSONSchema: true, Streaming: true, Reasoning: true, InputPrice: 1.10, OutputPrice: 4.40},
+	{Provider: "openai", Name: "dall-e-3", ImagePrice: 0.04},
+	{Provider: "openai", Name: "dall-e-2", ImagePrice: 0.02},
+
+	// Anthropic
+	{Provider: "anthropic", Name: "claude-opus-4", ContextWindow: 200000, MaxOutputTokens: 32000, Vision: true, Tools: true, Streaming: true, Reasoning: true, InputPrice: 15.00, OutputPrice: 75.00},
+	{Provider: "anthropic", Name: "claude-sonnet-4", ContextWindow: 200000, MaxOutputTokens: 64000, Vision: true, Tools: true, Streaming: true, Reasoning: true, InputPrice: 3.00, OutputPrice: 15.00},
+	{Provider: "anthropic", Name: "claude-3-7-sonnet", ContextWindow: 200000, MaxOutputTokens: 64000, Vision: true, Tools: true, Streaming: true, Reasoning: true, InputPrice: 3.00, OutputPrice: 15.00},
+	{Provider: "anthropic", Name: "claude-3-5-sonnet", ContextWindow: 200000, MaxOutputTokens: 8192, Vision: true, Tools: true, Streaming: true, InputPrice: 3.00, OutputPrice: 15.00},
+	{Provider: "anthropic", Name: "claude-3-5-haiku", ContextWindow: 200000, MaxOutputTokens: 8192, Tools: true, Streaming: true, InputPrice: 0.80, OutputPrice: 4.00},
+	{Provider: "anthropic", Name: "claude-3-opus", ContextWindow: 200000, MaxOutputTokens: 4096, Vision: true, Tools: true, Streaming: true, InputPrice: 15.00, OutputPrice: 75.00},
+	{Provider: "anthropic", Name: "claude-3-haiku", ContextWindow: 200000, MaxOutputTokens: 4096, Vision: true, Tools: true, Streaming: true, InputPrice: 0.25, OutputPrice: 1.25},
+
+	// Google
+	{Provider: "google", Name: "gemini-2.5-pro", ContextWindow: 1048576, MaxOutputTokens: 65536, Vision: true, Tools: true, JSONSchema: true, Streaming: true, Reasoning: true, InputPrice: 1.25, OutputPrice: 10.00},
+	{Provider: "google", Name: "gemini-2.5-flash", ContextWindow: 1048576, MaxOutputTokens: 65536, Vision: true, Tools: true, JSONSchema: true, Streaming: true, Reasoning: true, InputPrice: 0.30, OutputPrice: 2.50},
+	{Provider: "google", Name: "gemini-2.0-flash", ContextWindow: 1048576, MaxOutputTokens: 8192, Vision: true, Tools: true, JSONSchema: true, Streaming: true, InputPrice: 0.10, OutputPrice: 0.40},
+	{Provider: "google", Name: "gemini-1.5-pro", ContextWindow: 2097152, MaxOutputTokens: 8192, Vision: true, Tools: true, JSONSchema: true, Streaming: true, InputPrice: 1.25, OutputPrice: 5.00},
+	{Provider: "google", Name: "gemini-1.5-flash", ContextWindow: 1048576, MaxOutputTokens: 8192, Vision: true, Tools: true, JSONSchema: true, Streaming: true, InputPrice: 0.075, OutputPrice: 0.30},
+
+	// Ali / DashScope (international list prices)
+	{Provider: "ali", Name: "qwen-max", ContextWindow: 32768, MaxOutputTokens: 8192, Tools: true, Streaming: true, InputPrice: 1.60, OutputPrice: 6.40},
+	{Provider: "ali", Name: "qwen-plus", ContextWindow: 131072, MaxOutputTokens: 8192, Tools: true, Streaming: true, InputPrice: 0.40, OutputPrice: 1.20},
+	{Provider: "ali", Name: "qwen-turbo", ContextWindow: 1000000, MaxOutputTokens: 8192, Tools: true, Streaming: true, InputPrice: 0.05, OutputPrice: 0.20},
+	{Provider: "ali", Name: "qwen-vl-max", ContextWindow: 131072, MaxOutputTokens: 8192, Vision: true, Streaming: true, InputPrice: 0.80, OutputPrice: 3.20},
+	{Provider: "ali", Name: "qwen-vl-plus", ContextWindow: 131072, MaxOutputTokens: 8192, Vision: true, Streaming: true, InputPrice: 0.21, OutputPrice: 0.63},
+
+	// Groq
+	{Provider: "groq", Name: "llama-3.3-70b-versatile", ContextWindow: 131072, MaxOutputTokens: 32768, Tools: true, Streaming: true, InputPrice: 0.59, OutputPrice: 0.79},
+	{Provider: "groq", Name: "llama-3.1-8b-instant", ContextWindow: 131072, MaxOutputTokens: 131072, Tools: true, Streaming: true, InputPrice: 0.05, OutputPrice: 0.08},
+
+	// Moonshot (prices are quoted in CNY and left unset)
+	{Provider: "moonshot", Name: "moonshot-v1-8k", ContextWindow: 8192, Tools: true, Streaming: true},
+	{Provider: "moonshot", Name: "moonshot-v1-32k", ContextWindow: 32768, Tools: true, Streaming: true},
+	{Provider: "moonshot", Name: "moonshot-v1-128k", ContextWindow: 131072, Tools: true, Streaming: true},
+}
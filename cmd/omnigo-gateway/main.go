@@ -0,0 +1,76 @@
+// Command omnigo-gateway serves an OpenAI-compatible HTTP API in front of the
+// providers supported by omnigo.
+//
+// Usage:
+//
+//	omnigo-gateway -config gateway.yaml [-addr :8080] [-log-level info]
+//
+// The configuration file lists the routes and, optionally, the API keys accepted
+// by the gateway; see the gateway package for its layout.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/YspCoder/omnigo/gateway"
+	"github.com/YspCoder/omnigo/utils"
+)
+
+func main() {
+	configPath := flag.String("config", "gateway.yaml", "JSON or YAML gateway configuration")
+	addr := flag.String("addr", "", "listen address (overrides the configuration, default :8080)")
+	logLevel := flag.String("log-level", "info", "log level: off, error, warn, info or debug")
+	flag.Parse()
+
+	var level utils.LogLevel
+	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+		log.Fatalf("invalid log level: %v", err)
+	}
+	logger := utils.NewJSONLogger(os.Stderr, level)
+
+	cfg, err := gateway.LoadConfigFile(*configPath)
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+	if *addr != "" {
+		cfg.Addr = *addr
+	}
+	if cfg.Addr == "" {
+		cfg.Addr = ":8080"
+	}
+	if len(cfg.Keys) == 0 {
+		logger.Warn("No API keys configured; the gateway accepts unauthenticated requests")
+	}
+
+	handler, err := gateway.New(cfg, gateway.WithLogger(logger))
+	if err != nil {
+		log.Fatalf("failed to create gateway: %v", err)
+	}
+	server := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info("Gateway listening", "addr", cfg.Addr, "routes", len(cfg.Routes), "keys", len(cfg.Keys))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("gateway stopped: %v", err)
+	}
+}
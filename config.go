@@ -69,6 +69,10 @@ var (
 	SetEndpoint = config.SetEndpoint // Sets a custom endpoint for the selected provider
 	SetAPIKey   = config.SetAPIKey   // Sets the API key for the current provider
 
+	SetAccessKey    = config.SetAccessKey    // Sets an access key pair for providers that sign requests (jimeng)
+	SetRegion       = config.SetRegion       // Sets the signing region of providers that sign requests (jimeng)
+	SetChatProtocol = config.SetChatProtocol // Overrides the chat protocol ("openai" or "native")
+
 	// Generation parameters
 	SetTemperature      = config.SetTemperature      // Controls randomness in generation (0.0-1.0)
 	SetMaxTokens        = config.SetMaxTokens        // Sets maximum tokens to generate
@@ -87,14 +91,39 @@ var (
 	SetTfsZ          = config.SetTfsZ          // Sets tail-free sampling parameter
 
 	// Runtime configuration
-	SetTimeout      = config.SetTimeout      // Sets request timeout duration
-	SetMaxRetries   = config.SetMaxRetries   // Sets maximum retry attempts
-	SetRetryDelay   = config.SetRetryDelay   // Sets delay between retries
-	SetLogLevel     = config.SetLogLevel     // Sets logging verbosity
-	SetExtraHeaders = config.SetExtraHeaders // Sets additional HTTP headers
+	SetTimeout       = config.SetTimeout       // Sets request timeout duration
+	SetMaxRetries    = config.SetMaxRetries    // Sets maximum retry attempts
+	SetRetryDelay    = config.SetRetryDelay    // Sets delay between retries
+	SetRetryMaxDelay = config.SetRetryMaxDelay // Caps a single retry delay
+	SetRetryBudget   = config.SetRetryBudget   // Limits total time spent retrying
+	SetRetryPolicy   = config.SetRetryPolicy   // Installs a custom retry policy
+	SetRateLimit     = config.SetRateLimit     // Sets client-side requests/tokens per minute
+	SetMaxInFlight   = config.SetMaxInFlight   // Caps concurrent requests
+	SetRateLimiter   = config.SetRateLimiter   // Installs a shared rate limiter
+	SetContextWindow = config.SetContextWindow // Checks prompts against the context window
+	SetTokenizerFile = config.SetTokenizerFile // Counts tokens with a local .tiktoken file
+	SetTokenizer     = config.SetTokenizer     // Installs a custom tokenizer
+	SetMiddleware    = config.SetMiddleware    // Wraps provider requests with middlewares
+	SetTelemetry     = config.SetTelemetry     // Enables OpenTelemetry traces and metrics
+	SetLogLevel      = config.SetLogLevel      // Sets logging verbosity
+	SetLogFormat     = config.SetLogFormat     // Selects slog text or JSON output
+	SetSlogLogger    = config.SetSlogLogger    // Sends logs to a *slog.Logger
+	SetExtraHeaders  = config.SetExtraHeaders  // Sets additional HTTP headers
+
+	// Model catalog
+	SetModelCatalog     = config.SetModelCatalog     // Replaces the model catalog
+	SetModelCatalogFile = config.SetModelCatalogFile // Adds model catalog entries from a file
+
+	// Cost accounting
+	SetBudget      = config.SetBudget      // Fails requests once a spend limit is reached
+	SetCostTracker = config.SetCostTracker // Records spend in a shared tracker
+	SetPrices      = config.SetPrices      // Overrides the catalog prices
 
 	// Feature toggles
 	SetEnableCaching = config.SetEnableCaching // Enables/disables response caching
+	SetCacheTTL      = config.SetCacheTTL      // Sets how long cached responses remain valid
+	SetCacheDir      = config.SetCacheDir      // Stores cached responses on disk
+	SetCache         = config.SetCache         // Installs a custom response cache
 
 	// Configuration creation
 	NewConfig = config.NewConfig // Creates a new Config with default values
@@ -4,12 +4,22 @@
 package config
 
 import (
+	"log/slog"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/YspCoder/omnigo/cache"
+	"github.com/YspCoder/omnigo/catalog"
+	"github.com/YspCoder/omnigo/cost"
+	"github.com/YspCoder/omnigo/ratelimit"
+	"github.com/YspCoder/omnigo/relay"
+	"github.com/YspCoder/omnigo/retry"
+	"github.com/YspCoder/omnigo/tokenizer"
 	"github.com/YspCoder/omnigo/utils"
 	"github.com/caarlos0/env/v11"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Config represents the complete configuration for LLM interactions.
@@ -21,6 +31,8 @@ import (
 //   - LLM_PROVIDER: LLM provider name (default: "openai")
 //   - LLM_MODEL: Model name (default: "gpt-4o-mini")
 //   - LLM_ENDPOINT: Override provider endpoint/base URL
+//   - LLM_CHAT_PROTOCOL: Chat protocol override ("openai" or "native"), e.g. native DashScope for ali
+//   - LLM_REGION: Signing region of providers that sign requests, e.g. cn-north-1 for jimeng
 //   - LLM_TEMPERATURE: Generation temperature (default: 0.7)
 //   - LLM_MAX_TOKENS: Maximum tokens to generate (default: 100)
 //   - LLM_TOP_P: Top-p sampling parameter (default: 0.9)
@@ -28,10 +40,22 @@ import (
 //   - LLM_PRESENCE_PENALTY: Token presence penalty (default: 0.0)
 //   - LLM_TIMEOUT: Request timeout duration (default: 30s)
 //   - LLM_MAX_RETRIES: Maximum retry attempts (default: 3)
-//   - LLM_RETRY_DELAY: Delay between retries (default: 2s)
+//   - LLM_RETRY_DELAY: Initial delay of the exponential retry backoff (default: 2s)
+//   - LLM_RETRY_MAX_DELAY: Upper bound for a single retry delay (default: 30s)
+//   - LLM_RETRY_BUDGET: Total time allowed across retries; 0 disables the budget
+//   - LLM_RATE_LIMIT_RPM: Client-side limit of requests per minute per provider/model; 0 disables it
+//   - LLM_RATE_LIMIT_TPM: Client-side limit of tokens per minute per provider/model; 0 disables it
+//   - LLM_MAX_IN_FLIGHT: Maximum concurrent requests per provider/model; 0 disables it
+//   - LLM_CONTEXT_WINDOW: Model context window in tokens; prompts that do not fit are rejected before sending
+//   - LLM_TOKENIZER_FILE: Local .tiktoken file for exact token counts; the encoding is derived from LLM_MODEL
+//   - LLM_MODEL_CATALOG: JSON or YAML file adding to or overriding the built-in model catalog
+//   - LLM_BUDGET: Spend limit in USD for this configuration; requests fail once it is reached
 //   - LLM_LOG_LEVEL: Logging verbosity (default: "WARN")
+//   - LLM_LOG_FORMAT: Log output format: "text" or "json" via log/slog; empty uses the plain logger
 //   - LLM_SEED: Random seed for reproducible generation
 //   - LLM_ENABLE_CACHING: Enable response caching (default: false)
+//   - LLM_CACHE_TTL: Lifetime of cached responses (default: 1h)
+//   - LLM_CACHE_DIR: Store cached responses on disk in this directory instead of memory
 //   - LLM_ENABLE_STREAMING: Enable streaming responses (default: false)
 //
 // Advanced Parameters:
@@ -43,32 +67,57 @@ import (
 //   - LLM_MIROSTAT_TAU: Mirostat target entropy
 //   - LLM_TFS_Z: Tail-free sampling parameter
 type Config struct {
-	Provider              string            `env:"LLM_PROVIDER" envDefault:"openai" validate:"required"`
-	Model                 string            `env:"LLM_MODEL" envDefault:"gpt-4o-mini" validate:"required"`
-	Endpoint              string            `env:"LLM_ENDPOINT"`
-	Temperature           float64           `env:"LLM_TEMPERATURE" envDefault:"0.7" validate:"gte=0,lte=1"`
-	MaxTokens             int               `env:"LLM_MAX_TOKENS" envDefault:"100"`
-	TopP                  float64           `env:"LLM_TOP_P" envDefault:"0.9" validate:"gte=0,lte=1"`
-	FrequencyPenalty      float64           `env:"LLM_FREQUENCY_PENALTY" envDefault:"0.0"`
-	PresencePenalty       float64           `env:"LLM_PRESENCE_PENALTY" envDefault:"0.0"`
-	Timeout               time.Duration     `env:"LLM_TIMEOUT" envDefault:"30s"`
-	MaxRetries            int               `env:"LLM_MAX_RETRIES" envDefault:"3"`
-	RetryDelay            time.Duration     `env:"LLM_RETRY_DELAY" envDefault:"2s"`
-	APIKeys               map[string]string `validate:"required,apikey"`
-	LogLevel              utils.LogLevel    `env:"LLM_LOG_LEVEL" envDefault:"WARN"`
-	Seed                  *int              `env:"LLM_SEED"`
-	MinP                  *float64          `env:"LLM_MIN_P" envDefault:"0.05"`
-	RepeatPenalty         *float64          `env:"LLM_REPEAT_PENALTY" envDefault:"1.1"`
-	RepeatLastN           *int              `env:"LLM_REPEAT_LAST_N" envDefault:"64"`
-	Mirostat              *int              `env:"LLM_MIROSTAT" envDefault:"0"`
-	MirostatEta           *float64          `env:"LLM_MIROSTAT_ETA" envDefault:"0.1"`
-	MirostatTau           *float64          `env:"LLM_MIROSTAT_TAU" envDefault:"5.0"`
-	TfsZ                  *float64          `env:"LLM_TFS_Z" envDefault:"1"`
+	Provider              string               `env:"LLM_PROVIDER" envDefault:"openai" validate:"required"`
+	Model                 string               `env:"LLM_MODEL" envDefault:"gpt-4o-mini" validate:"required"`
+	Endpoint              string               `env:"LLM_ENDPOINT"`
+	ChatProtocol          string               `env:"LLM_CHAT_PROTOCOL"`
+	Region                string               `env:"LLM_REGION"`
+	Temperature           float64              `env:"LLM_TEMPERATURE" envDefault:"0.7" validate:"gte=0,lte=1"`
+	MaxTokens             int                  `env:"LLM_MAX_TOKENS" envDefault:"100"`
+	TopP                  float64              `env:"LLM_TOP_P" envDefault:"0.9" validate:"gte=0,lte=1"`
+	FrequencyPenalty      float64              `env:"LLM_FREQUENCY_PENALTY" envDefault:"0.0"`
+	PresencePenalty       float64              `env:"LLM_PRESENCE_PENALTY" envDefault:"0.0"`
+	Timeout               time.Duration        `env:"LLM_TIMEOUT" envDefault:"30s"`
+	MaxRetries            int                  `env:"LLM_MAX_RETRIES" envDefault:"3"`
+	RetryDelay            time.Duration        `env:"LLM_RETRY_DELAY" envDefault:"2s"`
+	RetryMaxDelay         time.Duration        `env:"LLM_RETRY_MAX_DELAY" envDefault:"30s"`
+	RetryBudget           time.Duration        `env:"LLM_RETRY_BUDGET"`
+	RetryPolicy           retry.Policy         `env:"-"`
+	RateLimitRPM          int                  `env:"LLM_RATE_LIMIT_RPM"`
+	RateLimitTPM          int                  `env:"LLM_RATE_LIMIT_TPM"`
+	MaxInFlight           int                  `env:"LLM_MAX_IN_FLIGHT"`
+	RateLimiter           *ratelimit.Limiter   `env:"-"`
+	ContextWindow         int                  `env:"LLM_CONTEXT_WINDOW"`
+	TokenizerFile         string               `env:"LLM_TOKENIZER_FILE"`
+	Tokenizer             tokenizer.Tokenizer  `env:"-"`
+	ModelCatalogFile      string               `env:"LLM_MODEL_CATALOG"`
+	ModelCatalog          *catalog.Catalog     `env:"-"`
+	Budget                float64              `env:"LLM_BUDGET"`
+	CostTracker           *cost.Tracker        `env:"-"`
+	Prices                *cost.Prices         `env:"-"`
+	Middlewares           []relay.Middleware   `env:"-"`
+	TracerProvider        trace.TracerProvider `env:"-"`
+	MeterProvider         metric.MeterProvider `env:"-"`
+	APIKeys               map[string]string    `validate:"required,apikey"`
+	LogLevel              utils.LogLevel       `env:"LLM_LOG_LEVEL" envDefault:"WARN"`
+	LogFormat             string               `env:"LLM_LOG_FORMAT"`
+	Logger                *slog.Logger         `env:"-"`
+	Seed                  *int                 `env:"LLM_SEED"`
+	MinP                  *float64             `env:"LLM_MIN_P" envDefault:"0.05"`
+	RepeatPenalty         *float64             `env:"LLM_REPEAT_PENALTY" envDefault:"1.1"`
+	RepeatLastN           *int                 `env:"LLM_REPEAT_LAST_N" envDefault:"64"`
+	Mirostat              *int                 `env:"LLM_MIROSTAT" envDefault:"0"`
+	MirostatEta           *float64             `env:"LLM_MIROSTAT_ETA" envDefault:"0.1"`
+	MirostatTau           *float64             `env:"LLM_MIROSTAT_TAU" envDefault:"5.0"`
+	TfsZ                  *float64             `env:"LLM_TFS_Z" envDefault:"1"`
 	SystemPrompt          string
 	SystemPromptCacheType string
 	ExtraHeaders          map[string]string
-	EnableCaching         bool `env:"LLM_ENABLE_CACHING" envDefault:"false"`
-	EnableStreaming       bool `env:"LLM_ENABLE_STREAMING" envDefault:"false"`
+	EnableCaching         bool          `env:"LLM_ENABLE_CACHING" envDefault:"false"`
+	EnableStreaming       bool          `env:"LLM_ENABLE_STREAMING" envDefault:"false"`
+	CacheTTL              time.Duration `env:"LLM_CACHE_TTL" envDefault:"1h"`
+	CacheDir              string        `env:"LLM_CACHE_DIR"`
+	Cache                 cache.Cache   `env:"-"`
 }
 
 // LoadConfig creates a new Config instance, loading values from environment
@@ -94,6 +143,7 @@ func LoadConfig() (*Config, error) {
 	}
 
 	cfg.Provider = strings.ToLower(cfg.Provider)
+	cfg.ChatProtocol = strings.ToLower(cfg.ChatProtocol)
 	loadAPIKeys(cfg)
 	return cfg, nil
 }
@@ -136,16 +186,18 @@ type ConfigOption func(*Config)
 //	)
 func NewConfig() *Config {
 	return &Config{
-		Provider:     "openai",
-		Model:        "gpt-4o-mini",
-		Temperature:  0.7,
-		MaxTokens:    300,
-		Timeout:      30 * time.Second,
-		MaxRetries:   3,
-		RetryDelay:   2 * time.Second,
-		APIKeys:      make(map[string]string),
-		LogLevel:     utils.LogLevelWarn,
-		ExtraHeaders: make(map[string]string),
+		Provider:      "openai",
+		Model:         "gpt-4o-mini",
+		Temperature:   0.7,
+		MaxTokens:     300,
+		Timeout:       30 * time.Second,
+		MaxRetries:    3,
+		RetryDelay:    2 * time.Second,
+		RetryMaxDelay: 30 * time.Second,
+		APIKeys:       make(map[string]string),
+		LogLevel:      utils.LogLevelWarn,
+		ExtraHeaders:  make(map[string]string),
+		CacheTTL:      time.Hour,
 	}
 }
 
@@ -156,6 +208,23 @@ func SetEndpoint(endpoint string) ConfigOption {
 	}
 }
 
+// SetChatProtocol overrides the chat protocol used for the provider.
+// Use "native" to call a provider's own API (e.g. DashScope text-generation for ali)
+// instead of its OpenAI-compatible endpoint.
+func SetChatProtocol(protocol string) ConfigOption {
+	return func(c *Config) {
+		c.ChatProtocol = strings.ToLower(protocol)
+	}
+}
+
+// SetRegion sets the signing region of providers that sign requests, such as
+// jimeng (Volcengine), which defaults to cn-north-1.
+func SetRegion(region string) ConfigOption {
+	return func(c *Config) {
+		c.Region = region
+	}
+}
+
 // SetEnableCaching sets the EnableCaching flag.
 func SetEnableCaching(enableCaching bool) ConfigOption {
 	return func(c *Config) {
@@ -163,6 +232,28 @@ func SetEnableCaching(enableCaching bool) ConfigOption {
 	}
 }
 
+// SetCacheTTL sets how long cached responses remain valid. Zero disables expiry.
+func SetCacheTTL(ttl time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.CacheTTL = ttl
+	}
+}
+
+// SetCacheDir stores cached responses on disk in dir instead of memory.
+func SetCacheDir(dir string) ConfigOption {
+	return func(c *Config) {
+		c.CacheDir = dir
+	}
+}
+
+// SetCache installs a custom response cache and enables caching.
+func SetCache(store cache.Cache) ConfigOption {
+	return func(c *Config) {
+		c.Cache = store
+		c.EnableCaching = store != nil
+	}
+}
+
 // SetProvider sets the LLM provider.
 func SetProvider(provider string) ConfigOption {
 	return func(c *Config) {
@@ -217,6 +308,13 @@ func SetAPIKey(apiKey string) ConfigOption {
 	}
 }
 
+// SetAccessKey sets an access key pair for the current provider, for providers
+// that sign requests instead of sending a bearer token, such as jimeng. The pair
+// is stored as the API key "<accessKeyID>:<secretAccessKey>".
+func SetAccessKey(accessKeyID, secretAccessKey string) ConfigOption {
+	return SetAPIKey(accessKeyID + ":" + secretAccessKey)
+}
+
 // SetMaxRetries sets the maximum number of retry attempts.
 func SetMaxRetries(maxRetries int) ConfigOption {
 	return func(c *Config) {
@@ -225,12 +323,141 @@ func SetMaxRetries(maxRetries int) ConfigOption {
 }
 
 // SetRetryDelay sets the delay between retries.
+// It is the initial delay of the exponential backoff; later retries wait longer.
 func SetRetryDelay(retryDelay time.Duration) ConfigOption {
 	return func(c *Config) {
 		c.RetryDelay = retryDelay
 	}
 }
 
+// SetRetryMaxDelay caps a single backoff delay. A provider Retry-After hint may exceed it.
+func SetRetryMaxDelay(maxDelay time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.RetryMaxDelay = maxDelay
+	}
+}
+
+// SetRetryBudget limits the total time spent retrying a call; 0 disables the budget.
+func SetRetryBudget(budget time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.RetryBudget = budget
+	}
+}
+
+// SetRetryPolicy installs a custom retry policy, replacing the default backoff
+// built from MaxRetries, RetryDelay, RetryMaxDelay and RetryBudget.
+func SetRetryPolicy(policy retry.Policy) ConfigOption {
+	return func(c *Config) {
+		c.RetryPolicy = policy
+	}
+}
+
+// SetRateLimit sets the client-side requests-per-minute and tokens-per-minute limits.
+// Instances with the same provider and model share one limiter; 0 disables a limit.
+func SetRateLimit(requestsPerMinute, tokensPerMinute int) ConfigOption {
+	return func(c *Config) {
+		c.RateLimitRPM = requestsPerMinute
+		c.RateLimitTPM = tokensPerMinute
+	}
+}
+
+// SetMaxInFlight caps the number of concurrent requests per provider and model.
+func SetMaxInFlight(maxInFlight int) ConfigOption {
+	return func(c *Config) {
+		c.MaxInFlight = maxInFlight
+	}
+}
+
+// SetRateLimiter installs a rate limiter, replacing the one shared per provider and
+// model. Pass the same limiter to several configs to make them share one quota.
+func SetRateLimiter(limiter *ratelimit.Limiter) ConfigOption {
+	return func(c *Config) {
+		c.RateLimiter = limiter
+	}
+}
+
+// SetContextWindow sets the model's context window in tokens. Prompts that do not
+// fit together with MaxTokens are truncated or rejected before they are sent.
+func SetContextWindow(tokens int) ConfigOption {
+	return func(c *Config) {
+		c.ContextWindow = tokens
+	}
+}
+
+// SetTokenizerFile counts tokens exactly with a local .tiktoken file whose
+// encoding matches the model, e.g. o200k_base.tiktoken for gpt-4o.
+func SetTokenizerFile(path string) ConfigOption {
+	return func(c *Config) {
+		c.TokenizerFile = path
+	}
+}
+
+// SetTokenizer installs the tokenizer used for token counts, replacing the
+// approximate counter.
+func SetTokenizer(t tokenizer.Tokenizer) ConfigOption {
+	return func(c *Config) {
+		c.Tokenizer = t
+	}
+}
+
+// SetModelCatalog replaces the process-wide model catalog used to look up context
+// limits, supported features and prices.
+func SetModelCatalog(c *catalog.Catalog) ConfigOption {
+	return func(cfg *Config) {
+		cfg.ModelCatalog = c
+	}
+}
+
+// SetModelCatalogFile loads a JSON or YAML file whose entries add to or override
+// the model catalog for this configuration.
+func SetModelCatalogFile(path string) ConfigOption {
+	return func(c *Config) {
+		c.ModelCatalogFile = path
+	}
+}
+
+// SetBudget sets a spend limit in USD. Once the LLM has spent it, Generate, Stream
+// and Media fail with ErrorTypeBudgetExceeded. It is ignored when SetCostTracker
+// installs a shared tracker; set the budget on that tracker instead.
+func SetBudget(usd float64) ConfigOption {
+	return func(c *Config) {
+		c.Budget = usd
+	}
+}
+
+// SetCostTracker records spend in a tracker shared with other LLMs, e.g. one per
+// tenant, so that their totals and budget are combined.
+func SetCostTracker(t *cost.Tracker) ConfigOption {
+	return func(c *Config) {
+		c.CostTracker = t
+	}
+}
+
+// SetPrices overrides the catalog prices used for cost accounting, e.g. with a
+// contract price or for a model the catalog does not list.
+func SetPrices(prices cost.Prices) ConfigOption {
+	return func(c *Config) {
+		c.Prices = &prices
+	}
+}
+
+// SetMiddleware appends middlewares wrapped around every provider HTTP request,
+// e.g. for signing, auditing or metrics. The first middleware is the outermost.
+func SetMiddleware(middlewares ...relay.Middleware) ConfigOption {
+	return func(c *Config) {
+		c.Middlewares = append(c.Middlewares, middlewares...)
+	}
+}
+
+// SetTelemetry enables OpenTelemetry spans and metrics for provider calls.
+// Either provider may be nil to record only the other signal.
+func SetTelemetry(tracerProvider trace.TracerProvider, meterProvider metric.MeterProvider) ConfigOption {
+	return func(c *Config) {
+		c.TracerProvider = tracerProvider
+		c.MeterProvider = meterProvider
+	}
+}
+
 // SetLogLevel sets the logging verbosity.
 func SetLogLevel(level utils.LogLevel) ConfigOption {
 	return func(c *Config) {
@@ -238,6 +465,37 @@ func SetLogLevel(level utils.LogLevel) ConfigOption {
 	}
 }
 
+// SetLogFormat selects the log output format: "text" or "json" write structured
+// records through log/slog to stderr; an empty format uses the plain logger.
+func SetLogFormat(format string) ConfigOption {
+	return func(c *Config) {
+		c.LogFormat = format
+	}
+}
+
+// SetSlogLogger sends log records to the given *slog.Logger. Records are still
+// filtered by LogLevel and have API keys redacted.
+func SetSlogLogger(logger *slog.Logger) ConfigOption {
+	return func(c *Config) {
+		c.Logger = logger
+	}
+}
+
+// NewLogger creates the logger described by cfg: the injected slog logger, a JSON or
+// text slog logger for LogFormat, or the plain logger.
+func NewLogger(cfg *Config) utils.Logger {
+	switch {
+	case cfg.Logger != nil:
+		return utils.NewSlogLogger(cfg.Logger, cfg.LogLevel)
+	case strings.EqualFold(cfg.LogFormat, "json"):
+		return utils.NewJSONLogger(os.Stderr, cfg.LogLevel)
+	case strings.EqualFold(cfg.LogFormat, "text"):
+		return utils.NewTextLogger(os.Stderr, cfg.LogLevel)
+	default:
+		return utils.NewLogger(cfg.LogLevel)
+	}
+}
+
 // SetExtraHeaders sets additional HTTP headers.
 func SetExtraHeaders(headers map[string]string) ConfigOption {
 	return func(c *Config) {
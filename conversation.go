@@ -0,0 +1,37 @@
+// Package omnigo provides multi-turn conversation functionality for Language Learning Models.
+// This file re-exports the Conversation type that keeps chat history across turns.
+package omnigo
+
+import (
+	"github.com/YspCoder/omnigo/llm"
+)
+
+// Re-export conversation types from the llm package
+type (
+	// Conversation is a concurrency-safe multi-turn chat session with its own history.
+	Conversation = llm.Conversation
+
+	// ConversationOption configures a Conversation.
+	ConversationOption = llm.ConversationOption
+)
+
+// Re-export conversation constructors and options
+var (
+	// NewConversation creates an empty conversation using the given LLM.
+	NewConversation = llm.NewConversation
+
+	// LoadConversation restores a conversation serialized with json.Marshal.
+	LoadConversation = llm.LoadConversation
+
+	// WithConversationSystemPrompt sets the system prompt sent with every turn.
+	WithConversationSystemPrompt = llm.WithConversationSystemPrompt
+
+	// WithConversationTools sets the tools offered to the model on every turn.
+	WithConversationTools = llm.WithConversationTools
+
+	// WithConversationTruncation shortens the history sent when it exceeds the context window.
+	WithConversationTruncation = llm.WithConversationTruncation
+
+	// WithConversationHistory starts the conversation from existing messages.
+	WithConversationHistory = llm.WithConversationHistory
+)
@@ -0,0 +1,41 @@
+// Package omnigo provides cost accounting for Language Learning Models.
+// This file re-exports spend tracking and budgets by provider, model and caller tag.
+package omnigo
+
+import (
+	"github.com/YspCoder/omnigo/cost"
+)
+
+// Re-export cost accounting types
+type (
+	// CostTracker records spend by provider, model and caller tag and enforces budgets.
+	CostTracker = cost.Tracker
+
+	// CostPrices holds the prices used to turn usage into spend.
+	CostPrices = cost.Prices
+
+	// CostUsage is the billable usage of one request.
+	CostUsage = cost.Usage
+
+	// CostKey identifies a line of the running totals.
+	CostKey = cost.Key
+
+	// CostTotals accumulates usage and spend.
+	CostTotals = cost.Totals
+
+	// BudgetError reports which spend limit has been reached.
+	BudgetError = cost.BudgetError
+)
+
+// Re-export cost accounting functions
+var (
+	// NewCostTracker creates a tracker with an overall budget in USD; 0 means unlimited.
+	NewCostTracker = cost.NewTracker
+
+	// GlobalCostTracker returns the process-wide tracker that records every LLM's spend.
+	GlobalCostTracker = cost.Global
+
+	// WithCostTag returns a context whose requests are recorded and budgeted under a tag,
+	// e.g. a tenant ID.
+	WithCostTag = cost.WithTag
+)
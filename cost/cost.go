@@ -0,0 +1,275 @@
+// Package cost turns provider usage into spend and enforces budgets. A Tracker
+// keeps running totals by provider, model and caller tag (e.g. a tenant ID carried
+// in the context) and can refuse new requests once a spend limit is reached.
+package cost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Prices holds list prices in USD. Zero prices are not charged.
+type Prices struct {
+	InputPerMillion  float64 // Per million prompt tokens
+	OutputPerMillion float64 // Per million completion tokens
+	PerImage         float64 // Per generated image
+	PerVideo         float64 // Per generated video
+	PerVideoSecond   float64 // Per second of generated video
+}
+
+// Usage is the billable usage of one request.
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+	Images       int
+	Videos       int
+	VideoSeconds int
+}
+
+// Cost returns the price of usage.
+func (p Prices) Cost(usage Usage) float64 {
+	return float64(usage.InputTokens)*p.InputPerMillion/1e6 +
+		float64(usage.OutputTokens)*p.OutputPerMillion/1e6 +
+		float64(usage.Images)*p.PerImage +
+		float64(usage.Videos)*p.PerVideo +
+		float64(usage.VideoSeconds)*p.PerVideoSecond
+}
+
+// Key identifies a line of the running totals.
+type Key struct {
+	Provider string
+	Model    string
+	Tag      string // Caller tag from WithTag; empty when untagged
+}
+
+// Totals accumulates usage and spend.
+type Totals struct {
+	Requests     int
+	InputTokens  int
+	OutputTokens int
+	Images       int
+	Videos       int
+	VideoSeconds int
+	Cost         float64 // USD
+}
+
+func (t *Totals) add(usage Usage, cost float64) {
+	t.Requests++
+	t.InputTokens += usage.InputTokens
+	t.OutputTokens += usage.OutputTokens
+	t.Images += usage.Images
+	t.Videos += usage.Videos
+	t.VideoSeconds += usage.VideoSeconds
+	t.Cost += cost
+}
+
+func (t *Totals) merge(other Totals) {
+	t.Requests += other.Requests
+	t.InputTokens += other.InputTokens
+	t.OutputTokens += other.OutputTokens
+	t.Images += other.Images
+	t.Videos += other.Videos
+	t.VideoSeconds += other.VideoSeconds
+	t.Cost += other.Cost
+}
+
+// ErrBudgetExceeded is matched by errors.Is for every *BudgetError.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// BudgetError reports that a spend limit has been reached.
+type BudgetError struct {
+	Tag   string  // Tag whose budget is exhausted; empty for the overall budget
+	Limit float64 // USD
+	Spent float64 // USD
+}
+
+func (e *BudgetError) Error() string {
+	if e.Tag != "" {
+		return fmt.Sprintf("budget of %.4f USD for %q exhausted: %.4f USD spent", e.Limit, e.Tag, e.Spent)
+	}
+	return fmt.Sprintf("budget of %.4f USD exhausted: %.4f USD spent", e.Limit, e.Spent)
+}
+
+// Is reports whether target is ErrBudgetExceeded.
+func (e *BudgetError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// Tracker records spend and enforces budgets. It is safe for concurrent use, and
+// its methods are no-ops on a nil Tracker.
+type Tracker struct {
+	mu         sync.Mutex
+	totals     map[Key]*Totals
+	spent      float64
+	tagSpent   map[string]float64
+	budget     float64
+	tagBudgets map[string]float64
+}
+
+// NewTracker creates a Tracker with an overall budget in USD; 0 means unlimited.
+func NewTracker(budget float64) *Tracker {
+	return &Tracker{
+		totals:     make(map[Key]*Totals),
+		tagSpent:   make(map[string]float64),
+		budget:     budget,
+		tagBudgets: make(map[string]float64),
+	}
+}
+
+var global = NewTracker(0)
+
+// Global returns the process-wide Tracker that records the spend of every LLM.
+func Global() *Tracker {
+	return global
+}
+
+// SetBudget sets the overall spend limit in USD; 0 removes it.
+func (t *Tracker) SetBudget(limit float64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.budget = limit
+}
+
+// SetTagBudget sets the spend limit in USD for requests carrying tag, e.g. one
+// tenant; 0 removes it.
+func (t *Tracker) SetTagBudget(tag string, limit float64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if limit <= 0 {
+		delete(t.tagBudgets, tag)
+		return
+	}
+	t.tagBudgets[tag] = limit
+}
+
+// Allow returns a *BudgetError if the overall budget or the budget of tag has
+// been reached. The check is made before a request, so the request that crosses
+// a limit completes and later ones are refused.
+func (t *Tracker) Allow(tag string) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.budget > 0 && t.spent >= t.budget {
+		return &BudgetError{Limit: t.budget, Spent: t.spent}
+	}
+	if limit, ok := t.tagBudgets[tag]; ok && t.tagSpent[tag] >= limit {
+		return &BudgetError{Tag: tag, Limit: limit, Spent: t.tagSpent[tag]}
+	}
+	return nil
+}
+
+// Record adds the usage of one request and returns its cost.
+//
+// Parameters:
+//   - key: Provider, model and caller tag of the request
+//   - usage: Billable usage reported by the provider
+//   - prices: Prices of the model
+//
+// Returns:
+//   - The cost of the request in USD
+func (t *Tracker) Record(key Key, usage Usage, prices Prices) float64 {
+	cost := prices.Cost(usage)
+	if t == nil {
+		return cost
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	totals, ok := t.totals[key]
+	if !ok {
+		totals = &Totals{}
+		t.totals[key] = totals
+	}
+	totals.add(usage, cost)
+	t.spent += cost
+	t.tagSpent[key.Tag] += cost
+	return cost
+}
+
+// Spent returns the total spend in USD.
+func (t *Tracker) Spent() float64 {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.spent
+}
+
+// Totals returns a copy of the running totals per provider, model and tag.
+func (t *Tracker) Totals() map[Key]Totals {
+	return t.group(func(key Key) Key { return key })
+}
+
+// ByProvider returns the running totals per provider.
+func (t *Tracker) ByProvider() map[string]Totals {
+	return regroup(t.group(func(key Key) Key { return Key{Provider: key.Provider} }), func(key Key) string { return key.Provider })
+}
+
+// ByModel returns the running totals per model.
+func (t *Tracker) ByModel() map[string]Totals {
+	return regroup(t.group(func(key Key) Key { return Key{Model: key.Model} }), func(key Key) string { return key.Model })
+}
+
+// ByTag returns the running totals per caller tag.
+func (t *Tracker) ByTag() map[string]Totals {
+	return regroup(t.group(func(key Key) Key { return Key{Tag: key.Tag} }), func(key Key) string { return key.Tag })
+}
+
+// Reset clears the totals and spend; budgets are kept.
+func (t *Tracker) Reset() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.totals = make(map[Key]*Totals)
+	t.tagSpent = make(map[string]float64)
+	t.spent = 0
+}
+
+func (t *Tracker) group(by func(Key) Key) map[Key]Totals {
+	grouped := make(map[Key]Totals)
+	if t == nil {
+		return grouped
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, totals := range t.totals {
+		line := grouped[by(key)]
+		line.merge(*totals)
+		grouped[by(key)] = line
+	}
+	return grouped
+}
+
+func regroup(grouped map[Key]Totals, name func(Key) string) map[string]Totals {
+	result := make(map[string]Totals, len(grouped))
+	for key, totals := range grouped {
+		result[name(key)] = totals
+	}
+	return result
+}
+
+type tagKey struct{}
+
+// WithTag returns a context whose requests are recorded and budgeted under tag,
+// e.g. a tenant or feature name.
+func WithTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, tagKey{}, tag)
+}
+
+// TagFromContext returns the tag set with WithTag, or "".
+func TagFromContext(ctx context.Context) string {
+	tag, _ := ctx.Value(tagKey{}).(string)
+	return tag
+}
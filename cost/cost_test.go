@@ -0,0 +1,57 @@
+package cost
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestTrackerTotalsAndBudgets(t *testing.T) {
+	tracker := NewTracker(0)
+	tracker.SetTagBudget("acme", 0.01)
+	prices := Prices{InputPerMillion: 2, OutputPerMillion: 8, PerImage: 0.04}
+
+	ctx := WithTag(context.Background(), "acme")
+	key := Key{Provider: "openai", Model: "gpt-4.1", Tag: TagFromContext(ctx)}
+	if err := tracker.Allow(key.Tag); err != nil {
+		t.Fatalf("Allow before any spend returned %v", err)
+	}
+	if cost := tracker.Record(key, Usage{InputTokens: 1000, OutputTokens: 1000}, prices); math.Abs(cost-0.01) > 1e-12 {
+		t.Fatalf("cost = %v, want 0.01", cost)
+	}
+	tracker.Record(Key{Provider: "openai", Model: "dall-e-3"}, Usage{Images: 2}, prices)
+
+	err := tracker.Allow("acme")
+	var budgetErr *BudgetError
+	if !errors.Is(err, ErrBudgetExceeded) || !errors.As(err, &budgetErr) || budgetErr.Tag != "acme" {
+		t.Fatalf("expected the acme budget to be exhausted, got %v", err)
+	}
+	if err := tracker.Allow("other"); err != nil {
+		t.Fatalf("other tags must not be limited by the acme budget, got %v", err)
+	}
+
+	if got := tracker.ByProvider()["openai"]; got.Requests != 2 || got.Images != 2 || math.Abs(got.Cost-0.09) > 1e-12 {
+		t.Fatalf("provider totals = %+v", got)
+	}
+	if got := tracker.ByTag()["acme"]; got.InputTokens != 1000 || got.Requests != 1 {
+		t.Fatalf("tag totals = %+v", got)
+	}
+	if got := tracker.ByModel()["dall-e-3"]; got.Images != 2 {
+		t.Fatalf("model totals = %+v", got)
+	}
+
+	tracker.SetBudget(0.05)
+	if err := tracker.Allow("other"); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected the overall budget to be exhausted, got %v", err)
+	}
+	tracker.Reset()
+	if err := tracker.Allow("acme"); err != nil || tracker.Spent() != 0 {
+		t.Fatalf("Reset should clear spend, got %v and %v", err, tracker.Spent())
+	}
+
+	var nilTracker *Tracker
+	if err := nilTracker.Allow("acme"); err != nil || len(nilTracker.Totals()) != 0 {
+		t.Fatal("a nil tracker should allow everything and record nothing")
+	}
+}
@@ -0,0 +1,83 @@
+// Package dto defines standardized request and response payloads.
+package dto
+
+import "strings"
+
+// ContentPartType identifies the kind of a multimodal content part.
+type ContentPartType string
+
+const (
+	ContentPartText  ContentPartType = "text"
+	ContentPartImage ContentPartType = "image"
+	ContentPartAudio ContentPartType = "audio"
+	ContentPartFile  ContentPartType = "file"
+)
+
+// ContentPart is a single piece of multimodal message content.
+// Media parts reference either a remote URL or inline base64 Data with its MIME type.
+// A Message carries parts by setting Content to a []ContentPart.
+type ContentPart struct {
+	Type     ContentPartType `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	URL      string          `json:"url,omitempty"`
+	Data     string          `json:"data,omitempty"`
+	MIMEType string          `json:"mime_type,omitempty"`
+	Filename string          `json:"filename,omitempty"`
+	Detail   string          `json:"detail,omitempty"`
+}
+
+// IsInline reports whether the part carries inline base64 data rather than a URL.
+func (p ContentPart) IsInline() bool {
+	return p.Data != ""
+}
+
+// DataURL returns the part as a data URL for inline data, or its URL otherwise.
+func (p ContentPart) DataURL() string {
+	if !p.IsInline() {
+		return p.URL
+	}
+	return "data:" + p.MIMEType + ";base64," + p.Data
+}
+
+// ContentParts returns the content of a message as parts. Plain string content is
+// returned as a single text part; ok is false for unsupported content values.
+func ContentParts(content interface{}) (parts []ContentPart, ok bool) {
+	switch value := content.(type) {
+	case nil:
+		return nil, true
+	case string:
+		if value == "" {
+			return nil, true
+		}
+		return []ContentPart{{Type: ContentPartText, Text: value}}, true
+	case []ContentPart:
+		return value, true
+	default:
+		return nil, false
+	}
+}
+
+// HasMedia reports whether the content includes any non-text part.
+func HasMedia(content interface{}) bool {
+	parts, ok := content.([]ContentPart)
+	if !ok {
+		return false
+	}
+	for _, part := range parts {
+		if part.Type != ContentPartText {
+			return true
+		}
+	}
+	return false
+}
+
+// JoinText concatenates the text parts of a multimodal content slice.
+func JoinText(parts []ContentPart) string {
+	var builder strings.Builder
+	for _, part := range parts {
+		if part.Type == ContentPartText {
+			builder.WriteString(part.Text)
+		}
+	}
+	return builder.String()
+}
@@ -1,13 +1,21 @@
 // Package dto defines standardized request and response payloads.
 package dto
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // LLMError represents a unified error structure across providers.
+// Code is the HTTP status; Type carries the provider's own error type or code
+// (OpenAI error.code/type, Anthropic error.type, Gemini error.status, DashScope code,
+// Jimeng numeric code) when the error body could be decoded.
 type LLMError struct {
-	Code     int    `json:"code"`
-	Message  string `json:"message"`
-	Provider string `json:"provider"`
+	Code       int           `json:"code"`
+	Message    string        `json:"message"`
+	Provider   string        `json:"provider"`
+	Type       string        `json:"type,omitempty"`
+	RetryAfter time.Duration `json:"-"`
 }
 
 func (e *LLMError) Error() string {
@@ -37,6 +37,15 @@ type MediaResponse struct {
 	Video        struct {
 		URL string `json:"url,omitempty"`
 	} `json:"video,omitempty"`
+	Usage   *MediaUsage `json:"usage,omitempty"`   // Billable output, when the provider reports it
+	Backend string      `json:"backend,omitempty"` // Router backend that served the request, if routed
+}
+
+// MediaUsage holds the billable output of a media request.
+type MediaUsage struct {
+	ImageCount    int `json:"image_count,omitempty"`
+	VideoCount    int `json:"video_count,omitempty"`
+	VideoDuration int `json:"video_duration,omitempty"` // Seconds
 }
 
 // ImageData holds the image payload.
@@ -70,5 +79,6 @@ type TaskStatusOutput struct {
 type TaskStatusUsage struct {
 	VideoDuration int `json:"video_duration,omitempty"`
 	VideoCount    int `json:"video_count,omitempty"`
+	ImageCount    int `json:"image_count,omitempty"`
 	SR            int `json:"SR,omitempty"`
 }
@@ -1,10 +1,32 @@
 // Package dto defines standardized request and response payloads.
 package dto
 
+import "encoding/json"
+
 // Message represents a single message in a chat conversation.
 type Message struct {
-	Role    string      `json:"role"`
-	Content interface{} `json:"content"`
+	Role       string      `json:"role"`
+	Content    interface{} `json:"content"`
+	Name       string      `json:"name,omitempty"`
+	ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`
+	ToolCallID string      `json:"tool_call_id,omitempty"`
+
+	// CacheType requests a prompt caching breakpoint after this message (e.g. "ephemeral").
+	// It is consumed by adaptors that support explicit caching and never sent as-is.
+	CacheType string `json:"-"`
+}
+
+// ToolCall represents a tool invocation requested by the model.
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction holds the function name and its JSON-encoded arguments.
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 // ChatRequest represents a chat completion request following the OpenAI schema.
@@ -37,8 +59,57 @@ type ChatChoice struct {
 }
 
 // Usage represents token usage statistics.
+// CachedTokens and CacheCreationTokens are included in PromptTokens.
 type Usage struct {
-	PromptTokens     int `json:"prompt_tokens,omitempty"`
-	CompletionTokens int `json:"completion_tokens,omitempty"`
-	TotalTokens      int `json:"total_tokens,omitempty"`
+	PromptTokens        int `json:"prompt_tokens,omitempty"`
+	CompletionTokens    int `json:"completion_tokens,omitempty"`
+	TotalTokens         int `json:"total_tokens,omitempty"`
+	CachedTokens        int `json:"cached_tokens,omitempty"`
+	CacheCreationTokens int `json:"cache_creation_tokens,omitempty"`
+}
+
+// UnmarshalJSON also reads the OpenAI prompt_tokens_details.cached_tokens field.
+func (u *Usage) UnmarshalJSON(data []byte) error {
+	type plain Usage
+	var raw struct {
+		plain
+		PromptTokensDetails *struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"prompt_tokens_details"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*u = Usage(raw.plain)
+	if u.CachedTokens == 0 && raw.PromptTokensDetails != nil {
+		u.CachedTokens = raw.PromptTokensDetails.CachedTokens
+	}
+	return nil
+}
+
+// Merge folds a partial usage report from a stream into u. Providers report usage
+// in several events (Anthropic sends input tokens first and output tokens last),
+// so non-zero counts replace earlier ones. A nil delta leaves u unchanged.
+func (u *Usage) Merge(delta *Usage) {
+	if delta == nil {
+		return
+	}
+	if delta.PromptTokens > 0 {
+		u.PromptTokens = delta.PromptTokens
+	}
+	if delta.CompletionTokens > 0 {
+		u.CompletionTokens = delta.CompletionTokens
+	}
+	if delta.TotalTokens > 0 {
+		u.TotalTokens = delta.TotalTokens
+	}
+	if delta.CachedTokens > 0 {
+		u.CachedTokens = delta.CachedTokens
+	}
+	if delta.CacheCreationTokens > 0 {
+		u.CacheCreationTokens = delta.CacheCreationTokens
+	}
+	if u.TotalTokens < u.PromptTokens+u.CompletionTokens {
+		u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	}
 }
@@ -0,0 +1,42 @@
+// Package dto defines standardized request and response payloads.
+package dto
+
+// StreamEventType identifies the kind of a streaming event.
+type StreamEventType string
+
+const (
+	StreamEventText     StreamEventType = "text"
+	StreamEventToolCall StreamEventType = "tool_call"
+	StreamEventUsage    StreamEventType = "usage"
+	StreamEventFinish   StreamEventType = "finish"
+	StreamEventError    StreamEventType = "error"
+	// StreamEventMetadata carries only response metadata (ID, Model, Created), for
+	// chunks that report it without any other event.
+	StreamEventMetadata StreamEventType = "metadata"
+)
+
+// StreamEvent represents a single typed event decoded from a provider stream chunk.
+type StreamEvent struct {
+	Type         StreamEventType `json:"type"`
+	Text         string          `json:"text,omitempty"`
+	ToolCall     *ToolCallDelta  `json:"tool_call,omitempty"`
+	Usage        *Usage          `json:"usage,omitempty"`
+	FinishReason string          `json:"finish_reason,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	ErrorCode    string          `json:"error_code,omitempty"` // Provider error type or code of an error event, e.g. "overloaded_error"
+
+	// Response metadata, set on the events of the chunks that report it.
+	ID      string `json:"id,omitempty"`
+	Model   string `json:"model,omitempty"`
+	Created int64  `json:"created,omitempty"` // Unix seconds
+}
+
+// ToolCallDelta is an incremental fragment of a streamed tool call.
+// ID and Name are usually only present on the first fragment of a call;
+// Arguments carries the next piece of the JSON-encoded arguments.
+type ToolCallDelta struct {
+	Index     int    `json:"index"`
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
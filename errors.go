@@ -0,0 +1,50 @@
+// Package omnigo provides typed errors for Language Learning Model requests.
+// This file re-exports the error types and classification helpers from the llm package.
+package omnigo
+
+import (
+	"github.com/YspCoder/omnigo/llm"
+)
+
+// Re-export error types
+type (
+	// LLMError is the structured error returned by LLM operations.
+	// Use errors.As to inspect its Type, StatusCode, Code and RetryAfter.
+	LLMError = llm.LLMError
+
+	// ErrorType represents the category of an LLMError.
+	ErrorType = llm.ErrorType
+)
+
+// Error categories reported in LLMError.Type
+const (
+	ErrorTypeUnknown         = llm.ErrorTypeUnknown
+	ErrorTypeProvider        = llm.ErrorTypeProvider
+	ErrorTypeRequest         = llm.ErrorTypeRequest
+	ErrorTypeResponse        = llm.ErrorTypeResponse
+	ErrorTypeAPI             = llm.ErrorTypeAPI
+	ErrorTypeRateLimit       = llm.ErrorTypeRateLimit
+	ErrorTypeAuthentication  = llm.ErrorTypeAuthentication
+	ErrorTypeInvalidInput    = llm.ErrorTypeInvalidInput
+	ErrorTypeUnsupported     = llm.ErrorTypeUnsupported
+	ErrorTypeContextLength   = llm.ErrorTypeContextLength
+	ErrorTypeContentFiltered = llm.ErrorTypeContentFiltered
+	ErrorTypeOverloaded      = llm.ErrorTypeOverloaded
+	ErrorTypeBudgetExceeded  = llm.ErrorTypeBudgetExceeded
+)
+
+// Sentinel errors for use with errors.Is
+var (
+	ErrRateLimit             = llm.ErrRateLimit
+	ErrAuthentication        = llm.ErrAuthentication
+	ErrInvalidInput          = llm.ErrInvalidInput
+	ErrContextLengthExceeded = llm.ErrContextLengthExceeded
+	ErrContentFiltered       = llm.ErrContentFiltered
+	ErrOverloaded            = llm.ErrOverloaded
+	ErrBudgetExceeded        = llm.ErrBudgetExceeded
+)
+
+var (
+	// ClassifyError determines the ErrorType of an error returned by a provider.
+	ClassifyError = llm.ClassifyError
+)
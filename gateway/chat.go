@@ -0,0 +1,480 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/YspCoder/omnigo/adapter"
+	"github.com/YspCoder/omnigo/dto"
+	"github.com/YspCoder/omnigo/llm"
+)
+
+// intOptions are request fields that adaptors read as int; JSON decodes every
+// number as float64.
+var intOptions = map[string]bool{
+	"max_completion_tokens": true,
+	"top_k":                 true,
+	"seed":                  true,
+	"n":                     true,
+	"top_logprobs":          true,
+}
+
+// chatMessage is a message of an OpenAI chat completion request.
+type chatMessage struct {
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content"`
+	Name       string          `json:"name,omitempty"`
+	ToolCalls  []dto.ToolCall  `json:"tool_calls,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+}
+
+// contentPart is an entry of an OpenAI content array.
+type contentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	ImageURL *struct {
+		URL    string `json:"url"`
+		Detail string `json:"detail"`
+	} `json:"image_url"`
+	InputAudio *struct {
+		Data   string `json:"data"`
+		Format string `json:"format"`
+	} `json:"input_audio"`
+	File *struct {
+		FileData string `json:"file_data"`
+		Filename string `json:"filename"`
+	} `json:"file"`
+}
+
+// chatCompletion is the OpenAI chat completion and chunk envelope.
+type chatCompletion struct {
+	ID      string       `json:"id"`
+	Object  string       `json:"object"`
+	Created int64        `json:"created"`
+	Model   string       `json:"model"`
+	Choices []chatChoice `json:"choices"`
+	Usage   *chatUsage   `json:"usage,omitempty"`
+}
+
+type chatChoice struct {
+	Index        int         `json:"index"`
+	Message      *chatOutput `json:"message,omitempty"`
+	Delta        *chatOutput `json:"delta,omitempty"`
+	FinishReason *string     `json:"finish_reason"`
+}
+
+type chatOutput struct {
+	Role      string          `json:"role,omitempty"`
+	Content   *string         `json:"content,omitempty"`
+	ToolCalls []chatToolDelta `json:"tool_calls,omitempty"`
+}
+
+type chatToolDelta struct {
+	Index    *int   `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+func newChatUsage(usage dto.Usage) *chatUsage {
+	total := usage.TotalTokens
+	if total < usage.PromptTokens+usage.CompletionTokens {
+		total = usage.PromptTokens + usage.CompletionTokens
+	}
+	return &chatUsage{PromptTokens: usage.PromptTokens, CompletionTokens: usage.CompletionTokens, TotalTokens: total}
+}
+
+// parseChatRequest translates an OpenAI chat completion request into a relay
+// request. Fields the gateway does not interpret are passed on as options.
+//
+// Returns:
+//   - The requested model
+//   - The relay request
+//   - Whether a stream was requested
+//   - Whether the stream should end with a usage chunk
+//   - An error if the request is malformed
+func parseChatRequest(body io.Reader) (string, *dto.ChatRequest, bool, bool, error) {
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(body).Decode(&fields); err != nil {
+		return "", nil, false, false, fmt.Errorf("invalid JSON body: %w", err)
+	}
+	var (
+		model         string
+		messages      []chatMessage
+		stream        bool
+		streamOptions struct {
+			IncludeUsage bool `json:"include_usage"`
+		}
+	)
+	request := &dto.ChatRequest{Options: make(map[string]interface{})}
+	for name, raw := range fields {
+		var err error
+		switch name {
+		case "model":
+			err = json.Unmarshal(raw, &model)
+		case "messages":
+			err = json.Unmarshal(raw, &messages)
+		case "stream":
+			err = json.Unmarshal(raw, &stream)
+		case "stream_options":
+			err = json.Unmarshal(raw, &streamOptions)
+		case "temperature":
+			// Also kept as an option so that an explicit 0 reaches the provider.
+			err = json.Unmarshal(raw, &request.Temperature)
+			request.Options[name] = request.Temperature
+		case "max_tokens":
+			err = json.Unmarshal(raw, &request.MaxTokens)
+		default:
+			var value interface{}
+			if err = json.Unmarshal(raw, &value); err == nil && value != nil {
+				if number, ok := value.(float64); ok && intOptions[name] && number == float64(int(number)) {
+					value = int(number)
+				}
+				request.Options[name] = value
+			}
+		}
+		if err != nil {
+			return "", nil, false, false, fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	if model == "" {
+		return "", nil, false, false, fmt.Errorf("model is required")
+	}
+	if len(messages) == 0 {
+		return "", nil, false, false, fmt.Errorf("messages must not be empty")
+	}
+	if request.MaxTokens == 0 {
+		// Native adaptors only read max_tokens.
+		if maxTokens, ok := request.Options["max_completion_tokens"].(int); ok {
+			request.MaxTokens = maxTokens
+		}
+	}
+	if request.MaxTokens > 0 {
+		request.Options["max_tokens"] = request.MaxTokens
+	}
+	for _, msg := range messages {
+		content, err := messageContent(msg.Content)
+		if err != nil {
+			return "", nil, false, false, fmt.Errorf("invalid content of %s message: %w", msg.Role, err)
+		}
+		request.Messages = append(request.Messages, dto.Message{
+			Role:       msg.Role,
+			Content:    content,
+			Name:       msg.Name,
+			ToolCalls:  msg.ToolCalls,
+			ToolCallID: msg.ToolCallID,
+		})
+	}
+	return model, request, stream, streamOptions.IncludeUsage, nil
+}
+
+// messageContent converts OpenAI message content, a string or an array of parts,
+// into a string or []dto.ContentPart.
+func messageContent(raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+	var parts []contentPart
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return nil, err
+	}
+	converted := make([]dto.ContentPart, 0, len(parts))
+	for _, part := range parts {
+		switch {
+		case part.Type == "text":
+			converted = append(converted, dto.ContentPart{Type: dto.ContentPartText, Text: part.Text})
+		case part.Type == "image_url" && part.ImageURL != nil:
+			image := urlPart(dto.ContentPartImage, part.ImageURL.URL)
+			image.Detail = part.ImageURL.Detail
+			converted = append(converted, image)
+		case part.Type == "input_audio" && part.InputAudio != nil:
+			converted = append(converted, dto.ContentPart{
+				Type:     dto.ContentPartAudio,
+				Data:     part.InputAudio.Data,
+				MIMEType: "audio/" + part.InputAudio.Format,
+			})
+		case part.Type == "file" && part.File != nil:
+			file := urlPart(dto.ContentPartFile, part.File.FileData)
+			file.Filename = part.File.Filename
+			converted = append(converted, file)
+		default:
+			return nil, fmt.Errorf("unsupported content part type %q", part.Type)
+		}
+	}
+	return converted, nil
+}
+
+// urlPart builds a part from a URL, unpacking base64 data URLs into inline data.
+func urlPart(partType dto.ContentPartType, url string) dto.ContentPart {
+	if rest, ok := strings.CutPrefix(url, "data:"); ok {
+		if meta, data, ok := strings.Cut(rest, ","); ok && strings.HasSuffix(meta, ";base64") {
+			return dto.ContentPart{Type: partType, Data: data, MIMEType: strings.TrimSuffix(meta, ";base64")}
+		}
+	}
+	return dto.ContentPart{Type: partType, URL: url}
+}
+
+// finishReason maps a provider stop reason onto the values OpenAI clients expect.
+func finishReason(raw string, toolCalls bool) *string {
+	reason := llm.NormalizeFinishReason(raw)
+	switch {
+	case reason == "":
+		return nil
+	case toolCalls && reason == llm.FinishReasonStop, reason == llm.FinishReasonToolCalls:
+		reason = llm.FinishReasonToolCalls
+	case reason == llm.FinishReasonOther:
+		reason = llm.FinishReasonStop
+	}
+	value := string(reason)
+	return &value
+}
+
+func newCompletionID() string {
+	var id [12]byte
+	_, _ = rand.Read(id[:])
+	return "chatcmpl-" + hex.EncodeToString(id[:])
+}
+
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	model, request, stream, includeUsage, err := parseChatRequest(r.Body)
+	if err != nil {
+		writeBodyError(w, err, err.Error())
+		return
+	}
+	var supports func(*backend) bool
+	if stream {
+		supports = func(b *backend) bool { return streamAdaptor(b) != nil }
+	}
+	b, ok := s.resolve(w, r, model, "streaming", supports)
+	if !ok {
+		return
+	}
+	request.Model = b.config.Model
+	if stream {
+		s.streamChat(w, r, b, model, request, includeUsage)
+		return
+	}
+
+	response, err := s.relay.Chat(r.Context(), b.adaptor, b.config, request)
+	if err != nil {
+		s.writeUpstreamError(w, r, b, err)
+		return
+	}
+	completion := chatCompletion{
+		ID:      response.ID,
+		Object:  "chat.completion",
+		Created: response.Created,
+		Model:   model,
+		Choices: make([]chatChoice, 0, len(response.Choices)),
+		Usage:   newChatUsage(response.Usage),
+	}
+	if completion.ID == "" {
+		completion.ID = newCompletionID()
+	}
+	if completion.Created == 0 {
+		completion.Created = time.Now().Unix()
+	}
+	for i, choice := range response.Choices {
+		output := &chatOutput{Role: "assistant"}
+		parts, _ := dto.ContentParts(choice.Message.Content)
+		if text := dto.JoinText(parts); text != "" || len(choice.Message.ToolCalls) == 0 {
+			output.Content = &text
+		}
+		for _, call := range choice.Message.ToolCalls {
+			delta := chatToolDelta{ID: call.ID, Type: "function"}
+			delta.Function.Name = call.Function.Name
+			delta.Function.Arguments = call.Function.Arguments
+			output.ToolCalls = append(output.ToolCalls, delta)
+		}
+		reason := finishReason(choice.FinishReason, len(output.ToolCalls) > 0)
+		if reason == nil {
+			stop := string(llm.FinishReasonStop)
+			reason = &stop
+		}
+		completion.Choices = append(completion.Choices, chatChoice{Index: i, Message: output, FinishReason: reason})
+	}
+	s.log(r, b).Debug("Chat completion served", "prompt_tokens", completion.Usage.PromptTokens,
+		"completion_tokens", completion.Usage.CompletionTokens)
+	writeJSON(w, http.StatusOK, completion)
+}
+
+// streamAdaptor returns the adaptor that prepares and parses the backend's
+// streams, or nil if it cannot stream.
+func streamAdaptor(b *backend) adapter.StreamAdaptor {
+	if strings.EqualFold(b.config.ChatProtocol, adapter.ChatProtocolOpenAI) {
+		return &adapter.OpenAIAdaptor{}
+	}
+	if adaptor, ok := b.adaptor.(adapter.StreamAdaptor); ok {
+		return adaptor
+	}
+	return nil
+}
+
+// streamChat relays a provider stream as OpenAI chat.completion.chunk events.
+func (s *Server) streamChat(w http.ResponseWriter, r *http.Request, b *backend, model string, request *dto.ChatRequest, includeUsage bool) {
+	parser := streamAdaptor(b)
+	request.Stream = true
+	request.Options["stream"] = true
+	request.Options["stream_options"] = map[string]interface{}{"include_usage": true}
+
+	config := *b.config
+	if headerProvider, ok := b.adaptor.(adapter.StreamHeadersProvider); ok {
+		if extra := headerProvider.StreamHeaders(&config); len(extra) > 0 {
+			headers := make(map[string]string, len(config.Headers)+len(extra))
+			for k, v := range config.Headers {
+				headers[k] = v
+			}
+			for k, v := range extra {
+				headers[k] = v
+			}
+			config.Headers = headers
+		}
+	}
+	body, err := s.relay.Stream(r.Context(), b.adaptor, parser, &config, request)
+	if err != nil {
+		s.writeUpstreamError(w, r, b, err)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	chunk := chatCompletion{ID: newCompletionID(), Object: "chat.completion.chunk", Created: time.Now().Unix(), Model: model}
+	send := func(choices []chatChoice, usage *chatUsage) bool {
+		chunk.Choices, chunk.Usage = choices, usage
+		data, _ := json.Marshal(chunk)
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return false
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return true
+	}
+	// fail ends the stream with an OpenAI error envelope and no [DONE], so clients
+	// can tell a failed answer from a complete one.
+	fail := func(message, code string) {
+		var failure errorBody
+		failure.Error.Message = message
+		failure.Error.Type = "upstream_error"
+		if code != "" {
+			failure.Error.Code = &code
+		}
+		data, _ := json.Marshal(failure)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	delta := func(output *chatOutput, reason *string) []chatChoice {
+		return []chatChoice{{Index: 0, Delta: output, FinishReason: reason}}
+	}
+
+	var (
+		usage     dto.Usage
+		rawReason string
+		toolCalls bool
+	)
+	if !send(delta(&chatOutput{Role: "assistant"}, nil), nil) {
+		return
+	}
+	decoder := llm.NewSSEDecoder(body)
+	for decoder.Next() {
+		data := decoder.Event().Data
+		if len(data) == 0 {
+			continue
+		}
+		events, err := parseStreamEvent(parser, data)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			continue // Keep-alive or malformed chunk
+		}
+		for _, event := range events {
+			switch event.Type {
+			case dto.StreamEventText:
+				text := event.Text
+				if !send(delta(&chatOutput{Content: &text}, nil), nil) {
+					return
+				}
+			case dto.StreamEventToolCall:
+				toolCalls = true
+				call := chatToolDelta{Index: &event.ToolCall.Index, ID: event.ToolCall.ID}
+				if call.ID != "" {
+					call.Type = "function"
+				}
+				call.Function.Name = event.ToolCall.Name
+				call.Function.Arguments = event.ToolCall.Arguments
+				if !send(delta(&chatOutput{ToolCalls: []chatToolDelta{call}}, nil), nil) {
+					return
+				}
+			case dto.StreamEventUsage:
+				usage.Merge(event.Usage)
+			case dto.StreamEventFinish:
+				rawReason = event.FinishReason
+			case dto.StreamEventError:
+				s.log(r, b).Warn("Provider stream failed", "error", event.Error)
+				fail(event.Error, event.ErrorCode)
+				return
+			}
+		}
+	}
+	if err := decoder.Err(); err != nil {
+		// The transport error is logged but not echoed, as it may carry provider URLs.
+		s.log(r, b).Warn("Provider stream interrupted", "error", err)
+		fail("The provider stream was interrupted", "stream_interrupted")
+		return
+	}
+
+	reason := finishReason(rawReason, toolCalls)
+	if reason == nil {
+		stop := string(llm.FinishReasonStop)
+		reason = &stop
+	}
+	if !send(delta(&chatOutput{}, reason), nil) {
+		return
+	}
+	if includeUsage && !send([]chatChoice{}, newChatUsage(usage)) {
+		return
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// parseStreamEvent decodes a provider chunk into typed events.
+func parseStreamEvent(parser adapter.StreamAdaptor, data []byte) ([]dto.StreamEvent, error) {
+	if eventParser, ok := parser.(adapter.StreamEventParser); ok {
+		return eventParser.ParseStreamEvent(data)
+	}
+	text, err := parser.ParseStreamResponse(data)
+	if err != nil {
+		return nil, err
+	}
+	return []dto.StreamEvent{{Type: dto.StreamEventText, Text: text}}, nil
+}
@@ -0,0 +1,459 @@
+// Package gateway serves an OpenAI-compatible HTTP API in front of the providers of
+// an adapter.Registry. Clients send OpenAI-shaped requests; each request is routed by
+// model name, and optionally by the caller's API key, to a configured backend and
+// translated through its adaptor and the relay.
+//
+// Endpoints:
+//   - POST /v1/chat/completions: Chat completions, streaming and non-streaming
+//   - POST /v1/images/generations: Image generation
+//   - POST /v1/videos/generations: Video generation, usually returning a task ID
+//   - GET /v1/tasks/{task_id}?model=...: Status of an asynchronous media task
+//   - GET /v1/models: Models available to the caller
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YspCoder/omnigo/adapter"
+	"github.com/YspCoder/omnigo/dto"
+	"github.com/YspCoder/omnigo/relay"
+	"github.com/YspCoder/omnigo/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Route maps a model name exposed to clients onto a provider backend.
+type Route struct {
+	Name            string            `json:"name,omitempty" yaml:"name,omitempty"` // Unique name keys refer to; defaults to Model
+	Model           string            `json:"model" yaml:"model"`                   // Model name clients request
+	Provider        string            `json:"provider" yaml:"provider"`             // Registry provider name, e.g. "anthropic"
+	UpstreamModel   string            `json:"upstream_model,omitempty" yaml:"upstream_model,omitempty"`
+	APIKey          string            `json:"api_key,omitempty" yaml:"api_key,omitempty"`
+	AccessKeyID     string            `json:"access_key_id,omitempty" yaml:"access_key_id,omitempty"`         // Signs requests of providers such as jimeng in place of APIKey
+	SecretAccessKey string            `json:"secret_access_key,omitempty" yaml:"secret_access_key,omitempty"` // Secret of AccessKeyID
+	Region          string            `json:"region,omitempty" yaml:"region,omitempty"`                       // Signing region, e.g. cn-north-1 for jimeng
+	Endpoint        string            `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	ChatProtocol    string            `json:"chat_protocol,omitempty" yaml:"chat_protocol,omitempty"`
+	Headers         map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Timeout         string            `json:"timeout,omitempty" yaml:"timeout,omitempty"` // Go duration, e.g. "2m"; covers a whole stream
+}
+
+// Key is an API key accepted by the gateway.
+type Key struct {
+	Key    string   `json:"key" yaml:"key"`
+	Name   string   `json:"name,omitempty" yaml:"name,omitempty"`     // Logged instead of the key
+	Routes []string `json:"routes,omitempty" yaml:"routes,omitempty"` // Route names the key may use, in order of preference; empty allows all
+}
+
+// Config is the gateway configuration. Without keys the gateway accepts every
+// request and routes by model name only.
+type Config struct {
+	Addr         string  `json:"addr,omitempty" yaml:"addr,omitempty"`                     // Listen address of cmd/omnigo-gateway
+	MaxBodyBytes int64   `json:"max_body_bytes,omitempty" yaml:"max_body_bytes,omitempty"` // Request body limit; defaults to DefaultMaxBodyBytes
+	Routes       []Route `json:"routes" yaml:"routes"`
+	Keys         []Key   `json:"keys,omitempty" yaml:"keys,omitempty"`
+}
+
+// DefaultMaxBodyBytes is the request body limit used when Config.MaxBodyBytes is
+// not set. It leaves room for a few base64-encoded images in a chat request.
+const DefaultMaxBodyBytes = 32 << 20
+
+// LoadConfig reads a configuration in JSON or YAML. $VAR and ${VAR} references
+// are replaced with environment variables, so API keys need not be stored in the file.
+//
+// Parameters:
+//   - r: The configuration data
+//   - format: "json" or "yaml"
+//
+// Returns:
+//   - The decoded configuration
+//   - An error if the data cannot be read or decoded
+func LoadConfig(r io.Reader, format string) (Config, error) {
+	var cfg Config
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return cfg, err
+	}
+	data = []byte(os.ExpandEnv(string(data)))
+	switch strings.ToLower(format) {
+	case "json":
+		err = json.Unmarshal(data, &cfg)
+	case "yaml", "yml":
+		err = yaml.Unmarshal(data, &cfg)
+	default:
+		err = fmt.Errorf("unsupported config format %q", format)
+	}
+	return cfg, err
+}
+
+// LoadConfigFile loads a .json, .yaml or .yml configuration file.
+func LoadConfigFile(path string) (Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Config{}, err
+	}
+	defer file.Close()
+	cfg, err := LoadConfig(file, strings.TrimPrefix(filepath.Ext(path), "."))
+	if err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Option configures a Server.
+type Option func(*options)
+
+type options struct {
+	registry    *adapter.Registry
+	logger      utils.Logger
+	middlewares []relay.Middleware
+	client      *http.Client
+}
+
+// WithRegistry builds backends from registry instead of the default registry.
+func WithRegistry(registry *adapter.Registry) Option {
+	return func(o *options) {
+		o.registry = registry
+	}
+}
+
+// WithLogger sets the logger for request and error logs.
+func WithLogger(logger utils.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithMiddleware wraps every provider request with middlewares, e.g. for auditing.
+func WithMiddleware(middlewares ...relay.Middleware) Option {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// WithHTTPClient sets the client used for provider requests.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// backend is a route bound to its adaptor and provider configuration.
+type backend struct {
+	route   Route
+	spec    adapter.ProviderSpec
+	adaptor adapter.Adaptor
+	config  *adapter.ProviderConfig
+}
+
+// Server is an http.Handler serving the OpenAI-compatible API.
+type Server struct {
+	backends map[string]*backend // By route name
+	order    []string            // Route names in configuration order
+	keys     map[string]Key
+	maxBody  int64
+	relay    *relay.Relay
+	logger   utils.Logger
+	mux      *http.ServeMux
+}
+
+// New builds a Server for cfg.
+//
+// Parameters:
+//   - cfg: Routes and keys
+//   - opts: Registry, logger, middlewares and HTTP client
+//
+// Returns:
+//   - The server
+//   - An error if a route names an unknown provider, a duplicate route, or a
+//     key refers to an unknown route
+func New(cfg Config, opts ...Option) (*Server, error) {
+	o := &options{
+		registry: adapter.GetDefaultRegistry(),
+		logger:   utils.NewLogger(utils.LogLevelWarn),
+		client:   &http.Client{},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	s := &Server{
+		backends: make(map[string]*backend, len(cfg.Routes)),
+		keys:     make(map[string]Key, len(cfg.Keys)),
+		maxBody:  cfg.MaxBodyBytes,
+		relay:    relay.NewRelay(),
+		logger:   o.logger,
+		mux:      http.NewServeMux(),
+	}
+	if s.maxBody <= 0 {
+		s.maxBody = DefaultMaxBodyBytes
+	}
+	s.relay.Client = o.client
+	s.relay.Use(o.middlewares...)
+
+	for i, route := range cfg.Routes {
+		if route.Model == "" || route.Provider == "" {
+			return nil, fmt.Errorf("route %d: model and provider are required", i)
+		}
+		if route.Name == "" {
+			route.Name = route.Model
+		}
+		if _, ok := s.backends[route.Name]; ok {
+			return nil, fmt.Errorf("duplicate route %q", route.Name)
+		}
+		b, err := newBackend(o.registry, route, o.client)
+		if err != nil {
+			return nil, fmt.Errorf("route %q: %w", route.Name, err)
+		}
+		s.backends[route.Name] = b
+		s.order = append(s.order, route.Name)
+	}
+	for _, key := range cfg.Keys {
+		if key.Key == "" {
+			return nil, fmt.Errorf("key %q has no value", key.Name)
+		}
+		for _, name := range key.Routes {
+			if _, ok := s.backends[name]; !ok {
+				return nil, fmt.Errorf("key %q refers to unknown route %q", key.Name, name)
+			}
+		}
+		s.keys[key.Key] = key
+	}
+
+	s.mux.HandleFunc("POST /v1/chat/completions", s.handleChat)
+	s.mux.HandleFunc("POST /v1/images/generations", s.handleImages)
+	s.mux.HandleFunc("POST /v1/videos/generations", s.handleVideos)
+	s.mux.HandleFunc("GET /v1/tasks/{task_id}", s.handleTask)
+	s.mux.HandleFunc("GET /v1/models", s.handleModels)
+	return s, nil
+}
+
+// newBackend resolves the route's provider and builds its provider configuration
+// the same way llm.NewLLM does.
+func newBackend(registry *adapter.Registry, route Route, client *http.Client) (*backend, error) {
+	adp, spec, err := registry.BuildAdaptor(route.Provider)
+	if err != nil {
+		return nil, err
+	}
+	var timeout time.Duration
+	if route.Timeout != "" {
+		if timeout, err = time.ParseDuration(route.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+	}
+
+	headers := make(map[string]string, len(spec.RequiredHeaders)+len(route.Headers))
+	for key, value := range spec.RequiredHeaders {
+		headers[key] = value
+	}
+	for key, value := range route.Headers {
+		headers[key] = value
+	}
+	baseURL := spec.Endpoint
+	if route.Endpoint != "" {
+		baseURL = route.Endpoint
+	}
+	chatProtocol := adapter.ChatProtocolOpenAI
+	if spec.Type == adapter.TypeCustom {
+		chatProtocol = adapter.ChatProtocolNative
+	}
+	if route.ChatProtocol != "" {
+		chatProtocol = route.ChatProtocol
+	}
+	apiKey := route.APIKey
+	if apiKey == "" && route.AccessKeyID != "" && route.SecretAccessKey != "" {
+		apiKey = route.AccessKeyID + ":" + route.SecretAccessKey
+	}
+	upstreamModel := route.UpstreamModel
+	if upstreamModel == "" {
+		upstreamModel = route.Model
+	}
+
+	return &backend{
+		route:   route,
+		spec:    spec,
+		adaptor: adp,
+		config: &adapter.ProviderConfig{
+			Name:         spec.Name,
+			APIKey:       apiKey,
+			Model:        upstreamModel,
+			BaseURL:      baseURL,
+			AuthHeader:   spec.AuthHeader,
+			AuthPrefix:   spec.AuthPrefix,
+			Headers:      headers,
+			HTTPClient:   client,
+			Timeout:      timeout,
+			ChatProtocol: chatProtocol,
+			Region:       route.Region,
+		},
+	}, nil
+}
+
+type keyContextKey struct{}
+
+// ServeHTTP authenticates the caller and dispatches the request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if len(s.keys) > 0 {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		key, known := s.keys[strings.TrimSpace(token)]
+		if !ok || !known {
+			writeError(w, http.StatusUnauthorized, "invalid_request_error", "invalid_api_key", "Invalid API key")
+			return
+		}
+		r = r.WithContext(context.WithValue(r.Context(), keyContextKey{}, key))
+	}
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxBody)
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// routes returns the backends available to the caller, in order of preference.
+func (s *Server) routes(ctx context.Context) []*backend {
+	names := s.order
+	if key, ok := ctx.Value(keyContextKey{}).(Key); ok && len(key.Routes) > 0 {
+		names = key.Routes
+	}
+	backends := make([]*backend, 0, len(names))
+	for _, name := range names {
+		backends = append(backends, s.backends[name])
+	}
+	return backends
+}
+
+// resolve returns the caller's preferred backend for model among those that
+// support the feature, or writes a 404 for an unknown model and a 400 when no
+// backend of the model supports the feature. A nil supports accepts any backend.
+func (s *Server) resolve(w http.ResponseWriter, r *http.Request, model, feature string, supports func(*backend) bool) (*backend, bool) {
+	known := false
+	for _, b := range s.routes(r.Context()) {
+		if b.route.Model != model {
+			continue
+		}
+		known = true
+		if supports == nil || supports(b) {
+			return b, true
+		}
+	}
+	if known {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "",
+			fmt.Sprintf("The model %q does not support %s", model, feature))
+	} else {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "model_not_found",
+			fmt.Sprintf("The model %q does not exist or you do not have access to it.", model))
+	}
+	return nil, false
+}
+
+// log returns the logger tagged with the caller's key name and the route.
+func (s *Server) log(r *http.Request, b *backend) utils.Logger {
+	fields := []interface{}{"path", r.URL.Path}
+	if key, ok := r.Context().Value(keyContextKey{}).(Key); ok {
+		fields = append(fields, "key", key.Name)
+	}
+	if b != nil {
+		fields = append(fields, "route", b.route.Name, "provider", b.spec.Name)
+	}
+	return utils.WithFields(s.logger, fields...)
+}
+
+type modelObject struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	seen := make(map[string]bool)
+	models := []modelObject{}
+	for _, b := range s.routes(r.Context()) {
+		if seen[b.route.Model] {
+			continue
+		}
+		seen[b.route.Model] = true
+		models = append(models, modelObject{ID: b.route.Model, Object: "model", OwnedBy: b.spec.Name})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"object": "list", "data": models})
+}
+
+// decodeBody decodes a JSON request body, writing a 400 on failure and a 413
+// when the body exceeds the size limit.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeBodyError(w, err, "Invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// writeBodyError reports a request body that could not be read or decoded.
+func writeBodyError(w http.ResponseWriter, err error, message string) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, "invalid_request_error", "request_too_large",
+			fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit))
+		return
+	}
+	writeError(w, http.StatusBadRequest, "invalid_request_error", "", message)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// errorBody is the OpenAI error envelope.
+type errorBody struct {
+	Error struct {
+		Message string  `json:"message"`
+		Type    string  `json:"type"`
+		Code    *string `json:"code"`
+	} `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, errType, code, message string) {
+	var body errorBody
+	body.Error.Message = message
+	body.Error.Type = errType
+	if code != "" {
+		body.Error.Code = &code
+	}
+	writeJSON(w, status, body)
+}
+
+// writeUpstreamError reports a failed provider request, keeping the provider's
+// status code and error type when it returned one. Transport errors are logged
+// but not echoed, as their URLs may carry provider credentials.
+func (s *Server) writeUpstreamError(w http.ResponseWriter, r *http.Request, b *backend, err error) {
+	s.log(r, b).Warn("Provider request failed", "error", err)
+	var providerErr *dto.LLMError
+	switch {
+	case errors.As(err, &providerErr) && providerErr.Code >= 400 && providerErr.Code < 600:
+		if providerErr.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(providerErr.RetryAfter.Seconds()))))
+		}
+		errType := providerErr.Type
+		if errType == "" {
+			errType = "upstream_error"
+		}
+		writeError(w, providerErr.Code, errType, "", providerErr.Message)
+	case errors.Is(err, context.Canceled):
+		// The client has gone away.
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusGatewayTimeout, "upstream_error", "", "The provider did not respond in time")
+	default:
+		writeError(w, http.StatusBadGateway, "upstream_error", "", "The provider request failed")
+	}
+}
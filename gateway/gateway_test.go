@@ -0,0 +1,198 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/YspCoder/omnigo/adapter"
+	"github.com/YspCoder/omnigo/utils"
+)
+
+func TestGatewayRoutesAndTranslates(t *testing.T) {
+	var (
+		upstream     map[string]interface{}
+		upstreamPath string
+	)
+	openai := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamPath = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&upstream)
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","choices":[{"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}],` +
+			`"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`))
+	}))
+	defer openai.Close()
+	anthropic := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`{"type":"message_start","message":{"usage":{"input_tokens":7}}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" there"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}`,
+			`{"type":"message_stop"}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+	}))
+	defer anthropic.Close()
+
+	server, err := New(Config{
+		Routes: []Route{
+			{Name: "fast", Model: "chat", Provider: "openai", UpstreamModel: "gpt-4o-mini", APIKey: "sk-openai", Endpoint: openai.URL},
+			{Name: "claude", Model: "chat", Provider: "anthropic", UpstreamModel: "claude-sonnet-4", APIKey: "sk-ant", Endpoint: anthropic.URL},
+		},
+		Keys: []Key{
+			{Key: "team-a", Name: "a", Routes: []string{"fast"}},
+			{Key: "team-b", Name: "b", Routes: []string{"claude", "fast"}},
+		},
+	}, WithRegistry(adapter.NewRegistry()), WithLogger(utils.NewLogger(utils.LogLevelOff)))
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	gw := httptest.NewServer(server)
+	defer gw.Close()
+
+	call := func(method, path, key, body string) *http.Response {
+		req, _ := http.NewRequest(method, gw.URL+path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		return resp
+	}
+
+	if resp := call("GET", "/v1/models", "wrong", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("unknown key: status %d, want 401", resp.StatusCode)
+	}
+
+	resp := call("POST", "/v1/chat/completions", "team-a",
+		`{"model":"chat","max_tokens":16,"messages":[{"role":"user","content":[{"type":"text","text":"hi"}]}]}`)
+	var completion chatCompletion
+	_ = json.NewDecoder(resp.Body).Decode(&completion)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(completion.Choices) != 1 || *completion.Choices[0].Message.Content != "hello" {
+		t.Fatalf("chat completion: status %d, body %+v", resp.StatusCode, completion)
+	}
+	if completion.Model != "chat" || *completion.Choices[0].FinishReason != "stop" || completion.Usage.TotalTokens != 6 {
+		t.Fatalf("unexpected completion metadata: %+v", completion)
+	}
+	if upstream["model"] != "gpt-4o-mini" || upstream["max_tokens"] != float64(16) {
+		t.Fatalf("upstream request = %v", upstream)
+	}
+
+	// team-b prefers the Anthropic route for the same model name.
+	resp = call("POST", "/v1/chat/completions", "team-b",
+		`{"model":"chat","stream":true,"stream_options":{"include_usage":true},"messages":[{"role":"user","content":"hi"}]}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("stream: status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	var (
+		text   strings.Builder
+		reason string
+		usage  *chatUsage
+		done   bool
+	)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+		var chunk chatCompletion
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("invalid chunk %q: %v", data, err)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != nil {
+				text.WriteString(*choice.Delta.Content)
+			}
+			if choice.FinishReason != nil {
+				reason = *choice.FinishReason
+			}
+		}
+	}
+	if !done || text.String() != "Hi there" || reason != "stop" {
+		t.Fatalf("stream: done %v, text %q, finish reason %q", done, text.String(), reason)
+	}
+	if usage == nil || usage.PromptTokens != 7 || usage.CompletionTokens != 2 || usage.TotalTokens != 9 {
+		t.Fatalf("stream usage = %+v", usage)
+	}
+
+	// Anthropic cannot generate images, so team-b falls back to its OpenAI route.
+	resp = call("POST", "/v1/images/generations", "team-b", `{"model":"chat","prompt":"a cat","quality":"hd"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasSuffix(upstreamPath, "/images/generations") || upstream["quality"] != "hd" {
+		t.Fatalf("image generation: status %d, upstream %s %v", resp.StatusCode, upstreamPath, upstream)
+	}
+	resp = call("GET", "/v1/tasks/123?model=chat", "team-a", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("task status on a provider without tasks: status %d, want 400", resp.StatusCode)
+	}
+	resp = call("POST", "/v1/chat/completions", "team-a", `{"model":"unknown","messages":[{"role":"user","content":"hi"}]}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown model: status %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestGatewayLimitsRequestBody(t *testing.T) {
+	server, err := New(Config{
+		MaxBodyBytes: 64,
+		Routes:       []Route{{Model: "chat", Provider: "openai", APIKey: "sk-openai", Endpoint: "http://127.0.0.1:1"}},
+	}, WithRegistry(adapter.NewRegistry()), WithLogger(utils.NewLogger(utils.LogLevelOff)))
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	large := `{"model":"chat","prompt":"` + strings.Repeat("a", 100) + `","messages":[{"role":"user","content":"hi"}]}`
+	for _, path := range []string{"/v1/chat/completions", "/v1/images/generations"} {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest("POST", path, strings.NewReader(large)))
+		if recorder.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("%s: status %d, want 413: %s", path, recorder.Code, recorder.Body)
+		}
+	}
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model":"chat"}`)))
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("small invalid body: status %d, want 400", recorder.Code)
+	}
+}
+
+func TestGatewayReportsInterruptedStream(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Promise more than is sent, so the connection ends mid-stream.
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Content-Length", "1000")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hi\"}}]}\n\n"))
+	}))
+	defer upstream.Close()
+	server, err := New(Config{
+		Routes: []Route{{Model: "chat", Provider: "openai", APIKey: "sk-openai", Endpoint: upstream.URL}},
+	}, WithRegistry(adapter.NewRegistry()), WithLogger(utils.NewLogger(utils.LogLevelOff)))
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest("POST", "/v1/chat/completions",
+		strings.NewReader(`{"model":"chat","stream":true,"messages":[{"role":"user","content":"hi"}]}`)))
+	body := recorder.Body.String()
+	if !strings.Contains(body, `"code":"stream_interrupted"`) || strings.Contains(body, "[DONE]") {
+		t.Fatalf("expected an error event without [DONE], got %q", body)
+	}
+}
@@ -0,0 +1,106 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/YspCoder/omnigo/adapter"
+	"github.com/YspCoder/omnigo/dto"
+)
+
+// mediaRequest is an OpenAI image generation request, extended with the video
+// fields of dto.MediaRequest. Other fields are passed on in Extra.
+type mediaRequest struct {
+	Model          string `json:"model"`
+	Prompt         string `json:"prompt"`
+	N              int    `json:"n"`
+	Size           string `json:"size"`
+	Duration       int    `json:"duration"`
+	Fps            int    `json:"fps"`
+	Seed           int    `json:"seed"`
+	ResponseFormat string `json:"response_format"`
+}
+
+func (s *Server) handleImages(w http.ResponseWriter, r *http.Request) {
+	s.handleMedia(w, r, dto.MediaTypeImage)
+}
+
+func (s *Server) handleVideos(w http.ResponseWriter, r *http.Request) {
+	s.handleMedia(w, r, dto.MediaTypeVideo)
+}
+
+// handleMedia relays an image or video generation request. Asynchronous providers
+// answer with a task ID to poll at /v1/tasks/{task_id}.
+func (s *Server) handleMedia(w http.ResponseWriter, r *http.Request, mediaType dto.MediaType) {
+	var fields map[string]interface{}
+	if !decodeBody(w, r, &fields) {
+		return
+	}
+	var body mediaRequest
+	if data, err := json.Marshal(fields); err != nil || json.Unmarshal(data, &body) != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", "Invalid media request")
+		return
+	}
+	if body.Model == "" || body.Prompt == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", "model and prompt are required")
+		return
+	}
+	b, ok := s.resolve(w, r, body.Model, string(mediaType)+" generation", func(b *backend) bool {
+		return b.spec.SupportsMode(string(mediaType))
+	})
+	if !ok {
+		return
+	}
+
+	for _, name := range []string{"model", "prompt", "n", "size", "duration", "fps", "seed", "response_format"} {
+		delete(fields, name)
+	}
+	request := &dto.MediaRequest{
+		Type:           mediaType,
+		Model:          b.config.Model,
+		Prompt:         body.Prompt,
+		N:              body.N,
+		Size:           body.Size,
+		Duration:       body.Duration,
+		Fps:            body.Fps,
+		Seed:           body.Seed,
+		ResponseFormat: body.ResponseFormat,
+	}
+	if len(fields) > 0 {
+		request.Extra = fields
+	}
+	response, err := s.relay.Media(r.Context(), b.adaptor, b.config, request)
+	if err != nil {
+		s.writeUpstreamError(w, r, b, err)
+		return
+	}
+	if response.Created == 0 {
+		response.Created = time.Now().Unix()
+	}
+	s.log(r, b).Debug("Media request served", "type", mediaType, "task_id", response.TaskID)
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleTask reports the status of a media task. The model query parameter
+// selects the backend that created the task.
+func (s *Server) handleTask(w http.ResponseWriter, r *http.Request) {
+	model := r.URL.Query().Get("model")
+	if model == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", "The model query parameter is required")
+		return
+	}
+	b, ok := s.resolve(w, r, model, "task status queries", func(b *backend) bool {
+		_, ok := b.adaptor.(adapter.TaskAdaptor)
+		return ok
+	})
+	if !ok {
+		return
+	}
+	response, err := s.relay.TaskStatus(r.Context(), b.adaptor, b.config, r.PathValue("task_id"))
+	if err != nil {
+		s.writeUpstreamError(w, r, b, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,170 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/YspCoder/omnigo/dto"
+)
+
+// StreamCallback receives every token read from a stream before it is aggregated.
+// Returning an error stops the collection.
+type StreamCallback func(*StreamToken) error
+
+// StreamAggregator rebuilds a complete chat response from streamed tokens.
+// It can be fed manually with Add or driven by CollectStream.
+type StreamAggregator struct {
+	id           string
+	model        string
+	created      int64
+	text         strings.Builder
+	toolCalls    map[int]ToolCall
+	usage        dto.Usage
+	finishReason string
+}
+
+// NewStreamAggregator creates an empty StreamAggregator.
+func NewStreamAggregator() *StreamAggregator {
+	return &StreamAggregator{toolCalls: make(map[int]ToolCall)}
+}
+
+// Add folds a single token into the aggregated response.
+func (a *StreamAggregator) Add(token *StreamToken) {
+	if token == nil {
+		return
+	}
+	// Provider streams report the response ID, model and creation time in the
+	// metadata of every token; the first values seen are kept.
+	if id, ok := token.Metadata["response_id"].(string); ok && a.id == "" {
+		a.id = id
+	}
+	if model, ok := token.Metadata["model"].(string); ok && a.model == "" {
+		a.model = model
+	}
+	if created, ok := token.Metadata["created"].(int64); ok && a.created == 0 {
+		a.created = created
+	}
+	switch token.Type {
+	case TokenTypeToolCall:
+		if token.ToolCall == nil {
+			return
+		}
+		index := len(a.toolCalls)
+		if value, ok := token.Metadata["tool_call_index"].(int); ok {
+			index = value
+		}
+		// Tool-call tokens carry the accumulated state, so the latest one wins.
+		a.toolCalls[index] = *token.ToolCall
+	case TokenTypeUsage:
+		if token.Usage != nil {
+			a.usage = *token.Usage
+		}
+	case TokenTypeFinish:
+		if raw, ok := token.Metadata["raw_finish_reason"].(string); ok && raw != "" {
+			a.finishReason = raw
+		} else {
+			a.finishReason = string(token.FinishReason)
+		}
+	case TokenTypeError:
+		// Errors are surfaced by TokenStream.Next; nothing to aggregate.
+	default:
+		a.text.WriteString(token.Text)
+	}
+}
+
+// Response returns the aggregated response in the same shape as a non-streaming chat call.
+func (a *StreamAggregator) Response() *dto.ChatResponse {
+	indexes := make([]int, 0, len(a.toolCalls))
+	for index := range a.toolCalls {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	calls := make([]ToolCall, 0, len(indexes))
+	for _, index := range indexes {
+		calls = append(calls, a.toolCalls[index])
+	}
+
+	message := dto.Message{
+		Role:      "assistant",
+		Content:   a.text.String(),
+		ToolCalls: toDTOToolCalls(calls),
+	}
+	return &dto.ChatResponse{
+		ID:      a.id,
+		Object:  "chat.completion",
+		Created: a.created,
+		Model:   a.model,
+		Choices: []dto.ChatChoice{{Message: message, FinishReason: a.finishReason}},
+		Usage:   a.usage,
+	}
+}
+
+// Result converts the aggregated response into a GenerateResult.
+func (a *StreamAggregator) Result() (*GenerateResult, error) {
+	result, err := newGenerateResult(a.Response())
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeResponse, "invalid stream response", err)
+	}
+	return result, nil
+}
+
+// CollectStream drains the stream and returns the aggregated response.
+// Each token is passed to onToken (when non-nil) before it is aggregated, which lets
+// callers forward tokens to a client while still collecting the final result.
+// The stream is closed when CollectStream returns.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - stream: The token stream to drain
+//   - onToken: Optional callback invoked for every token
+//
+// Returns:
+//   - The aggregated response; on error, the partial response collected so far
+//   - The first error from the stream or the callback, or nil at a clean end of stream
+func CollectStream(ctx context.Context, stream TokenStream, onToken StreamCallback) (*dto.ChatResponse, error) {
+	defer stream.Close()
+
+	aggregator := NewStreamAggregator()
+	for {
+		token, err := stream.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return aggregator.Response(), nil
+		}
+		if err != nil {
+			return aggregator.Response(), err
+		}
+		if onToken != nil {
+			if err := onToken(token); err != nil {
+				return aggregator.Response(), err
+			}
+		}
+		aggregator.Add(token)
+	}
+}
+
+// TeeStream returns a TokenStream that invokes onToken for every token it yields.
+// An error from onToken is returned from Next in place of the token.
+func TeeStream(stream TokenStream, onToken StreamCallback) TokenStream {
+	return &teeStream{TokenStream: stream, onToken: onToken}
+}
+
+type teeStream struct {
+	TokenStream
+	onToken StreamCallback
+}
+
+func (t *teeStream) Next(ctx context.Context) (*StreamToken, error) {
+	token, err := t.TokenStream.Next(ctx)
+	if err != nil {
+		return token, err
+	}
+	if t.onToken != nil {
+		if err := t.onToken(token); err != nil {
+			return nil, err
+		}
+	}
+	return token, nil
+}
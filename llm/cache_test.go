@@ -0,0 +1,36 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/YspCoder/omnigo/config"
+)
+
+func TestGenerateResultServesCachedResponse(t *testing.T) {
+	var calls int32
+	model := newTestLLM(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"resp_1","choices":[{"message":{"role":"assistant","content":"cached answer"},"finish_reason":"stop"}]}`))
+	}, config.SetEnableCaching(true))
+
+	ctx := context.Background()
+	first, err := model.GenerateResult(ctx, NewPrompt("hello"))
+	if err != nil {
+		t.Fatalf("first GenerateResult returned error: %v", err)
+	}
+	second, err := model.GenerateResult(ctx, NewPrompt("hello"))
+	if err != nil {
+		t.Fatalf("second GenerateResult returned error: %v", err)
+	}
+
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("expected one provider call, got %d", calls)
+	}
+	if first.Cached || !second.Cached || second.Content != "cached answer" {
+		t.Fatalf("unexpected cache metadata: first=%v second=%+v", first.Cached, second)
+	}
+}
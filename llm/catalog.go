@@ -0,0 +1,126 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/YspCoder/omnigo/catalog"
+)
+
+// reasoningOptions are provider options that only reasoning models accept.
+var reasoningOptions = []string{"reasoning_effort", "thinking"}
+
+// ModelInfoProvider is implemented by LLMs that expose the catalog entry of their model.
+type ModelInfoProvider interface {
+	ModelInfo() (catalog.Model, bool)
+}
+
+// ModelInfo returns the catalog entry of the configured model.
+//
+// Returns:
+//   - The model's limits, features and prices
+//   - Whether the catalog knows the model; if not, no feature checks are made
+func (l *LLMImpl) ModelInfo() (catalog.Model, bool) {
+	if l.modelInfo == nil {
+		return catalog.Model{}, false
+	}
+	return *l.modelInfo, true
+}
+
+// preparePrompt rejects features the model does not support and fits the prompt
+// into the context window. It runs once per call, before any attempt is made.
+func (l *LLMImpl) preparePrompt(ctx context.Context, prompt *Prompt) (*Prompt, error) {
+	if err := l.checkModelSupport(prompt); err != nil {
+		return nil, err
+	}
+	return l.fitContext(ctx, prompt)
+}
+
+// checkModelSupport returns ErrorTypeUnsupported when the prompt uses tools, images
+// or reasoning options that the model's catalog entry does not list.
+func (l *LLMImpl) checkModelSupport(prompt *Prompt) error {
+	model := l.modelInfo
+	if model == nil {
+		return nil
+	}
+	unsupported := func(feature string) error {
+		return NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("model %s does not support %s", l.config.Model, feature), nil)
+	}
+	if len(prompt.Tools) > 0 && !model.Tools {
+		return unsupported("tool calls")
+	}
+	if !model.Vision {
+		for _, msg := range prompt.Messages {
+			for _, part := range msg.Parts {
+				if part.Type == ContentPartImage {
+					return unsupported("image input")
+				}
+			}
+		}
+	}
+	if !model.Reasoning {
+		l.optionsMutex.RLock()
+		defer l.optionsMutex.RUnlock()
+		for _, option := range reasoningOptions {
+			if _, ok := l.Options[option]; ok {
+				return unsupported("reasoning options (" + option + ")")
+			}
+		}
+	}
+	return nil
+}
+
+// contextWindow returns the configured context window, or the catalog's.
+func (l *LLMImpl) contextWindow() int {
+	if l.config.ContextWindow > 0 || l.modelInfo == nil {
+		return l.config.ContextWindow
+	}
+	return l.modelInfo.ContextWindow
+}
+
+// maxOutputTokens returns the output limit sent with requests: max_completion_tokens
+// or max_tokens from the options set with SetOption, else MaxTokens, capped at the
+// model's output limit.
+func (l *LLMImpl) maxOutputTokens() int {
+	l.optionsMutex.RLock()
+	options := make(map[string]interface{}, len(l.Options))
+	for k, v := range l.Options {
+		options[k] = v
+	}
+	l.optionsMutex.RUnlock()
+	options = applyDefaultOptions(options, l.config)
+
+	tokens := 0
+	for _, key := range []string{"max_completion_tokens", "max_tokens"} {
+		switch value := options[key].(type) {
+		case int:
+			tokens = value
+		case int64:
+			tokens = int(value)
+		case float64:
+			tokens = int(value)
+		}
+		if tokens > 0 {
+			break
+		}
+	}
+	if l.modelInfo != nil && l.modelInfo.MaxOutputTokens > 0 && tokens > l.modelInfo.MaxOutputTokens {
+		return l.modelInfo.MaxOutputTokens
+	}
+	return tokens
+}
+
+// clampMaxTokens caps max_tokens and max_completion_tokens at the model's output
+// limit, which providers would otherwise reject with a 400.
+func (l *LLMImpl) clampMaxTokens(options map[string]interface{}) map[string]interface{} {
+	if l.modelInfo == nil || l.modelInfo.MaxOutputTokens <= 0 {
+		return options
+	}
+	limit := l.modelInfo.MaxOutputTokens
+	for _, key := range []string{"max_tokens", "max_completion_tokens"} {
+		if value, ok := options[key].(int); ok && value > limit {
+			options[key] = limit
+		}
+	}
+	return options
+}
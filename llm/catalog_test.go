@@ -0,0 +1,47 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/YspCoder/omnigo/catalog"
+	"github.com/YspCoder/omnigo/config"
+	"github.com/YspCoder/omnigo/utils"
+)
+
+func TestModelCatalogClampsAndRejects(t *testing.T) {
+	var maxTokens float64
+	model := newTestLLM(t, func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		maxTokens, _ = req["max_tokens"].(float64)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	},
+		config.SetModel("tiny-1"),
+		config.SetMaxTokens(4096),
+		config.SetModelCatalog(catalog.New(catalog.Model{Provider: "openai", Name: "tiny", MaxOutputTokens: 256})),
+	)
+	if model.SupportsStreaming() || model.SupportsJSONSchema() {
+		t.Fatal("features missing from the catalog entry should be reported as unsupported")
+	}
+
+	if _, err := model.Generate(context.Background(), NewPrompt("hi")); err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	if maxTokens != 256 {
+		t.Fatalf("max_tokens = %v, want it clamped to 256", maxTokens)
+	}
+
+	maxTokens = 0
+	_, err := model.Generate(context.Background(), NewPrompt("hi", WithTools([]utils.Tool{{Type: "function"}})))
+	var llmErr *LLMError
+	if !errors.As(err, &llmErr) || llmErr.Type != ErrorTypeUnsupported {
+		t.Fatalf("expected ErrorTypeUnsupported for tools, got %v", err)
+	}
+	if maxTokens != 0 {
+		t.Fatal("unsupported request was sent")
+	}
+}
@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"encoding/base64"
+
+	"github.com/YspCoder/omnigo/dto"
+)
+
+// ContentPart is a single piece of multimodal message content (text, image, audio or file).
+type ContentPart = dto.ContentPart
+
+// Content part types.
+const (
+	ContentPartText  = dto.ContentPartText
+	ContentPartImage = dto.ContentPartImage
+	ContentPartAudio = dto.ContentPartAudio
+	ContentPartFile  = dto.ContentPartFile
+)
+
+// TextPart creates a text content part.
+func TextPart(text string) ContentPart {
+	return ContentPart{Type: ContentPartText, Text: text}
+}
+
+// ImageURLPart creates an image part referencing a remote URL.
+func ImageURLPart(url string) ContentPart {
+	return ContentPart{Type: ContentPartImage, URL: url}
+}
+
+// ImageDataPart creates an inline image part from raw bytes.
+//
+// Parameters:
+//   - mimeType: Image MIME type, e.g. "image/png"
+//   - data: Raw image bytes; they are base64-encoded for transport
+func ImageDataPart(mimeType string, data []byte) ContentPart {
+	return ContentPart{Type: ContentPartImage, MIMEType: mimeType, Data: base64.StdEncoding.EncodeToString(data)}
+}
+
+// AudioDataPart creates an inline audio part from raw bytes (e.g. "audio/wav").
+func AudioDataPart(mimeType string, data []byte) ContentPart {
+	return ContentPart{Type: ContentPartAudio, MIMEType: mimeType, Data: base64.StdEncoding.EncodeToString(data)}
+}
+
+// AudioURLPart creates an audio part referencing a remote URL.
+func AudioURLPart(url, mimeType string) ContentPart {
+	return ContentPart{Type: ContentPartAudio, URL: url, MIMEType: mimeType}
+}
+
+// FileURLPart creates a file part (e.g. a PDF) referencing a remote URL.
+func FileURLPart(url, mimeType string) ContentPart {
+	return ContentPart{Type: ContentPartFile, URL: url, MIMEType: mimeType}
+}
+
+// FileDataPart creates an inline file part from raw bytes.
+//
+// Parameters:
+//   - mimeType: File MIME type, e.g. "application/pdf"
+//   - data: Raw file bytes; they are base64-encoded for transport
+//   - filename: Optional file name passed to providers that accept one
+func FileDataPart(mimeType string, data []byte, filename string) ContentPart {
+	return ContentPart{Type: ContentPartFile, MIMEType: mimeType, Data: base64.StdEncoding.EncodeToString(data), Filename: filename}
+}
+
+// WithContentParts attaches multimodal parts to the last user message of the prompt,
+// adding a user message if there is none.
+//
+// Parameters:
+//   - parts: Content parts to attach after the message text
+func WithContentParts(parts ...ContentPart) PromptOption {
+	return func(p *Prompt) {
+		for i := len(p.Messages) - 1; i >= 0; i-- {
+			if p.Messages[i].Role == "user" {
+				p.Messages[i].Parts = append(p.Messages[i].Parts, parts...)
+				return
+			}
+		}
+		p.Messages = append(p.Messages, PromptMessage{Role: "user", Parts: parts})
+	}
+}
+
+// WithImageURL attaches an image URL to the last user message.
+func WithImageURL(url string) PromptOption {
+	return WithContentParts(ImageURLPart(url))
+}
+
+// WithImageData attaches an inline image to the last user message.
+func WithImageData(mimeType string, data []byte) PromptOption {
+	return WithContentParts(ImageDataPart(mimeType, data))
+}
+
+// WithAudioData attaches inline audio to the last user message.
+func WithAudioData(mimeType string, data []byte) PromptOption {
+	return WithContentParts(AudioDataPart(mimeType, data))
+}
+
+// WithFile attaches a file URL (e.g. a PDF) to the last user message.
+func WithFile(url, mimeType string) PromptOption {
+	return WithContentParts(FileURLPart(url, mimeType))
+}
+
+// WithFileData attaches an inline file to the last user message.
+func WithFileData(mimeType string, data []byte, filename string) PromptOption {
+	return WithContentParts(FileDataPart(mimeType, data, filename))
+}
+
+// messageContent returns the DTO content for a prompt message: plain text, or a
+// part slice with the text first when the message carries multimodal parts.
+func messageContent(msg PromptMessage) interface{} {
+	if len(msg.Parts) == 0 {
+		return msg.Content
+	}
+	parts := make([]ContentPart, 0, len(msg.Parts)+1)
+	if msg.Content != "" {
+		parts = append(parts, TextPart(msg.Content))
+	}
+	return append(parts, msg.Parts...)
+}
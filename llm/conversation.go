@@ -0,0 +1,309 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/YspCoder/omnigo/utils"
+)
+
+// Conversation is a multi-turn chat session on top of an LLM. It owns its system
+// prompt and tools and records the history: each successful Send, Continue, Stream
+// or Run appends the user input, the assistant reply and any tool messages.
+//
+// A Conversation is safe for concurrent use. Turns are serialized so the history
+// stays in order, and several conversations may share one LLM instance. The history
+// can be persisted with json.Marshal and restored with LoadConversation.
+//
+// Example:
+//
+//	conv := NewConversation(model, WithConversationSystemPrompt("You are a travel agent.", ""))
+//	reply, err := conv.Send(ctx, "Plan a weekend in Lisbon")
+//	reply, err = conv.Send(ctx, "Make it cheaper")
+type Conversation struct {
+	llm  LLM
+	turn sync.Mutex // Serializes turns
+	mu   sync.Mutex // Guards the fields below
+
+	systemPrompt    string
+	systemCacheType CacheType
+	tools           []utils.Tool
+	truncation      TruncationStrategy
+	messages        []PromptMessage
+}
+
+// ConversationOption is a function type for configuring a Conversation.
+type ConversationOption func(*Conversation)
+
+// WithConversationSystemPrompt sets the system prompt sent with every turn.
+func WithConversationSystemPrompt(prompt string, cacheType CacheType) ConversationOption {
+	return func(c *Conversation) {
+		c.systemPrompt = prompt
+		c.systemCacheType = cacheType
+	}
+}
+
+// WithConversationTools sets the tools offered to the model on every turn.
+func WithConversationTools(tools ...utils.Tool) ConversationOption {
+	return func(c *Conversation) {
+		c.tools = append([]utils.Tool(nil), tools...)
+	}
+}
+
+// WithConversationTruncation sets the strategy that shortens the history sent to
+// the model when it exceeds the context window. The stored history is not changed.
+func WithConversationTruncation(strategy TruncationStrategy) ConversationOption {
+	return func(c *Conversation) {
+		c.truncation = strategy
+	}
+}
+
+// WithConversationHistory starts the conversation from existing messages.
+func WithConversationHistory(messages []PromptMessage) ConversationOption {
+	return func(c *Conversation) {
+		c.messages = append([]PromptMessage(nil), messages...)
+	}
+}
+
+// NewConversation creates an empty conversation using the given LLM.
+func NewConversation(model LLM, opts ...ConversationOption) *Conversation {
+	c := &Conversation{llm: model}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// LoadConversation restores a conversation serialized with json.Marshal.
+//
+// Parameters:
+//   - model: The LLM used for further turns
+//   - data: JSON produced by marshaling a Conversation
+//
+// Returns:
+//   - The restored conversation
+//   - An error if data cannot be decoded
+func LoadConversation(model LLM, data []byte) (*Conversation, error) {
+	c := NewConversation(model)
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// conversationState is the serialized form of a Conversation.
+type conversationState struct {
+	SystemPrompt    string          `json:"system_prompt,omitempty"`
+	SystemCacheType CacheType       `json:"system_cache_type,omitempty"`
+	Tools           []utils.Tool    `json:"tools,omitempty"`
+	Messages        []PromptMessage `json:"messages"`
+}
+
+// MarshalJSON encodes the system prompt, tools and history.
+func (c *Conversation) MarshalJSON() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	messages := c.messages
+	if messages == nil {
+		messages = []PromptMessage{}
+	}
+	return json.Marshal(conversationState{
+		SystemPrompt:    c.systemPrompt,
+		SystemCacheType: c.systemCacheType,
+		Tools:           c.tools,
+		Messages:        messages,
+	})
+}
+
+// UnmarshalJSON replaces the system prompt, tools and history. The LLM is kept.
+func (c *Conversation) UnmarshalJSON(data []byte) error {
+	var state conversationState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.systemPrompt = state.SystemPrompt
+	c.systemCacheType = state.SystemCacheType
+	c.tools = state.Tools
+	c.messages = state.Messages
+	return nil
+}
+
+// Messages returns a copy of the history.
+func (c *Conversation) Messages() []PromptMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]PromptMessage(nil), c.messages...)
+}
+
+// SystemPrompt returns the system prompt sent with every turn.
+func (c *Conversation) SystemPrompt() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.systemPrompt
+}
+
+// SetSystemPrompt replaces the system prompt for later turns.
+func (c *Conversation) SetSystemPrompt(prompt string, cacheType CacheType) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.systemPrompt = prompt
+	c.systemCacheType = cacheType
+}
+
+// AddToolResult records the output of a tool call requested in the last reply.
+// Call Continue to send the results back to the model.
+func (c *Conversation) AddToolResult(toolCallID, name, content string) {
+	c.append(PromptMessage{Role: "tool", Content: content, Name: name, ToolCallID: toolCallID})
+}
+
+// Reset clears the history, keeping the system prompt and tools.
+func (c *Conversation) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = nil
+}
+
+// Send adds a user message, generates the reply and records both in the history.
+// Nothing is recorded when generation fails.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - input: The user message
+//   - opts: Options applied to this turn's prompt only, e.g. WithToolChoice
+//
+// Returns:
+//   - The generation result, including any tool calls
+//   - Error types as per GenerateResult
+func (c *Conversation) Send(ctx context.Context, input string, opts ...PromptOption) (*GenerateResult, error) {
+	return c.generate(ctx, userMessage(input), opts)
+}
+
+// Continue generates the next reply without new user input, typically after
+// AddToolResult, and records it in the history.
+func (c *Conversation) Continue(ctx context.Context, opts ...PromptOption) (*GenerateResult, error) {
+	return c.generate(ctx, nil, opts)
+}
+
+func (c *Conversation) generate(ctx context.Context, turn []PromptMessage, opts []PromptOption) (*GenerateResult, error) {
+	c.turn.Lock()
+	defer c.turn.Unlock()
+
+	result, err := c.llm.GenerateResult(ctx, c.prompt(turn, opts))
+	if err != nil {
+		return nil, err
+	}
+	c.append(append(turn, result.AssistantMessage())...)
+	return result, nil
+}
+
+// Stream adds a user message and streams the reply. The turn is recorded once the
+// stream ends cleanly with io.EOF; it is dropped if the stream fails or is closed
+// early. Other turns wait until the stream is finished or closed.
+func (c *Conversation) Stream(ctx context.Context, input string, opts ...PromptOption) (TokenStream, error) {
+	c.turn.Lock()
+	turn := userMessage(input)
+	stream, err := c.llm.Stream(ctx, c.prompt(turn, opts))
+	if err != nil {
+		c.turn.Unlock()
+		return nil, err
+	}
+	return &conversationStream{
+		TokenStream:  stream,
+		conversation: c,
+		turn:         turn,
+		aggregator:   NewStreamAggregator(),
+	}, nil
+}
+
+// Run executes a Runner tool loop for a user message and records the whole exchange,
+// including tool calls and results, in the history.
+func (c *Conversation) Run(ctx context.Context, runner *Runner, input string, opts ...PromptOption) (*RunResult, error) {
+	c.turn.Lock()
+	defer c.turn.Unlock()
+
+	// The run returns the prompt's messages followed by the new ones. The prompt is
+	// a snapshot, so messages added meanwhile (e.g. by AddToolResult) do not shift
+	// the offset.
+	turn := userMessage(input)
+	prompt := c.prompt(turn, opts)
+	run, err := runner.Run(ctx, prompt)
+	if err != nil {
+		return run, err
+	}
+	c.append(append(turn, run.Messages[len(prompt.Messages):]...)...)
+	return run, nil
+}
+
+// prompt builds the request for a turn from the history and the new messages.
+func (c *Conversation) prompt(turn []PromptMessage, opts []PromptOption) *Prompt {
+	c.mu.Lock()
+	prompt := &Prompt{
+		SystemPrompt:    c.systemPrompt,
+		SystemCacheType: c.systemCacheType,
+		Tools:           append([]utils.Tool(nil), c.tools...),
+		Truncation:      c.truncation,
+		Messages:        append(append(make([]PromptMessage, 0, len(c.messages)+len(turn)), c.messages...), turn...),
+	}
+	c.mu.Unlock()
+	if len(turn) > 0 {
+		prompt.Input = turn[len(turn)-1].Content
+	}
+	prompt.Apply(opts...)
+	return prompt
+}
+
+func (c *Conversation) append(messages ...PromptMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, messages...)
+}
+
+func userMessage(input string) []PromptMessage {
+	return []PromptMessage{{Role: "user", Content: input}}
+}
+
+// conversationStream records the streamed turn in the conversation when it completes.
+type conversationStream struct {
+	TokenStream
+	conversation *Conversation
+	turn         []PromptMessage
+	aggregator   *StreamAggregator
+	done         sync.Once
+}
+
+func (s *conversationStream) Next(ctx context.Context) (*StreamToken, error) {
+	token, err := s.TokenStream.Next(ctx)
+	if errors.Is(err, io.EOF) {
+		s.finish(true)
+		return token, err
+	}
+	if err != nil {
+		s.finish(false)
+		return token, err
+	}
+	s.aggregator.Add(token)
+	return token, nil
+}
+
+func (s *conversationStream) Close() error {
+	s.finish(false)
+	return s.TokenStream.Close()
+}
+
+// finish records the turn when complete and lets the next turn start.
+func (s *conversationStream) finish(complete bool) {
+	s.done.Do(func() {
+		defer s.conversation.turn.Unlock()
+		if !complete {
+			return
+		}
+		if result, err := s.aggregator.Result(); err == nil {
+			s.conversation.append(append(s.turn, result.AssistantMessage())...)
+		}
+	})
+}
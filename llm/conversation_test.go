@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/YspCoder/omnigo/utils"
+)
+
+func TestConversationKeepsHistoryPerSession(t *testing.T) {
+	// The server replies with the system prompt and the number of messages it received.
+	model := newTestLLM(t, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		system := ""
+		if len(req.Messages) > 0 && req.Messages[0].Role == "system" {
+			system = req.Messages[0].Content
+		}
+		reply := fmt.Sprintf("%s:%d", system, len(req.Messages))
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"` + reply + `"},"finish_reason":"stop"}]}`))
+	})
+
+	conversations := make([]*Conversation, 4)
+	var wg sync.WaitGroup
+	for i := range conversations {
+		conversations[i] = NewConversation(model, WithConversationSystemPrompt(fmt.Sprintf("sys%d", i), ""))
+		wg.Add(1)
+		go func(conv *Conversation, system string) {
+			defer wg.Done()
+			for turn := 0; turn < 2; turn++ {
+				result, err := conv.Send(context.Background(), "hi")
+				if err != nil {
+					t.Errorf("Send returned error: %v", err)
+					return
+				}
+				// System prompt + two messages per earlier turn + the new user message.
+				if want := fmt.Sprintf("%s:%d", system, 2+2*turn); result.Content != want {
+					t.Errorf("turn %d reply = %q, want %q", turn, result.Content, want)
+				}
+			}
+		}(conversations[i], fmt.Sprintf("sys%d", i))
+	}
+	wg.Wait()
+
+	if _, ok := model.(*LLMImpl).Options["system_prompt"]; ok {
+		t.Error("conversation system prompt leaked into shared LLM options")
+	}
+
+	data, err := json.Marshal(conversations[0])
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+	restored, err := LoadConversation(model, data)
+	if err != nil {
+		t.Fatalf("LoadConversation returned error: %v", err)
+	}
+	if restored.SystemPrompt() != "sys0" || len(restored.Messages()) != 4 {
+		t.Fatalf("restored conversation = %q with %d messages", restored.SystemPrompt(), len(restored.Messages()))
+	}
+	result, err := restored.Send(context.Background(), "again")
+	if err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	if result.Content != "sys0:6" {
+		t.Fatalf("reply after restore = %q, want %q", result.Content, "sys0:6")
+	}
+}
+
+func TestConversationRunRecordsToolExchange(t *testing.T) {
+	model := &scriptedLLM{results: []*GenerateResult{
+		{Content: "hello"},
+		{ToolCalls: []ToolCall{toolCall("1", "echo", `{"v":"a"}`)}},
+		{Content: "done"},
+	}}
+	conv := NewConversation(model)
+	if _, err := conv.Send(context.Background(), "hi"); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	runner := NewRunner(model, WithRunnerTool(utils.Tool{Function: utils.Function{Name: "echo"}},
+		func(ctx context.Context, args json.RawMessage) (string, error) { return string(args), nil }))
+	if _, err := conv.Run(context.Background(), runner, "call echo"); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	var roles []string
+	for _, msg := range conv.Messages() {
+		roles = append(roles, msg.Role)
+	}
+	if got := strings.Join(roles, ","); got != "user,assistant,user,assistant,tool,assistant" {
+		t.Fatalf("recorded roles = %s", got)
+	}
+}
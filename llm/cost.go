@@ -0,0 +1,165 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/YspCoder/omnigo/cost"
+	"github.com/YspCoder/omnigo/dto"
+)
+
+// CostTrackerProvider is implemented by LLMs that account for their spend.
+type CostTrackerProvider interface {
+	CostTracker() *cost.Tracker
+}
+
+// CostTracker returns the tracker recording the spend of this LLM: the one set
+// with SetCostTracker, or a tracker of its own limited by SetBudget. Every request
+// is also recorded in cost.Global.
+func (l *LLMImpl) CostTracker() *cost.Tracker {
+	return l.costs
+}
+
+// pendingTaskTTL bounds how long an asynchronous media task waits to be charged
+// when its status is never polled until the task finishes.
+const pendingTaskTTL = 24 * time.Hour
+
+// pendingTask remembers who submitted an asynchronous media task, so its output
+// can be charged to the right model and tag when it completes.
+type pendingTask struct {
+	model     string
+	tag       string
+	mediaType dto.MediaType
+	expires   time.Time
+}
+
+// checkBudget fails fast when the budget of this LLM or the global budget, overall
+// or for the caller tag in ctx, has been reached.
+//
+// Returns:
+//   - ErrorTypeBudgetExceeded wrapping a *cost.BudgetError
+func (l *LLMImpl) checkBudget(ctx context.Context) error {
+	tag := cost.TagFromContext(ctx)
+	for _, tracker := range []*cost.Tracker{l.costs, cost.Global()} {
+		if err := tracker.Allow(tag); err != nil {
+			return NewLLMError(ErrorTypeBudgetExceeded, "spend limit reached", err)
+		}
+	}
+	return nil
+}
+
+// prices returns the configured prices, or the catalog prices of model.
+func (l *LLMImpl) prices(model string) cost.Prices {
+	if l.config.Prices != nil {
+		return *l.config.Prices
+	}
+	entry := l.modelInfo
+	if model != l.config.Model {
+		entry = nil
+		if found, ok := l.models.Lookup(l.providerName, model); ok {
+			entry = &found
+		}
+	}
+	if entry == nil {
+		return cost.Prices{}
+	}
+	return cost.Prices{
+		InputPerMillion:  entry.InputPrice,
+		OutputPerMillion: entry.OutputPrice,
+		PerImage:         entry.ImagePrice,
+		PerVideo:         entry.PerVideoPrice,
+		PerVideoSecond:   entry.VideoPrice,
+	}
+}
+
+// recordCost records the usage of one request under the caller tag in ctx, in
+// this LLM's tracker and the global one.
+func (l *LLMImpl) recordCost(ctx context.Context, model string, usage cost.Usage) {
+	key := cost.Key{Provider: l.providerName, Model: model, Tag: cost.TagFromContext(ctx)}
+	prices := l.prices(model)
+	spent := l.costs.Record(key, usage, prices)
+	cost.Global().Record(key, usage, prices)
+	l.log(ctx).Debug("Recorded request cost", "model", model, "tag", key.Tag, "cost_usd", spent)
+}
+
+// tokenUsage converts provider token usage to billable usage.
+func tokenUsage(usage dto.Usage) cost.Usage {
+	return cost.Usage{InputTokens: usage.PromptTokens, OutputTokens: usage.CompletionTokens}
+}
+
+// recordMediaCost records the output of a media request. Asynchronous tasks that
+// report no output yet are recorded by TaskStatus once they complete.
+func (l *LLMImpl) recordMediaCost(ctx context.Context, request *dto.MediaRequest, response *dto.MediaResponse) {
+	var usage cost.Usage
+	if response.Usage != nil {
+		usage = cost.Usage{
+			Images:       response.Usage.ImageCount,
+			Videos:       response.Usage.VideoCount,
+			VideoSeconds: response.Usage.VideoDuration,
+		}
+	} else if request.Type != dto.MediaTypeVideo {
+		usage.Images = len(response.Data)
+	}
+	if usage == (cost.Usage{}) && response.TaskID != "" {
+		now := time.Now()
+		l.pendingTasks.Range(func(key, value interface{}) bool {
+			if now.After(value.(pendingTask).expires) {
+				l.pendingTasks.Delete(key)
+			}
+			return true
+		})
+		l.pendingTasks.Store(response.TaskID, pendingTask{
+			model:     request.Model,
+			tag:       cost.TagFromContext(ctx),
+			mediaType: request.Type,
+			expires:   now.Add(pendingTaskTTL),
+		})
+		return
+	}
+	l.recordCost(ctx, request.Model, usage)
+}
+
+// recordTaskCost records the output of a task submitted through Media once the
+// provider reports it. Each task is recorded once, however often it is polled, and
+// tasks that finish without output are forgotten.
+func (l *LLMImpl) recordTaskCost(ctx context.Context, taskID string, response *dto.TaskStatusResponse) {
+	value, ok := l.pendingTasks.Load(taskID)
+	if !ok {
+		return
+	}
+	task := value.(pendingTask)
+	usage := taskUsage(task.mediaType, response)
+	if usage == (cost.Usage{}) {
+		if finishedTaskStatuses[strings.ToLower(response.Output.TaskStatus)] {
+			l.pendingTasks.Delete(taskID)
+		}
+		return
+	}
+	if _, ok := l.pendingTasks.LoadAndDelete(taskID); !ok {
+		return
+	}
+	l.recordCost(cost.WithTag(ctx, task.tag), task.model, usage)
+}
+
+// taskUsage returns the billable output reported by a task status. Without usage
+// figures, a finished task with an output URL counts as one image or video.
+func taskUsage(mediaType dto.MediaType, response *dto.TaskStatusResponse) cost.Usage {
+	var usage cost.Usage
+	if response.Usage != nil {
+		if mediaType == dto.MediaTypeImage {
+			usage.Images = response.Usage.ImageCount
+		} else {
+			usage = cost.Usage{Videos: response.Usage.VideoCount, VideoSeconds: response.Usage.VideoDuration}
+		}
+	}
+	if usage == (cost.Usage{}) && response.Output.VideoURL != "" {
+		// Providers such as Google report image results in the same field.
+		if mediaType == dto.MediaTypeImage {
+			usage.Images = 1
+		} else {
+			usage.Videos = 1
+		}
+	}
+	return usage
+}
@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/YspCoder/omnigo/catalog"
+	"github.com/YspCoder/omnigo/config"
+	"github.com/YspCoder/omnigo/cost"
+	"github.com/YspCoder/omnigo/dto"
+)
+
+func TestCostTrackingAndBudget(t *testing.T) {
+	requests := 0
+	tracker := cost.NewTracker(0)
+	tracker.SetTagBudget("tenant-a", 0.001)
+	model := newTestLLM(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],` +
+			`"usage":{"prompt_tokens":1000,"completion_tokens":500,"total_tokens":1500}}`))
+	},
+		config.SetModel("gpt-4o-mini"),
+		config.SetCostTracker(tracker),
+		config.SetPrices(cost.Prices{InputPerMillion: 1, OutputPerMillion: 2}),
+	)
+
+	ctx := cost.WithTag(context.Background(), "tenant-a")
+	if _, err := model.Generate(ctx, NewPrompt("hi")); err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	totals := tracker.Totals()[cost.Key{Provider: "openai", Model: "gpt-4o-mini", Tag: "tenant-a"}]
+	if totals.Requests != 1 || totals.InputTokens != 1000 || totals.OutputTokens != 500 || totals.Cost != 0.002 {
+		t.Fatalf("totals = %+v", totals)
+	}
+
+	_, err := model.Generate(ctx, NewPrompt("hi"))
+	if !errors.Is(err, ErrBudgetExceeded) || !errors.Is(err, cost.ErrBudgetExceeded) {
+		t.Fatalf("expected a budget error, got %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("request over budget was sent (%d requests)", requests)
+	}
+	if _, err := model.Generate(context.Background(), NewPrompt("hi")); err != nil {
+		t.Fatalf("untagged requests should not be limited by the tenant budget, got %v", err)
+	}
+}
+
+func TestMediaTaskCost(t *testing.T) {
+	var status atomic.Value
+	tracker := cost.NewTracker(0)
+	model := newTestLLM(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/tasks/") {
+			_, _ = w.Write([]byte(status.Load().(string)))
+			return
+		}
+		_, _ = w.Write([]byte(`{"output":{"task_id":"task-1","task_status":"PENDING"},"request_id":"req-1"}`))
+	},
+		config.SetProvider("ali"),
+		config.SetModel("wan-test"),
+		config.SetCostTracker(tracker),
+		config.SetModelCatalog(catalog.New(catalog.Model{Provider: "ali", Name: "wan-test", ImagePrice: 0.1, PerVideoPrice: 0.5})),
+	)
+	impl := model.(*LLMImpl)
+	key := cost.Key{Provider: "ali", Model: "wan-test"}
+	poll := func(body string) {
+		t.Helper()
+		status.Store(body)
+		if _, err := model.TaskStatus(context.Background(), "task-1"); err != nil {
+			t.Fatalf("TaskStatus returned error: %v", err)
+		}
+	}
+	submit := func(mediaType dto.MediaType) {
+		t.Helper()
+		if _, err := model.Media(context.Background(), &dto.MediaRequest{Type: mediaType, Prompt: "a cat"}); err != nil {
+			t.Fatalf("Media returned error: %v", err)
+		}
+	}
+
+	// A video without reported usage is charged the per-video price once.
+	submit(dto.MediaTypeVideo)
+	poll(`{"output":{"task_id":"task-1","task_status":"RUNNING"}}`)
+	poll(`{"output":{"task_id":"task-1","task_status":"SUCCEEDED","video_url":"https://example.com/v.mp4"}}`)
+	poll(`{"output":{"task_id":"task-1","task_status":"SUCCEEDED","video_url":"https://example.com/v.mp4"}}`)
+	if totals := tracker.Totals()[key]; totals.Videos != 1 || totals.Cost != 0.5 {
+		t.Fatalf("unexpected video totals %+v", totals)
+	}
+
+	// An asynchronous image task is charged per image.
+	submit(dto.MediaTypeImage)
+	poll(`{"output":{"task_id":"task-1","task_status":"SUCCEEDED"},"usage":{"image_count":2}}`)
+	if totals := tracker.Totals()[key]; totals.Images != 2 || totals.Cost != 0.7 {
+		t.Fatalf("unexpected image totals %+v", totals)
+	}
+
+	// A task that fails is forgotten without a charge.
+	submit(dto.MediaTypeVideo)
+	poll(`{"output":{"task_id":"task-1","task_status":"FAILED","code":"InternalError"}}`)
+	if _, pending := impl.pendingTasks.Load("task-1"); pending || tracker.Totals()[key].Requests != 2 {
+		t.Fatalf("expected the failed task to be dropped, pending %v, totals %+v", pending, tracker.Totals()[key])
+	}
+}
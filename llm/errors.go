@@ -1,8 +1,13 @@
 package llm
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/YspCoder/omnigo/dto"
+	"github.com/YspCoder/omnigo/retry"
 	"github.com/YspCoder/omnigo/utils"
 )
 
@@ -32,11 +37,35 @@ const (
 	// ErrorTypeAuthentication indicates an authentication or authorization failure
 	ErrorTypeAuthentication
 
-	// ErrorTypeInvalidInput indicates invalid input parameters or prompt
+	// ErrorTypeInvalidInput indicates invalid input parameters or prompt,
+	// including requests rejected by the provider as invalid
 	ErrorTypeInvalidInput
 
 	// ErrorTypeUnsupported indicates a requested feature is not supported
 	ErrorTypeUnsupported
+
+	// ErrorTypeContextLength indicates the prompt exceeds the model's context window
+	ErrorTypeContextLength
+
+	// ErrorTypeContentFiltered indicates the prompt or output was blocked by a safety filter
+	ErrorTypeContentFiltered
+
+	// ErrorTypeOverloaded indicates the provider is temporarily overloaded or unavailable
+	ErrorTypeOverloaded
+
+	// ErrorTypeBudgetExceeded indicates a spend limit has been reached
+	ErrorTypeBudgetExceeded
+)
+
+// Sentinel errors for use with errors.Is. An LLMError matches a sentinel of the same Type.
+var (
+	ErrRateLimit             = &LLMError{Type: ErrorTypeRateLimit, Message: "rate limit exceeded"}
+	ErrAuthentication        = &LLMError{Type: ErrorTypeAuthentication, Message: "authentication failed"}
+	ErrInvalidInput          = &LLMError{Type: ErrorTypeInvalidInput, Message: "invalid request"}
+	ErrContextLengthExceeded = &LLMError{Type: ErrorTypeContextLength, Message: "context length exceeded"}
+	ErrContentFiltered       = &LLMError{Type: ErrorTypeContentFiltered, Message: "content filtered"}
+	ErrOverloaded            = &LLMError{Type: ErrorTypeOverloaded, Message: "provider overloaded"}
+	ErrBudgetExceeded        = &LLMError{Type: ErrorTypeBudgetExceeded, Message: "budget exceeded"}
 )
 
 // LLMError represents a structured error in the LLM package.
@@ -46,16 +75,32 @@ type LLMError struct {
 	Type    ErrorType // The category of the error
 	Message string    // A human-readable error message
 	Err     error     // The underlying error, if any
+
+	// Fields below are filled for errors returned by a provider API.
+	StatusCode int           // HTTP status code of the provider response
+	Code       string        // Provider error type or code, e.g. "rate_limit_error"
+	Provider   string        // Provider that returned the error
+	RetryAfter time.Duration // Delay requested by the provider's Retry-After header
 }
 
 // LoggableFields returns a slice of interface{} containing error information
 // in a format suitable for structured logging.
 func (e *LLMError) LoggableFields() []interface{} {
-	return []interface{}{
+	fields := []interface{}{
 		"error_type", e.TypeString(),
 		"message", e.Message,
 		"error", e.Err,
 	}
+	if e.StatusCode != 0 {
+		fields = append(fields, "status_code", e.StatusCode)
+	}
+	if e.Code != "" {
+		fields = append(fields, "code", e.Code)
+	}
+	if e.RetryAfter > 0 {
+		fields = append(fields, "retry_after", e.RetryAfter)
+	}
+	return fields
 }
 
 // Error implements the error interface.
@@ -74,6 +119,34 @@ func (e *LLMError) Unwrap() error {
 	return e.Err
 }
 
+// Retryable reports whether retrying the failed call may succeed. Rate limits and overload
+// are retryable; authentication, invalid input, context length, content filter, response
+// and budget errors are not. Other API errors depend on the HTTP status or, for
+// transport failures, the underlying error.
+func (e *LLMError) Retryable() bool {
+	switch e.Type {
+	case ErrorTypeRateLimit:
+		// An exhausted quota will not recover by waiting.
+		return e.Code != "insufficient_quota"
+	case ErrorTypeOverloaded:
+		return true
+	case ErrorTypeAuthentication, ErrorTypeInvalidInput, ErrorTypeContextLength, ErrorTypeContentFiltered,
+		ErrorTypeUnsupported, ErrorTypeRequest, ErrorTypeResponse, ErrorTypeBudgetExceeded:
+		return false
+	}
+	if e.StatusCode != 0 {
+		return retry.RetryableStatus(e.StatusCode)
+	}
+	return e.Err != nil && retry.IsRetryable(e.Err)
+}
+
+// Is reports whether target is an LLMError sentinel of the same type,
+// so that errors.Is(err, ErrRateLimit) matches any rate limit error.
+func (e *LLMError) Is(target error) bool {
+	sentinel, ok := target.(*LLMError)
+	return ok && sentinel.Err == nil && sentinel.Type == e.Type
+}
+
 // TypeString returns a string representation of the error type.
 // This is used for logging and error messages.
 func (e *LLMError) TypeString() string {
@@ -94,6 +167,14 @@ func (e *LLMError) TypeString() string {
 		return "InvalidInputError"
 	case ErrorTypeUnsupported:
 		return "UnsupportedError"
+	case ErrorTypeContextLength:
+		return "ContextLengthError"
+	case ErrorTypeContentFiltered:
+		return "ContentFilteredError"
+	case ErrorTypeOverloaded:
+		return "OverloadedError"
+	case ErrorTypeBudgetExceeded:
+		return "BudgetExceededError"
 	default:
 		return "UnknownError"
 	}
@@ -117,6 +198,142 @@ func NewLLMError(errType ErrorType, message string, err error) *LLMError {
 	}
 }
 
+// providerErrorCodes maps lower-cased provider error types and codes to error types.
+var providerErrorCodes = map[string]ErrorType{
+	// OpenAI and OpenAI-compatible APIs
+	"rate_limit_exceeded":      ErrorTypeRateLimit,
+	"insufficient_quota":       ErrorTypeRateLimit,
+	"invalid_api_key":          ErrorTypeAuthentication,
+	"context_length_exceeded":  ErrorTypeContextLength,
+	"string_above_max_length":  ErrorTypeContextLength,
+	"content_filter":           ErrorTypeContentFiltered,
+	"content_policy_violation": ErrorTypeContentFiltered,
+	"server_is_overloaded":     ErrorTypeOverloaded,
+	"invalid_request_error":    ErrorTypeInvalidInput,
+	// Anthropic
+	"rate_limit_error":     ErrorTypeRateLimit,
+	"authentication_error": ErrorTypeAuthentication,
+	"permission_error":     ErrorTypeAuthentication,
+	"overloaded_error":     ErrorTypeOverloaded,
+	"not_found_error":      ErrorTypeInvalidInput,
+	"request_too_large":    ErrorTypeInvalidInput,
+	// Gemini
+	"resource_exhausted":  ErrorTypeRateLimit,
+	"unauthenticated":     ErrorTypeAuthentication,
+	"permission_denied":   ErrorTypeAuthentication,
+	"unavailable":         ErrorTypeOverloaded,
+	"invalid_argument":    ErrorTypeInvalidInput,
+	"failed_precondition": ErrorTypeInvalidInput,
+	"not_found":           ErrorTypeInvalidInput,
+	// DashScope
+	"throttling":                      ErrorTypeRateLimit,
+	"throttling.ratequota":            ErrorTypeRateLimit,
+	"throttling.allocationquota":      ErrorTypeRateLimit,
+	"throttling.user":                 ErrorTypeRateLimit,
+	"invalidapikey":                   ErrorTypeAuthentication,
+	"accessdenied":                    ErrorTypeAuthentication,
+	"accessdenied.unpurchased":        ErrorTypeAuthentication,
+	"datainspectionfailed":            ErrorTypeContentFiltered,
+	"data_inspection_failed":          ErrorTypeContentFiltered,
+	"invalidparameter":                ErrorTypeInvalidInput,
+	"invalidparameter.datainspection": ErrorTypeContentFiltered,
+	"invalidparameter.exceedlimit":    ErrorTypeContextLength,
+	"modelunavailable":                ErrorTypeOverloaded,
+	// Jimeng (Volcengine visual) numeric codes
+	"50411": ErrorTypeContentFiltered, // input image rejected by review
+	"50412": ErrorTypeContentFiltered, // input text rejected by review
+	"50413": ErrorTypeContentFiltered, // input text rejected as sensitive or copyrighted
+	"50511": ErrorTypeContentFiltered, // output image rejected by review
+	"50512": ErrorTypeContentFiltered, // output text rejected by review
+	"50429": ErrorTypeRateLimit,       // QPS limit exceeded
+	"50430": ErrorTypeRateLimit,       // concurrency limit exceeded
+	"50500": ErrorTypeOverloaded,      // internal error
+	"50501": ErrorTypeOverloaded,      // internal RPC error
+	"50400": ErrorTypeAuthentication,  // access denied
+}
+
+// contextLengthMarkers are message fragments providers use for oversized prompts
+// when they do not report a dedicated error code.
+var contextLengthMarkers = []string{
+	"context_length_exceeded",
+	"maximum context length",
+	"context window",
+	"prompt is too long",
+	"input is too long",
+	"exceeds the maximum number of tokens",
+	"range of input length should be",
+	"too many tokens",
+}
+
+// ClassifyError determines the ErrorType of an error returned by the relay.
+// Provider responses (dto.LLMError) are classified from the provider error code,
+// the message and the HTTP status; an existing LLMError keeps its type; any other
+// error is reported as ErrorTypeAPI.
+//
+// Parameters:
+//   - err: The error to classify
+//
+// Returns:
+//   - The error category, or ErrorTypeUnknown for a nil error
+func ClassifyError(err error) ErrorType {
+	if err == nil {
+		return ErrorTypeUnknown
+	}
+	var llmErr *LLMError
+	if errors.As(err, &llmErr) {
+		return llmErr.Type
+	}
+	var providerErr *dto.LLMError
+	if !errors.As(err, &providerErr) {
+		return ErrorTypeAPI
+	}
+
+	code := strings.ToLower(strings.TrimSpace(providerErr.Type))
+	if errType, ok := providerErrorCodes[code]; ok && errType != ErrorTypeInvalidInput {
+		return errType
+	}
+	if NormalizeFinishReason(code) == FinishReasonContentFilter {
+		// Gemini reports block reasons such as SAFETY or PROHIBITED_CONTENT.
+		return ErrorTypeContentFiltered
+	}
+	message := strings.ToLower(providerErr.Message)
+	for _, marker := range contextLengthMarkers {
+		if strings.Contains(message, marker) {
+			return ErrorTypeContextLength
+		}
+	}
+	if errType, ok := providerErrorCodes[code]; ok {
+		return errType
+	}
+
+	switch status := providerErr.Code; {
+	case status == 429:
+		return ErrorTypeRateLimit
+	case status == 401 || status == 403:
+		return ErrorTypeAuthentication
+	case status == 503 || status == 529:
+		return ErrorTypeOverloaded
+	case status == 400 || status == 404 || status == 413 || status == 422:
+		return ErrorTypeInvalidInput
+	default:
+		return ErrorTypeAPI
+	}
+}
+
+// newAPIError wraps an error from the relay in a classified LLMError,
+// copying the provider status, code and Retry-After hint when present.
+func newAPIError(message string, err error) *LLMError {
+	llmErr := NewLLMError(ClassifyError(err), message, err)
+	var providerErr *dto.LLMError
+	if errors.As(err, &providerErr) {
+		llmErr.StatusCode = providerErr.Code
+		llmErr.Code = providerErr.Type
+		llmErr.Provider = providerErr.Provider
+		llmErr.RetryAfter = providerErr.RetryAfter
+	}
+	return llmErr
+}
+
 // HandleError processes an error based on its severity.
 // It logs the error appropriately and can optionally terminate the program
 // if the error is considered fatal.
@@ -0,0 +1,162 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/YspCoder/omnigo/adapter"
+	"github.com/YspCoder/omnigo/config"
+	"github.com/YspCoder/omnigo/dto"
+)
+
+func TestClassifyProviderErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   ErrorType
+	}{
+		{"openai context", 400, `{"error":{"message":"This model's maximum context length is 8192 tokens","type":"invalid_request_error","code":"context_length_exceeded"}}`, ErrorTypeContextLength},
+		{"openai invalid", 400, `{"error":{"message":"bad param","type":"invalid_request_error","code":null}}`, ErrorTypeInvalidInput},
+		{"openai auth", 401, `{"error":{"message":"Incorrect API key","type":"invalid_request_error","code":"invalid_api_key"}}`, ErrorTypeAuthentication},
+		{"anthropic overloaded", 529, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, ErrorTypeOverloaded},
+		{"anthropic prompt too long", 400, `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`, ErrorTypeContextLength},
+		{"gemini quota", 429, `{"error":{"code":429,"message":"Quota exceeded","status":"RESOURCE_EXHAUSTED"}}`, ErrorTypeRateLimit},
+		{"dashscope throttling", 400, `{"code":"Throttling.RateQuota","message":"Requests rate limit exceeded","request_id":"1"}`, ErrorTypeRateLimit},
+		{"dashscope inspection", 400, `{"code":"DataInspectionFailed","message":"Input data may contain inappropriate content."}`, ErrorTypeContentFiltered},
+		{"jimeng concurrency", 200, `{"code":50430,"message":"Request Has Reached API Concurrent Limit"}`, ErrorTypeRateLimit},
+		{"unknown 500", 500, `internal error`, ErrorTypeAPI},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, message := adapter.ParseErrorBody([]byte(tt.body))
+			err := &dto.LLMError{Code: tt.status, Message: message, Type: code}
+			if got := ClassifyError(err); got != tt.want {
+				t.Fatalf("ClassifyError = %v (code %q), want %v", got, code, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateReturnsTypedRateLimitError(t *testing.T) {
+	model := newTestLLM(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`))
+	})
+
+	_, err := model.Generate(context.Background(), NewPrompt("hello"))
+	if !errors.Is(err, ErrRateLimit) {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+	var llmErr *LLMError
+	if !errors.As(err, &llmErr) {
+		t.Fatalf("expected LLMError, got %T", err)
+	}
+	if llmErr.StatusCode != http.StatusTooManyRequests || llmErr.Code != "rate_limit_exceeded" || llmErr.RetryAfter != 7*time.Second {
+		t.Fatalf("unexpected error metadata: %+v", llmErr)
+	}
+}
+
+func TestGenerateRetriesOnlyRetryableErrors(t *testing.T) {
+	var calls int32
+	status := http.StatusBadRequest
+	model := newTestLLM(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"error":{"message":"failure","type":"server_error"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	},
+		config.SetMaxRetries(2),
+		config.SetRetryDelay(time.Millisecond),
+	)
+
+	if _, err := model.GenerateResult(context.Background(), NewPrompt("hello")); !errors.Is(err, ErrInvalidInput) || calls != 1 {
+		t.Fatalf("expected a single non-retried invalid request, got %v after %d calls", err, calls)
+	}
+	// A single attempt is reported as is by both entry points.
+	calls = 0
+	_, schemaErr := model.GenerateWithSchema(context.Background(), NewPrompt("hello"), map[string]interface{}{"type": "object"})
+	var llmErr *LLMError
+	if !errors.As(schemaErr, &llmErr) || schemaErr != error(llmErr) || calls != 1 {
+		t.Fatalf("expected an unwrapped invalid request error, got %v after %d calls", schemaErr, calls)
+	}
+
+	calls, status = 0, http.StatusServiceUnavailable
+	result, err := model.GenerateResult(context.Background(), NewPrompt("hello"))
+	if err != nil {
+		t.Fatalf("expected the retry to succeed, got %v", err)
+	}
+	if result.Attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", result.Attempts)
+	}
+}
+
+func TestMediaRetriesOnlyRejectedSubmissions(t *testing.T) {
+	var calls int32
+	var mode atomic.Value
+	model := newTestLLM(t, func(w http.ResponseWriter, r *http.Request) {
+		call := atomic.AddInt32(&calls, 1)
+		switch mode.Load().(string) {
+		case "timeout":
+			time.Sleep(100 * time.Millisecond)
+		case "rejected":
+			if call == 1 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = w.Write([]byte(`{"code":"Throttling","message":"too many requests"}`))
+				return
+			}
+		}
+		_, _ = w.Write([]byte(`{"output":{"task_id":"task-1","task_status":"PENDING"},"request_id":"req-1"}`))
+	},
+		config.SetProvider("ali"),
+		config.SetModel("wanx2.1-t2v-turbo"),
+		config.SetMaxRetries(2),
+		config.SetRetryDelay(time.Millisecond),
+		config.SetTimeout(20*time.Millisecond),
+	)
+
+	// The provider may still run a submission that timed out, so it is not sent again.
+	mode.Store("timeout")
+	if _, err := model.Media(context.Background(), &dto.MediaRequest{Type: dto.MediaTypeVideo, Prompt: "a cat"}); err == nil || atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("expected a single timed-out submission, got %v after %d calls", err, atomic.LoadInt32(&calls))
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	mode.Store("rejected")
+	response, err := model.Media(context.Background(), &dto.MediaRequest{Type: dto.MediaTypeVideo, Prompt: "a cat"})
+	if err != nil || response.TaskID != "task-1" || atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("expected the rejected submission to be retried, got %+v, %v after %d calls", response, err, atomic.LoadInt32(&calls))
+	}
+}
+
+func TestGenerateWithSchemaRetriesMismatchedAnswers(t *testing.T) {
+	var calls int32
+	model := newTestLLM(t, func(w http.ResponseWriter, r *http.Request) {
+		content := `{\"answer\":42}`
+		if atomic.AddInt32(&calls, 1) == 1 {
+			content = "not json"
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"` + content + `"},"finish_reason":"stop"}]}`))
+	},
+		config.SetMaxRetries(2),
+		config.SetRetryDelay(time.Millisecond),
+	)
+	// Response errors are not retryable in general, but another answer may match the schema.
+	if (&LLMError{Type: ErrorTypeResponse}).Retryable() {
+		t.Fatal("expected response errors not to be retryable")
+	}
+	result, err := model.GenerateWithSchema(context.Background(), NewPrompt("answer"), map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"answer": map[string]interface{}{"type": "number"}},
+	})
+	if err != nil || result != `{"answer":42}` || atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("expected the mismatched answer to be retried, got %q, %v after %d calls", result, err, atomic.LoadInt32(&calls))
+	}
+}
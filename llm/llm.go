@@ -5,7 +5,11 @@ package llm
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -14,9 +18,16 @@ import (
 	"time"
 
 	"github.com/YspCoder/omnigo/adapter"
+	"github.com/YspCoder/omnigo/cache"
+	"github.com/YspCoder/omnigo/catalog"
 	"github.com/YspCoder/omnigo/config"
+	"github.com/YspCoder/omnigo/cost"
 	"github.com/YspCoder/omnigo/dto"
+	"github.com/YspCoder/omnigo/ratelimit"
 	"github.com/YspCoder/omnigo/relay"
+	"github.com/YspCoder/omnigo/retry"
+	"github.com/YspCoder/omnigo/telemetry"
+	"github.com/YspCoder/omnigo/tokenizer"
 	"github.com/YspCoder/omnigo/utils"
 )
 
@@ -26,9 +37,15 @@ import (
 type LLM interface {
 	// Generate produces text based on the given prompt and options.
 	// Returns ErrorTypeRequest for request preparation failures,
-	// ErrorTypeAPI for provider API errors, or ErrorTypeResponse for response processing issues.
+	// ErrorTypeAPI or a classified provider error type (rate limit, authentication,
+	// context length, content filter, overloaded, invalid input) for provider API errors,
+	// or ErrorTypeResponse for response processing issues.
 	Generate(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (response string, err error)
 
+	// GenerateResult produces a structured result including any tool calls requested by the model.
+	// Error types are the same as for Generate.
+	GenerateResult(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (*GenerateResult, error)
+
 	// GenerateWithSchema generates text that conforms to a specific JSON schema.
 	// Returns ErrorTypeInvalidInput for schema validation failures,
 	// or other error types as per Generate.
@@ -62,6 +79,9 @@ type LLM interface {
 
 	// SupportsJSONSchema checks if the provider supports JSON schema validation.
 	SupportsJSONSchema() bool
+
+	// CountTokens returns the number of input tokens the prompt occupies for the model.
+	CountTokens(prompt *Prompt) int
 }
 
 // LLMImpl implements the LLM interface and manages interactions with specific providers.
@@ -70,17 +90,25 @@ type LLMImpl struct {
 	providerName      string                 // Provider identifier
 	supportsSchema    bool                   // Supports JSON schema validation
 	supportsStreaming bool                   // Supports streaming responses
+	providerSpec      adapter.ProviderSpec   // Registry spec the provider was built from
 	chatProtocol      string                 // Chat protocol format (e.g., openai)
 	Options           map[string]interface{} // Provider-specific options
 	optionsMutex      sync.RWMutex           // Mutex to protect concurrent access to Options map
 	client            *http.Client           // HTTP client for API requests
 	logger            utils.Logger           // Logger for debugging and monitoring
 	config            *config.Config         // Configuration settings
-	MaxRetries        int                    // Maximum number of retry attempts
-	RetryDelay        time.Duration          // Delay between retry attempts
+	MaxRetries        int                    // Maximum number of retries after the first attempt
+	RetryDelay        time.Duration          // Initial delay of the retry backoff
 	relay             *relay.Relay
 	adaptor           adapter.Adaptor
 	adaptorCfg        *adapter.ProviderConfig
+	cache             cache.Cache          // Response cache; nil when caching is disabled
+	telemetry         *telemetry.Telemetry // OpenTelemetry instrumentation; nil when disabled
+	tokenizer         tokenizer.Tokenizer  // Counts prompt tokens for context window checks
+	modelInfo         *catalog.Model       // Catalog entry of the model; nil when unknown
+	models            *catalog.Catalog     // Catalog used to price media models
+	costs             *cost.Tracker        // Spend of this LLM, or the configured shared tracker
+	pendingTasks      sync.Map             // Task ID to pendingTask of media tasks not yet recorded
 }
 
 // GenerateOption is a function type for configuring generation behavior.
@@ -110,6 +138,15 @@ func NewLLM(cfg *config.Config, logger utils.Logger, registry *adapter.Registry)
 		return nil, err
 	}
 
+	models, err := loadCatalog(cfg)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeProvider, "failed to load model catalog", err)
+	}
+	var modelInfo *catalog.Model
+	if model, ok := models.Lookup(spec.Name, cfg.Model); ok {
+		modelInfo = &model
+	}
+
 	headers := make(map[string]string)
 	for key, value := range spec.RequiredHeaders {
 		headers[key] = value
@@ -133,17 +170,35 @@ func NewLLM(cfg *config.Config, logger utils.Logger, registry *adapter.Registry)
 		}
 	}
 
+	// OpenAI-compatible providers (including DashScope's compatible mode) share the
+	// OpenAI payloads; custom providers convert chat requests with their own adaptor.
+	// The config can override this, e.g. to use DashScope's native protocol.
+	chatProtocol := adapter.ChatProtocolOpenAI
+	if spec.Type == adapter.TypeCustom {
+		chatProtocol = adapter.ChatProtocolNative
+	}
+	if cfg.ChatProtocol != "" {
+		chatProtocol = cfg.ChatProtocol
+	}
+
 	llmClient := &LLMImpl{
 		providerName:      spec.Name,
 		supportsSchema:    spec.SupportsSchema,
 		supportsStreaming: spec.SupportsStreaming,
-		chatProtocol:      "openai",
+		providerSpec:      spec,
+		chatProtocol:      chatProtocol,
 		client:            &http.Client{Timeout: cfg.Timeout},
-		logger:            logger,
+		logger:            utils.WithFields(logger, "provider", spec.Name, "model", cfg.Model),
 		config:            cfg,
 		MaxRetries:        cfg.MaxRetries,
 		RetryDelay:        cfg.RetryDelay,
 		Options:           make(map[string]interface{}),
+		modelInfo:         modelInfo,
+		models:            models,
+		costs:             cfg.CostTracker,
+	}
+	if llmClient.costs == nil {
+		llmClient.costs = cost.NewTracker(cfg.Budget)
 	}
 
 	llmClient.adaptor = adp
@@ -158,12 +213,88 @@ func NewLLM(cfg *config.Config, logger utils.Logger, registry *adapter.Registry)
 		HTTPClient:   llmClient.client,
 		Timeout:      cfg.Timeout,
 		ChatProtocol: llmClient.chatProtocol,
+		Region:       cfg.Region,
+	}
+	llmClient.adaptorCfg.Limiter, err = newRateLimiter(cfg, spec.Name, baseURL, apiKey)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "invalid rate limit configuration", err)
 	}
 	llmClient.relay = relay.NewRelay()
+	llmClient.relay.Use(cfg.Middlewares...)
+
+	if cfg.TracerProvider != nil || cfg.MeterProvider != nil {
+		tel, err := telemetry.New(cfg.TracerProvider, cfg.MeterProvider)
+		if err != nil {
+			return nil, NewLLMError(ErrorTypeProvider, "failed to initialize telemetry", err)
+		}
+		llmClient.telemetry = tel
+		llmClient.relay.Use(tel.Middleware())
+	}
+
+	llmClient.tokenizer, err = newTokenizer(cfg)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeProvider, "failed to initialize tokenizer", err)
+	}
+
+	if cfg.EnableCaching {
+		responseCache, err := newResponseCache(cfg)
+		if err != nil {
+			return nil, NewLLMError(ErrorTypeProvider, "failed to initialize response cache", err)
+		}
+		llmClient.cache = responseCache
+	}
 
 	return llmClient, nil
 }
 
+// newRateLimiter returns the configured rate limiter, or the process-wide limiter
+// shared by all instances using the same provider, endpoint, API key and model
+// when limits are set. The API key is keyed by its hash so it is never kept in clear.
+func newRateLimiter(cfg *config.Config, provider, baseURL, apiKey string) (*ratelimit.Limiter, error) {
+	if cfg.RateLimiter != nil {
+		return cfg.RateLimiter, nil
+	}
+	limits := ratelimit.Limits{
+		RequestsPerMinute: cfg.RateLimitRPM,
+		TokensPerMinute:   cfg.RateLimitTPM,
+		MaxInFlight:       cfg.MaxInFlight,
+	}
+	if !limits.Enabled() {
+		return nil, nil
+	}
+	credential := sha256.Sum256([]byte(apiKey))
+	key := fmt.Sprintf("%s/%s@%s#%x", provider, cfg.Model, baseURL, credential[:8])
+	return ratelimit.Shared(key, limits)
+}
+
+// loadCatalog returns the configured model catalog, or the default one. Entries
+// from ModelCatalogFile take precedence.
+func loadCatalog(cfg *config.Config) (*catalog.Catalog, error) {
+	models := cfg.ModelCatalog
+	if models == nil {
+		models = catalog.Default()
+	}
+	if cfg.ModelCatalogFile != "" {
+		models = models.Clone()
+		if err := models.LoadFile(cfg.ModelCatalogFile); err != nil {
+			return nil, err
+		}
+	}
+	return models, nil
+}
+
+// newResponseCache returns the configured cache: a custom one, a disk cache when a
+// directory is set, or an in-memory LRU cache.
+func newResponseCache(cfg *config.Config) (cache.Cache, error) {
+	if cfg.Cache != nil {
+		return cfg.Cache, nil
+	}
+	if cfg.CacheDir != "" {
+		return cache.NewDiskCache(cfg.CacheDir, cfg.CacheTTL)
+	}
+	return cache.NewMemoryCache(cache.DefaultCapacity, cfg.CacheTTL), nil
+}
+
 func isReservedHeaderKey(key string) bool {
 	switch strings.ToLower(key) {
 	case "endpoint", "azure_endpoint":
@@ -200,7 +331,11 @@ func (l *LLMImpl) NewPrompt(prompt string) *Prompt {
 }
 
 // SupportsJSONSchema checks if the current provider supports JSON schema validation.
+// A model whose catalog entry lacks JSON schema support reports false.
 func (l *LLMImpl) SupportsJSONSchema() bool {
+	if l.modelInfo != nil && !l.modelInfo.JSONSchema {
+		return false
+	}
 	return l.supportsSchema
 }
 
@@ -212,55 +347,148 @@ func (l *LLMImpl) SupportsJSONSchema() bool {
 //   - ErrorTypeRequest for request preparation failures
 //   - ErrorTypeAPI for provider API errors
 //   - ErrorTypeResponse for response processing issues
-//   - ErrorTypeRateLimit if provider rate limit is exceeded
+//   - ErrorTypeRateLimit, ErrorTypeAuthentication, ErrorTypeContextLength,
+//     ErrorTypeContentFiltered, ErrorTypeOverloaded or ErrorTypeInvalidInput
+//     when the provider error can be classified (see ClassifyError)
 func (l *LLMImpl) Generate(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (string, error) {
+	result, err := l.GenerateResult(ctx, prompt, opts...)
+	if err != nil {
+		return "", err
+	}
+	return result.Content, nil
+}
+
+// GenerateResult produces a structured result based on the given prompt and options.
+// Tool calls returned by the provider are preserved in the result; send their outputs
+// back with WithToolResult to continue a multi-turn tool loop.
+//
+// Returns:
+//   - Generation result with text content and tool calls
+//   - Error types as per Generate
+func (l *LLMImpl) GenerateResult(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (*GenerateResult, error) {
 	config := &GenerateConfig{}
 	for _, opt := range opts {
 		opt(config)
 	}
-	// Set the system prompt in the LLM's options
-	if prompt.SystemPrompt != "" {
-		l.SetOption("system_prompt", prompt.SystemPrompt)
-	}
-	for attempt := 0; attempt <= l.MaxRetries; attempt++ {
-		l.logger.Debug("Generating text", "provider", l.providerName, "prompt", prompt.String(), "system_prompt", prompt.SystemPrompt, "attempt", attempt+1)
-		// Pass the entire Prompt struct to attemptGenerate
-		result, err := l.attemptGenerate(ctx, prompt)
-		if err == nil {
-			return result, nil
-		}
-		l.logger.Warn("Generation attempt failed", "error", err, "attempt", attempt+1)
-		if attempt < l.MaxRetries {
-			l.logger.Debug("Retrying", "delay", l.RetryDelay)
-			if err := l.wait(ctx); err != nil {
-				return "", err
-			}
+	if err := l.checkBudget(ctx); err != nil {
+		return nil, err
+	}
+	ctx = withLogFields(ctx, "request_id", newRequestID())
+	ctx, span := l.telemetry.Start(ctx, telemetry.OperationChat, l.providerName, l.config.Model)
+	prompt, err := l.preparePrompt(ctx, prompt)
+	if err != nil {
+		span.End(telemetry.Result{ErrorType: telemetryErrorType(err)})
+		return nil, err
+	}
+	var result *GenerateResult
+	attempts, err := retry.Do(ctx, l.retryPolicy(), func(attempt int) error {
+		attemptCtx := withLogFields(ctx, "attempt", attempt)
+		l.log(attemptCtx).Debug("Generating text", "prompt", prompt.String(), "system_prompt", prompt.SystemPrompt)
+		var err error
+		result, err = l.attemptGenerate(attemptCtx, prompt)
+		return err
+	}, l.logRetry(ctx, span, "Generation attempt failed"))
+	if err != nil {
+		span.End(telemetry.Result{Attempts: attempts, ErrorType: telemetryErrorType(err)})
+		if attempts > 1 {
+			return nil, fmt.Errorf("failed to generate after %d attempts: %w", attempts, err)
 		}
+		return nil, err
 	}
-	return "", fmt.Errorf("failed to generate after %d attempts", l.MaxRetries+1)
+	result.Attempts = attempts
+	span.End(telemetry.Result{
+		ResponseModel: result.Model,
+		FinishReasons: []string{string(result.FinishReason)},
+		InputTokens:   result.Usage.PromptTokens,
+		OutputTokens:  result.Usage.CompletionTokens,
+		Attempts:      attempts,
+	})
+	return result, nil
 }
 
-// wait implements a cancellable delay between retry attempts.
-// Returns context.Canceled if the context is cancelled during the wait.
-func (l *LLMImpl) wait(ctx context.Context) error {
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-time.After(l.RetryDelay):
-		return nil
+// retryPolicy returns the configured retry policy, or exponential backoff with
+// jitter derived from MaxRetries, RetryDelay, RetryMaxDelay and RetryBudget.
+func (l *LLMImpl) retryPolicy() retry.Policy {
+	if l.config.RetryPolicy != nil {
+		return l.config.RetryPolicy
 	}
+	backoff := retry.NewBackoff(l.MaxRetries, l.RetryDelay, l.config.RetryMaxDelay)
+	backoff.MaxElapsed = l.config.RetryBudget
+	return backoff
+}
+
+// submissionPolicy restricts a retry policy to failures after which the provider has
+// certainly not started a task. Media submissions are not idempotent: after a timeout,
+// a network failure or an unreadable response the task may already be running and
+// billed, so only errors answered by the provider with a rejection are retried.
+type submissionPolicy struct {
+	retry.Policy
+}
+
+// Next implements retry.Policy.
+func (p submissionPolicy) Next(attempts int, elapsed time.Duration, err error) (time.Duration, bool) {
+	var llmErr *LLMError
+	if !errors.As(err, &llmErr) || llmErr.StatusCode == 0 || llmErr.Type == ErrorTypeResponse ||
+		llmErr.StatusCode == http.StatusRequestTimeout || llmErr.StatusCode == http.StatusGatewayTimeout {
+		return 0, false
+	}
+	return p.Policy.Next(attempts, elapsed, err)
+}
+
+// logRetry returns a retry hook that logs the failed attempt and the upcoming delay
+// and records it on the operation span.
+func (l *LLMImpl) logRetry(ctx context.Context, span *telemetry.Span, message string) func(int, error, time.Duration) {
+	return func(attempt int, err error, delay time.Duration) {
+		logger := l.log(withLogFields(ctx, "attempt", attempt))
+		logger.Warn(message, "error", err)
+		logger.Debug("Retrying", "delay", delay)
+		span.RecordRetry(attempt, telemetryErrorType(err), delay)
+	}
+}
+
+// logFieldsKey is the context key for per-call log fields such as the request ID
+// and attempt number.
+type logFieldsKey struct{}
+
+// withLogFields returns a context carrying additional log fields.
+func withLogFields(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	fields, _ := ctx.Value(logFieldsKey{}).([]interface{})
+	return context.WithValue(ctx, logFieldsKey{}, append(append([]interface{}(nil), fields...), keysAndValues...))
+}
+
+// log returns the logger tagged with the log fields carried by ctx.
+func (l *LLMImpl) log(ctx context.Context) utils.Logger {
+	fields, _ := ctx.Value(logFieldsKey{}).([]interface{})
+	return utils.WithFields(l.logger, fields...)
+}
+
+// newRequestID returns a random identifier correlating the log records of one call.
+func newRequestID() string {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// telemetryErrorType returns the error.type reported for err, or "" for nil.
+func telemetryErrorType(err error) string {
+	if err == nil {
+		return ""
+	}
+	return (&LLMError{Type: ClassifyError(err)}).TypeString()
 }
 
 // attemptGenerate makes a single attempt to generate text using the provider.
 // It handles request preparation, API communication, and response processing.
 //
 // Returns:
-//   - Generated text response
+//   - Generation result with text content and tool calls
 //   - ErrorTypeRequest for request preparation failures
 //   - ErrorTypeAPI for provider API errors
 //   - ErrorTypeResponse for response processing issues
-//   - ErrorTypeRateLimit if provider rate limit is exceeded
-func (l *LLMImpl) attemptGenerate(ctx context.Context, prompt *Prompt) (string, error) {
+//   - ErrorTypeRateLimit, ErrorTypeAuthentication, ErrorTypeContextLength,
+//     ErrorTypeContentFiltered, ErrorTypeOverloaded or ErrorTypeInvalidInput
+//     when the provider error can be classified (see ClassifyError)
+func (l *LLMImpl) attemptGenerate(ctx context.Context, prompt *Prompt) (*GenerateResult, error) {
 	// Create a new options map that includes both l.Options and prompt-specific options
 	options := make(map[string]interface{})
 
@@ -271,6 +499,12 @@ func (l *LLMImpl) attemptGenerate(ctx context.Context, prompt *Prompt) (string,
 	}
 	l.optionsMutex.RUnlock()
 
+	// The system prompt belongs to this request only; it must not leak into the
+	// shared Options map used by later calls.
+	if prompt.SystemPrompt != "" {
+		options["system_prompt"] = prompt.SystemPrompt
+	}
+
 	// Add Tools and ToolChoice to options
 	if len(prompt.Tools) > 0 {
 		options["tools"] = prompt.Tools
@@ -278,8 +512,11 @@ func (l *LLMImpl) attemptGenerate(ctx context.Context, prompt *Prompt) (string,
 	if len(prompt.ToolChoice) > 0 {
 		options["tool_choice"] = prompt.ToolChoice
 	}
+	if prompt.SystemCacheType != "" {
+		options["system_cache_type"] = string(prompt.SystemCacheType)
+	}
 
-	options = applyDefaultOptions(options, l.config)
+	options = l.clampMaxTokens(applyDefaultOptions(options, l.config))
 
 	messages := toDTOMessages(prompt.Messages)
 	if l.useOpenAIProtocol() {
@@ -292,17 +529,34 @@ func (l *LLMImpl) attemptGenerate(ctx context.Context, prompt *Prompt) (string,
 		Prompt:   prompt.String(),
 		Options:  options,
 	}
-	response, err := l.relay.Chat(ctx, l.adaptor, l.adaptorCfg, request)
-	if err != nil {
-		return "", NewLLMError(ErrorTypeAPI, "relay chat request failed", err)
+	start := time.Now()
+	cacheKey, response, cached := l.cachedChat(ctx, request)
+	if !cached {
+		var err error
+		response, err = l.relay.Chat(ctx, l.adaptor, l.adaptorCfg, request)
+		if err != nil {
+			return nil, newAPIError("relay chat request failed", err)
+		}
 	}
+	latency := time.Since(start)
 
-	result, err := firstChoiceContent(response)
+	result, err := newGenerateResult(response)
 	if err != nil {
-		return "", NewLLMError(ErrorTypeResponse, "failed to parse response", err)
+		return nil, NewLLMError(ErrorTypeResponse, "failed to parse response", err)
+	}
+	if !cached {
+		l.recordCost(ctx, l.config.Model, tokenUsage(result.Usage))
+		l.storeCachedChat(ctx, cacheKey, response)
+	}
+	result.Provider = l.providerName
+	result.Latency = latency
+	result.Cached = cached
+	if result.Model == "" {
+		result.Model = l.config.Model
 	}
 
-	l.logger.Debug("Text generated successfully", "result", result)
+	l.log(ctx).Debug("Text generated successfully", "result", result.Content, "tool_calls", len(result.ToolCalls),
+		"finish_reason", result.FinishReason, "total_tokens", result.Usage.TotalTokens, "latency", latency)
 	return result, nil
 }
 
@@ -318,34 +572,55 @@ func (l *LLMImpl) GenerateWithSchema(ctx context.Context, prompt *Prompt, schema
 	for _, opt := range opts {
 		opt(config)
 	}
+	if err := l.checkBudget(ctx); err != nil {
+		return "", err
+	}
 
+	ctx = withLogFields(ctx, "request_id", newRequestID())
+	ctx, span := l.telemetry.Start(ctx, telemetry.OperationChat, l.providerName, l.config.Model)
+	prompt, err := l.fitContext(ctx, prompt)
+	if err != nil {
+		span.End(telemetry.Result{ErrorType: telemetryErrorType(err)})
+		return "", err
+	}
 	var result string
-	var lastErr error
-
-	for attempt := 0; attempt <= l.MaxRetries; attempt++ {
-		l.logger.Debug("Generating text with schema", "provider", l.providerName, "prompt", prompt.String(), "attempt", attempt+1)
-
-		result, _, lastErr = l.attemptGenerateWithSchema(ctx, prompt.String(), schema)
-		if lastErr == nil {
-			return result, nil
+	attempts, err := retry.Do(ctx, l.retryPolicy(), func(attempt int) error {
+		attemptCtx := withLogFields(ctx, "attempt", attempt)
+		l.log(attemptCtx).Debug("Generating text with schema", "prompt", prompt.String())
+		var err error
+		result, _, err = l.attemptGenerateWithSchema(attemptCtx, prompt.String(), schema)
+		var llmErr *LLMError
+		if errors.As(err, &llmErr) && llmErr.Type == ErrorTypeResponse {
+			// Another sample from the model may match the schema.
+			return retryableResponse{err}
 		}
-
-		l.logger.Warn("Generation attempt with schema failed", "error", lastErr, "attempt", attempt+1)
-
-		if attempt < l.MaxRetries {
-			l.logger.Debug("Retrying", "delay", l.RetryDelay)
-			select {
-			case <-ctx.Done():
-				return "", ctx.Err()
-			case <-time.After(l.RetryDelay):
-				// Continue to next attempt
-			}
+		return err
+	}, l.logRetry(ctx, span, "Generation attempt with schema failed"))
+	var marked retryableResponse
+	if errors.As(err, &marked) {
+		err = marked.error
+	}
+	span.End(telemetry.Result{Attempts: attempts, ErrorType: telemetryErrorType(err)})
+	if err != nil {
+		if attempts > 1 {
+			return "", fmt.Errorf("failed to generate with schema after %d attempts: %w", attempts, err)
 		}
+		return "", err
 	}
+	return result, nil
+}
 
-	return "", fmt.Errorf("failed to generate with schema after %d attempts: %w", l.MaxRetries+1, lastErr)
+// retryableResponse marks a response error as worth retrying. Response errors are
+// not retried by default, but GenerateWithSchema retries answers that fail to parse
+// or to match the schema.
+type retryableResponse struct {
+	error
 }
 
+func (e retryableResponse) Retryable() bool { return true }
+
+func (e retryableResponse) Unwrap() error { return e.error }
+
 // attemptGenerateWithSchema makes a single attempt to generate text using the provider and a JSON schema.
 // It handles request preparation, API communication, and response processing.
 //
@@ -370,7 +645,7 @@ func (l *LLMImpl) attemptGenerateWithSchema(ctx context.Context, prompt string,
 		fullPrompt = l.preparePromptWithSchema(prompt, schema)
 	}
 
-	options = applyDefaultOptions(options, l.config)
+	options = l.clampMaxTokens(applyDefaultOptions(options, l.config))
 	if l.useOpenAIProtocol() {
 		options = filterOptions(options, "structured_messages")
 	}
@@ -385,25 +660,83 @@ func (l *LLMImpl) attemptGenerateWithSchema(ctx context.Context, prompt string,
 		request.Schema = schema
 	}
 
-	response, err := l.relay.Chat(ctx, l.adaptor, l.adaptorCfg, request)
-	if err != nil {
-		return "", fullPrompt, NewLLMError(ErrorTypeAPI, "relay chat request failed", err)
+	cacheKey, response, cached := l.cachedChat(ctx, request)
+	if !cached {
+		var err error
+		response, err = l.relay.Chat(ctx, l.adaptor, l.adaptorCfg, request)
+		if err != nil {
+			return "", fullPrompt, newAPIError("relay chat request failed", err)
+		}
 	}
 
-	result, err := firstChoiceContent(response)
+	generated, err := newGenerateResult(response)
 	if err != nil {
 		return "", fullPrompt, NewLLMError(ErrorTypeResponse, "failed to parse response", err)
 	}
+	result := generated.Content
+	if !cached {
+		// The tokens are paid for even if the response fails validation below.
+		l.recordCost(ctx, l.config.Model, tokenUsage(generated.Usage))
+	}
 
 	// Validate the result against the schema
 	if err := ValidateAgainstSchema(result, schema); err != nil {
 		return "", fullPrompt, NewLLMError(ErrorTypeResponse, "response does not match schema", err)
 	}
+	// Only responses that pass validation are cached, so retries are not served a bad answer.
+	if !cached {
+		l.storeCachedChat(ctx, cacheKey, response)
+	}
 
-	l.logger.Debug("Text generated successfully", "result", result)
+	l.log(ctx).Debug("Text generated successfully", "result", result)
 	return result, fullPrompt, nil
 }
 
+// cachedChat looks the request up in the response cache. It returns the cache key
+// (empty when caching is disabled or the request cannot be hashed) and the cached
+// response on a hit. Cache failures are logged and treated as misses.
+func (l *LLMImpl) cachedChat(ctx context.Context, request *dto.ChatRequest) (string, *dto.ChatResponse, bool) {
+	if l.cache == nil {
+		return "", nil, false
+	}
+	key, err := cache.Key(l.providerName, request)
+	if err != nil {
+		l.log(ctx).Warn("Skipping response cache", "error", err)
+		return "", nil, false
+	}
+	data, ok, err := l.cache.Get(ctx, key)
+	if err != nil {
+		l.log(ctx).Warn("Response cache lookup failed", "error", err)
+		return key, nil, false
+	}
+	if !ok {
+		l.log(ctx).Debug("Response cache miss", "key", key)
+		return key, nil, false
+	}
+	var response dto.ChatResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		l.log(ctx).Warn("Discarding unreadable cache entry", "key", key, "error", err)
+		return key, nil, false
+	}
+	l.log(ctx).Info("Response cache hit", "key", key)
+	return key, &response, true
+}
+
+// storeCachedChat saves a successful response under key.
+func (l *LLMImpl) storeCachedChat(ctx context.Context, key string, response *dto.ChatResponse) {
+	if l.cache == nil || key == "" {
+		return
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		l.log(ctx).Warn("Failed to serialize response for cache", "error", err)
+		return
+	}
+	if err := l.cache.Set(ctx, key, data); err != nil {
+		l.log(ctx).Warn("Failed to store response in cache", "key", key, "error", err)
+	}
+}
+
 // preparePromptWithSchema prepares a prompt with a JSON schema for providers that do not support JSON schema validation.
 // Returns the original prompt if schema marshaling fails (with a warning log).
 func (l *LLMImpl) preparePromptWithSchema(prompt string, schema interface{}) string {
@@ -421,6 +754,9 @@ func (l *LLMImpl) Stream(ctx context.Context, prompt *Prompt, opts ...StreamOpti
 	if !l.SupportsStreaming() {
 		return nil, NewLLMError(ErrorTypeUnsupported, "streaming not supported by provider", nil)
 	}
+	if err := l.checkBudget(ctx); err != nil {
+		return nil, err
+	}
 
 	// Apply stream options
 	config := &StreamConfig{
@@ -444,6 +780,11 @@ func (l *LLMImpl) Stream(ctx context.Context, prompt *Prompt, opts ...StreamOpti
 		return nil, NewLLMError(ErrorTypeUnsupported, "streaming not supported by adaptor", nil)
 	}
 
+	prompt, err := l.preparePrompt(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
 	options := make(map[string]interface{})
 	l.optionsMutex.RLock()
 	for k, v := range l.Options {
@@ -451,13 +792,19 @@ func (l *LLMImpl) Stream(ctx context.Context, prompt *Prompt, opts ...StreamOpti
 	}
 	l.optionsMutex.RUnlock()
 
+	if prompt.SystemPrompt != "" {
+		options["system_prompt"] = prompt.SystemPrompt
+	}
 	if len(prompt.Tools) > 0 {
 		options["tools"] = prompt.Tools
 	}
 	if len(prompt.ToolChoice) > 0 {
 		options["tool_choice"] = prompt.ToolChoice
 	}
-	options = applyDefaultOptions(options, l.config)
+	if prompt.SystemCacheType != "" {
+		options["system_cache_type"] = string(prompt.SystemCacheType)
+	}
+	options = l.clampMaxTokens(applyDefaultOptions(options, l.config))
 	options["stream"] = true
 	options["stream_options"] = map[string]interface{}{
 		"include_usage": true,
@@ -489,16 +836,50 @@ func (l *LLMImpl) Stream(ctx context.Context, prompt *Prompt, opts ...StreamOpti
 			adaptorCfg.Headers = headers
 		}
 	}
-	body, err := l.relay.Stream(ctx, l.adaptor, streamAdaptor, &adaptorCfg, request)
+	open := func(ctx context.Context, partial string) (io.ReadCloser, error) {
+		streamRequest := request
+		if partial = strings.TrimRight(partial, " \t\r\n"); partial != "" {
+			// Continue the interrupted answer as an assistant prefill.
+			resumed := *request
+			resumed.Messages = append(append([]dto.Message(nil), request.Messages...), dto.Message{Role: "assistant", Content: partial})
+			streamRequest = &resumed
+		}
+		body, err := l.relay.Stream(ctx, l.adaptor, streamAdaptor, &adaptorCfg, streamRequest)
+		if err != nil {
+			return nil, newAPIError("relay stream request failed", err)
+		}
+		return body, nil
+	}
+	spanCtx, span := l.telemetry.Start(ctx, telemetry.OperationStream, l.providerName, l.config.Model)
+	// A request that fails before the first byte is retried like Generate; the
+	// stream's retry strategy is kept for interruptions of the opened stream.
+	var body io.ReadCloser
+	attempts, err := retry.Do(spanCtx, l.retryPolicy(), func(int) error {
+		var err error
+		body, err = open(spanCtx, "")
+		return err
+	}, l.logRetry(ctx, span, "Stream request failed"))
 	if err != nil {
-		return nil, NewLLMError(ErrorTypeAPI, "relay stream request failed", err)
+		span.End(telemetry.Result{Attempts: attempts, ErrorType: telemetryErrorType(err)})
+		return nil, err
 	}
 
-	return newProviderStream(body, streamAdaptor, config), nil
+	if config.RetryStrategy != nil {
+		config.RetryStrategy.Reset()
+	}
+	stream := newProviderStream(body, streamAdaptor, l.providerName, config)
+	stream.model = adaptorCfg.Model
+	stream.reconnect = open
+	stream.span = span
+	stream.recordUsage = func(usage dto.Usage) {
+		l.recordCost(ctx, l.config.Model, tokenUsage(usage))
+	}
+	return stream, nil
 }
 
 // Image initiates an image generation request.
-// Media initiates an image/video generation request.
+// Media initiates an image/video generation request. Submissions are retried only
+// when the provider rejected them; see submissionPolicy.
 func (l *LLMImpl) Media(ctx context.Context, request *dto.MediaRequest) (*dto.MediaResponse, error) {
 	if request == nil {
 		return nil, NewLLMError(ErrorTypeInvalidInput, "media request is nil", nil)
@@ -506,23 +887,49 @@ func (l *LLMImpl) Media(ctx context.Context, request *dto.MediaRequest) (*dto.Me
 	if request.Model == "" {
 		request.Model = l.config.Model
 	}
+	if err := l.checkBudget(ctx); err != nil {
+		return nil, err
+	}
 
 	adaptorCfg := *l.adaptorCfg
 	adaptorCfg.Model = request.Model
-	response, err := l.relay.Media(ctx, l.adaptor, &adaptorCfg, request)
+	ctx = withLogFields(ctx, "request_id", newRequestID())
+	ctx, span := l.telemetry.Start(ctx, telemetry.OperationMedia, l.providerName, request.Model)
+	var response *dto.MediaResponse
+	attempts, err := retry.Do(ctx, submissionPolicy{l.retryPolicy()}, func(int) error {
+		var err error
+		response, err = l.relay.Media(ctx, l.adaptor, &adaptorCfg, request)
+		if err != nil {
+			return newAPIError("relay media request failed", err)
+		}
+		return nil
+	}, l.logRetry(ctx, span, "Media request failed"))
+	span.End(telemetry.Result{Attempts: attempts, ErrorType: telemetryErrorType(err)})
 	if err != nil {
-		return nil, NewLLMError(ErrorTypeAPI, "relay media request failed", err)
+		return nil, err
 	}
-
+	l.recordMediaCost(ctx, request, response)
 	return response, nil
 }
 
 // TaskStatus queries a provider task status.
 func (l *LLMImpl) TaskStatus(ctx context.Context, taskID string) (*dto.TaskStatusResponse, error) {
-	response, err := l.relay.TaskStatus(ctx, l.adaptor, l.adaptorCfg, taskID)
+	ctx = withLogFields(ctx, "request_id", newRequestID())
+	ctx, span := l.telemetry.Start(ctx, telemetry.OperationTask, l.providerName, l.config.Model)
+	var response *dto.TaskStatusResponse
+	attempts, err := retry.Do(ctx, l.retryPolicy(), func(int) error {
+		var err error
+		response, err = l.relay.TaskStatus(ctx, l.adaptor, l.adaptorCfg, taskID)
+		if err != nil {
+			return newAPIError("relay task status request failed", err)
+		}
+		return nil
+	}, l.logRetry(ctx, span, "Task status request failed"))
+	span.End(telemetry.Result{Attempts: attempts, ErrorType: telemetryErrorType(err)})
 	if err != nil {
-		return nil, NewLLMError(ErrorTypeAPI, "relay task status request failed", err)
+		return nil, err
 	}
+	l.recordTaskCost(ctx, taskID, response)
 	return response, nil
 }
 
@@ -533,24 +940,17 @@ func toDTOMessages(messages []PromptMessage) []dto.Message {
 	converted := make([]dto.Message, 0, len(messages))
 	for _, msg := range messages {
 		converted = append(converted, dto.Message{
-			Role:    msg.Role,
-			Content: msg.Content,
+			Role:       msg.Role,
+			Content:    messageContent(msg),
+			Name:       msg.Name,
+			ToolCalls:  toDTOToolCalls(msg.ToolCalls),
+			ToolCallID: msg.ToolCallID,
+			CacheType:  string(msg.CacheType),
 		})
 	}
 	return converted
 }
 
-func firstChoiceContent(response *dto.ChatResponse) (string, error) {
-	if response == nil || len(response.Choices) == 0 {
-		return "", fmt.Errorf("empty response choices")
-	}
-	content := response.Choices[0].Message.Content
-	if content == nil {
-		return "", fmt.Errorf("empty response content")
-	}
-	return fmt.Sprint(content), nil
-}
-
 func filterOptions(options map[string]interface{}, keys ...string) map[string]interface{} {
 	if len(options) == 0 || len(keys) == 0 {
 		return options
@@ -570,7 +970,7 @@ func filterOptions(options map[string]interface{}, keys ...string) map[string]in
 }
 
 func (l *LLMImpl) useOpenAIProtocol() bool {
-	return l.chatProtocol == "openai"
+	return l.chatProtocol == adapter.ChatProtocolOpenAI
 }
 
 func applyDefaultOptions(options map[string]interface{}, cfg *config.Config) map[string]interface{} {
@@ -620,49 +1020,136 @@ func applyDefaultOptions(options map[string]interface{}, cfg *config.Config) map
 }
 
 // SupportsStreaming checks if the provider supports streaming responses.
+// A model whose catalog entry lacks streaming support reports false.
 func (l *LLMImpl) SupportsStreaming() bool {
+	if l.modelInfo != nil && !l.modelInfo.Streaming {
+		return false
+	}
 	if _, ok := l.adaptor.(adapter.StreamAdaptor); ok {
 		return true
 	}
 	return l.supportsStreaming
 }
 
+// SupportsMedia reports whether the provider can generate the given media type.
+func (l *LLMImpl) SupportsMedia(mediaType dto.MediaType) bool {
+	return l.providerSpec.SupportsMode(string(mediaType))
+}
+
 // providerStream implements TokenStream for a specific provider
 type providerStream struct {
 	decoder       *SSEDecoder
-	parser        interface{ ParseStreamResponse([]byte) (string, error) }
+	parser        adapter.StreamAdaptor
+	provider      string
 	config        *StreamConfig
 	buffer        []byte
 	currentIndex  int
 	retryStrategy RetryStrategy
 	reader        io.ReadCloser
+
+	// reconnect re-issues the stream request, continuing from partial text when non-empty.
+	reconnect func(ctx context.Context, partial string) (io.ReadCloser, error)
+	text      strings.Builder
+
+	pending   []*StreamToken
+	toolCalls []*toolCallBuilder
+	toolSlots map[int]int
+	usage     dto.Usage
+
+	// Response metadata reported by the provider; model and created default to the
+	// requested model and the time the stream was opened.
+	responseID string
+	model      string
+	created    int64
+
+	span         *telemetry.Span // Operation span, ended when the stream finishes
+	finishReason FinishReason
+	recordUsage  func(dto.Usage) // Records the stream's cost once it finishes; nil afterwards
 }
 
-func newProviderStream(reader io.ReadCloser, parser interface{ ParseStreamResponse([]byte) (string, error) }, config *StreamConfig) *providerStream {
+// toolCallBuilder accumulates argument fragments for a streamed tool call.
+type toolCallBuilder struct {
+	call       ToolCall
+	providerID string
+	arguments  strings.Builder
+}
+
+func newProviderStream(reader io.ReadCloser, parser adapter.StreamAdaptor, provider string, config *StreamConfig) *providerStream {
 	return &providerStream{
 		decoder:       NewSSEDecoder(reader),
 		parser:        parser,
+		provider:      provider,
 		config:        config,
 		buffer:        make([]byte, 0, 4096),
 		currentIndex:  0,
 		retryStrategy: config.RetryStrategy,
 		reader:        reader,
+		toolSlots:     make(map[int]int),
+		created:       time.Now().Unix(),
 	}
 }
 
 func (s *providerStream) Next(ctx context.Context) (*StreamToken, error) {
+	token, err := s.next(ctx)
+	if err == io.EOF {
+		s.endSpan(nil)
+		return nil, err
+	}
+	if err != nil {
+		s.endSpan(err)
+		return nil, err
+	}
+	s.span.RecordFirstToken()
+	if token.FinishReason != "" {
+		s.finishReason = token.FinishReason
+	}
+	return token, nil
+}
+
+// endSpan ends the operation span with the final usage and finish reason, records
+// the cost of the stream and corrects the rate limiter's token estimate.
+func (s *providerStream) endSpan(err error) {
+	result := telemetry.Result{
+		InputTokens:  s.usage.PromptTokens,
+		OutputTokens: s.usage.CompletionTokens,
+		ErrorType:    telemetryErrorType(err),
+	}
+	if s.finishReason != "" {
+		result.FinishReasons = []string{string(s.finishReason)}
+	}
+	s.span.End(result)
+	if reporter, ok := s.reader.(relay.UsageReporter); ok {
+		reporter.ReportUsage(s.usage)
+	}
+	if s.recordUsage != nil {
+		s.recordUsage(s.usage)
+		s.recordUsage = nil
+	}
+}
+
+func (s *providerStream) next(ctx context.Context) (*StreamToken, error) {
 	for {
+		if len(s.pending) > 0 {
+			token := s.pending[0]
+			s.pending = s.pending[1:]
+			token.Index = s.currentIndex
+			s.currentIndex++
+			if token.Type == TokenTypeError {
+				return nil, s.streamError(token)
+			}
+			return token, nil
+		}
+
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		default:
 			if !s.decoder.Next() {
 				if err := s.decoder.Err(); err != nil {
-					if s.retryStrategy.ShouldRetry(err) {
-						time.Sleep(s.retryStrategy.NextDelay())
-						continue
+					if err := s.resume(ctx, err); err != nil {
+						return nil, err
 					}
-					return nil, err
+					continue
 				}
 				return nil, io.EOF
 			}
@@ -672,29 +1159,186 @@ func (s *providerStream) Next(ctx context.Context) (*StreamToken, error) {
 				continue
 			}
 
-			// Process the event
-			token, err := s.parser.ParseStreamResponse(event.Data)
+			events, err := s.parseEvent(event.Data)
 			if err != nil {
-				if err.Error() == "skip token" {
-					continue
-				}
 				if err == io.EOF {
 					return nil, io.EOF
 				}
 				continue // Not enough data or malformed
 			}
+			for _, ev := range events {
+				if token := s.toToken(ev, event.Type); token != nil {
+					s.pending = append(s.pending, token)
+				}
+			}
+		}
+	}
+}
+
+// resume recovers from a transport failure by re-issuing the request. Before any token
+// has been emitted the request is simply repeated; afterwards it is only resumed when
+// ResumePartial is set and no tool call is in progress, continuing from the text so far.
+// It returns the error to surface when the stream cannot be recovered.
+func (s *providerStream) resume(ctx context.Context, cause error) error {
+	if s.reconnect == nil || s.retryStrategy == nil {
+		return cause
+	}
+	partial := ""
+	if s.currentIndex > 0 {
+		if !s.config.ResumePartial || len(s.toolCalls) > 0 {
+			return cause
+		}
+		partial = s.text.String()
+	}
+
+	err := cause
+	for s.retryStrategy.ShouldRetry(err) {
+		if waitErr := retry.Wait(ctx, s.retryStrategy.NextDelay()); waitErr != nil {
+			return waitErr
+		}
+		reader, reconnectErr := s.reconnect(ctx, partial)
+		if reconnectErr != nil {
+			err = reconnectErr
+			continue
+		}
+		_ = s.reader.Close()
+		s.reader = reader
+		s.decoder = NewSSEDecoder(reader)
+		return nil
+	}
+	return err
+}
+
+// streamError converts an error event into an LLMError, classified from the
+// provider's error code in the same way as an error response.
+func (s *providerStream) streamError(token *StreamToken) error {
+	code, _ := token.Metadata["error_code"].(string)
+	llmErr := NewLLMError(ClassifyError(&dto.LLMError{Message: token.Text, Provider: s.provider, Type: code}), token.Text, nil)
+	llmErr.Code = code
+	llmErr.Provider = s.provider
+	return llmErr
+}
+
+// parseEvent decodes one SSE payload, preferring typed events when the adaptor supports them.
+func (s *providerStream) parseEvent(data []byte) ([]dto.StreamEvent, error) {
+	if parser, ok := s.parser.(adapter.StreamEventParser); ok {
+		return parser.ParseStreamEvent(data)
+	}
+	text, err := s.parser.ParseStreamResponse(data)
+	if err != nil {
+		return nil, err
+	}
+	return []dto.StreamEvent{{Type: dto.StreamEventText, Text: text}}, nil
+}
+
+// toToken converts a provider event into a StreamToken, accumulating tool-call
+// arguments and usage across the stream. Tokens carry the response ID, model and
+// creation time known so far in their metadata.
+func (s *providerStream) toToken(ev dto.StreamEvent, eventName string) *StreamToken {
+	if ev.ID != "" {
+		s.responseID = ev.ID
+	}
+	if ev.Model != "" {
+		s.model = ev.Model
+	}
+	if ev.Created != 0 {
+		s.created = ev.Created
+	}
+	metadata := map[string]interface{}{"provider": s.provider, "created": s.created}
+	if eventName != "" {
+		metadata["event"] = eventName
+	}
+	if s.responseID != "" {
+		metadata["response_id"] = s.responseID
+	}
+	if s.model != "" {
+		metadata["model"] = s.model
+	}
+	token := &StreamToken{Type: string(ev.Type), Metadata: metadata}
 
-			// Create and return token
-			return &StreamToken{
-				Text:  token,
-				Type:  event.Type,
-				Index: s.currentIndex,
-			}, nil
+	switch ev.Type {
+	case dto.StreamEventText:
+		if ev.Text == "" {
+			return nil
+		}
+		token.Text = ev.Text
+		s.text.WriteString(ev.Text)
+	case dto.StreamEventToolCall:
+		if ev.ToolCall == nil {
+			return nil
+		}
+		slot := s.toolCallSlot(ev.ToolCall)
+		builder := s.toolCalls[slot]
+		builder.arguments.WriteString(ev.ToolCall.Arguments)
+		snapshot := builder.call
+		snapshot.Function.Arguments = json.RawMessage(builder.arguments.String())
+		token.ToolCall = &snapshot
+		token.ArgumentsDelta = ev.ToolCall.Arguments
+		metadata["tool_call_index"] = slot
+	case dto.StreamEventUsage:
+		if ev.Usage == nil {
+			return nil
+		}
+		s.usage.Merge(ev.Usage)
+		usage := s.usage
+		token.Usage = &usage
+	case dto.StreamEventFinish:
+		token.FinishReason = NormalizeFinishReason(ev.FinishReason)
+		if token.FinishReason == FinishReasonStop && len(s.toolCalls) > 0 {
+			token.FinishReason = FinishReasonToolCalls
 		}
+		metadata["raw_finish_reason"] = ev.FinishReason
+	case dto.StreamEventError:
+		token.Text = ev.Error
+		if ev.ErrorCode != "" {
+			metadata["error_code"] = ev.ErrorCode
+		}
+	default:
+		return nil
+	}
+	return token
+}
+
+// toolCallSlot resolves the accumulator for a delta. Deltas sharing a provider index
+// extend the same call, even when they repeat its name and ID (DashScope). A named
+// delta starts a new call on that index when its ID differs, or, without IDs, when
+// the arguments received so far are complete (Gemini reuses part indexes across chunks).
+func (s *providerStream) toolCallSlot(delta *dto.ToolCallDelta) int {
+	if slot, ok := s.toolSlots[delta.Index]; ok {
+		builder := s.toolCalls[slot]
+		startsNew := false
+		switch {
+		case delta.Name == "" || builder.call.Function.Name == "":
+		case delta.ID != "" && builder.providerID != "":
+			startsNew = delta.ID != builder.providerID
+		default:
+			startsNew = json.Valid([]byte(builder.arguments.String()))
+		}
+		if !startsNew {
+			if builder.providerID == "" && delta.ID != "" {
+				builder.providerID = delta.ID
+				builder.call.ID = delta.ID
+			}
+			if builder.call.Function.Name == "" {
+				builder.call.Function.Name = delta.Name
+			}
+			return slot
+		}
+	}
+
+	slot := len(s.toolCalls)
+	builder := &toolCallBuilder{providerID: delta.ID, call: ToolCall{ID: delta.ID, Type: "function"}}
+	if builder.call.ID == "" {
+		builder.call.ID = fmt.Sprintf("call_%d", slot)
 	}
+	builder.call.Function.Name = delta.Name
+	s.toolCalls = append(s.toolCalls, builder)
+	s.toolSlots[delta.Index] = slot
+	return slot
 }
 
 func (s *providerStream) Close() error {
+	s.endSpan(nil)
 	if s.reader == nil {
 		return nil
 	}
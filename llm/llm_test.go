@@ -0,0 +1,36 @@
+package llm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YspCoder/omnigo/adapter"
+	"github.com/YspCoder/omnigo/config"
+	"github.com/YspCoder/omnigo/utils"
+)
+
+// newTestLLM serves handler from a test server and returns an OpenAI LLM sending
+// its requests there, with retries and logging off. opts are applied last, so they
+// can change the provider, enable retries or point the LLM elsewhere.
+func newTestLLM(t *testing.T, handler http.HandlerFunc, opts ...config.ConfigOption) LLM {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, append([]config.ConfigOption{
+		config.SetProvider("openai"),
+		config.SetEndpoint(server.URL),
+		config.SetMaxRetries(0),
+	}, opts...)...)
+	// API keys are kept per provider, so the key is set once the provider is known.
+	if cfg.APIKeys[cfg.Provider] == "" {
+		config.ApplyOptions(cfg, config.SetAPIKey("test"))
+	}
+	model, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), adapter.NewRegistry())
+	if err != nil {
+		t.Fatalf("NewLLM returned error: %v", err)
+	}
+	return model
+}
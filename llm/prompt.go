@@ -22,12 +22,13 @@ const (
 // It can be a system message, user message, or assistant message, and may include
 // tool calls and caching configuration.
 type PromptMessage struct {
-	Role       string     `json:"role"`                   // Role of the message sender (e.g., "system", "user", "assistant")
-	Content    string     `json:"content"`                // The actual message content
-	CacheType  CacheType  `json:"cache_type,omitempty"`   // Optional caching strategy for this message
-	Name       string     `json:"name,omitempty"`         // Optional name identifier for the message
-	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // Optional tool calls requested by the LLM
-	ToolCallID string     `json:"tool_call_id,omitempty"` // ID of the tool call this message responds to
+	Role       string        `json:"role"`                   // Role of the message sender (e.g., "system", "user", "assistant")
+	Content    string        `json:"content"`                // The actual message content
+	CacheType  CacheType     `json:"cache_type,omitempty"`   // Optional caching strategy for this message
+	Name       string        `json:"name,omitempty"`         // Optional name identifier for the message
+	ToolCalls  []ToolCall    `json:"tool_calls,omitempty"`   // Optional tool calls requested by the LLM
+	ToolCallID string        `json:"tool_call_id,omitempty"` // ID of the tool call this message responds to
+	Parts      []ContentPart `json:"parts,omitempty"`        // Optional multimodal parts sent after Content
 }
 
 // ToolCall represents a request from the LLM to use a specific tool.
@@ -56,6 +57,7 @@ type Prompt struct {
 	Messages        []PromptMessage        `json:"messages,omitempty" jsonschema:"description=List of messages for the conversation"`
 	Tools           []utils.Tool           `json:"tools,omitempty" jsonschema:"description=Available tools for the LLM to use"`
 	ToolChoice      map[string]interface{} `json:"tool_choice,omitempty" jsonschema:"description=Configuration for tool selection behavior"`
+	Truncation      TruncationStrategy     `json:"-"` // Applied when the prompt exceeds the context window
 }
 
 // PromptOption is a function type that modifies a Prompt.
@@ -124,6 +126,20 @@ func WithMessage(role, content string, cacheType CacheType) PromptOption {
 	}
 }
 
+// WithToolResult appends the output of a tool call so it can be sent back to the LLM.
+// The assistant message that requested the call must precede it in the conversation
+// (see GenerateResult.AssistantMessage).
+//
+// Parameters:
+//   - toolCallID: ID of the tool call being answered
+//   - name: Name of the function that was called
+//   - content: Tool output, typically JSON
+func WithToolResult(toolCallID, name, content string) PromptOption {
+	return func(p *Prompt) {
+		p.Messages = append(p.Messages, PromptMessage{Role: "tool", Content: content, Name: name, ToolCallID: toolCallID})
+	}
+}
+
 // WithTools configures the available tools for the LLM to use.
 //
 // Parameters:
@@ -0,0 +1,187 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/YspCoder/omnigo/dto"
+)
+
+// FinishReason is the provider-independent reason a generation stopped.
+type FinishReason string
+
+const (
+	// FinishReasonStop indicates the model finished naturally or hit a stop sequence.
+	FinishReasonStop FinishReason = "stop"
+
+	// FinishReasonLength indicates the output was truncated by the token limit.
+	FinishReasonLength FinishReason = "length"
+
+	// FinishReasonToolCalls indicates the model stopped to request tool calls.
+	FinishReasonToolCalls FinishReason = "tool_calls"
+
+	// FinishReasonContentFilter indicates the output was blocked or cut by a safety filter.
+	FinishReasonContentFilter FinishReason = "content_filter"
+
+	// FinishReasonOther covers provider reasons without a portable equivalent.
+	FinishReasonOther FinishReason = "other"
+)
+
+// NormalizeFinishReason maps provider-specific stop reasons (OpenAI finish_reason,
+// Anthropic stop_reason, Gemini finishReason, DashScope finish_reason) onto FinishReason.
+// An empty input yields an empty result.
+func NormalizeFinishReason(raw string) FinishReason {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", "null":
+		return ""
+	case "stop", "end_turn", "stop_sequence", "finish_reason_stop":
+		return FinishReasonStop
+	case "length", "max_tokens":
+		return FinishReasonLength
+	case "tool_calls", "function_call", "tool_use":
+		return FinishReasonToolCalls
+	case "content_filter", "safety", "recitation", "blocklist", "prohibited_content", "spii", "refusal", "image_safety":
+		return FinishReasonContentFilter
+	default:
+		return FinishReasonOther
+	}
+}
+
+// GenerateResult holds the structured outcome of a generation call.
+// Unlike the plain string returned by Generate, it preserves the tool calls
+// requested by the model so callers can execute them and continue the conversation,
+// together with response metadata used for billing and truncation detection.
+type GenerateResult struct {
+	Content         string        // Text content of the assistant message
+	ToolCalls       []ToolCall    // Tool calls requested by the model, if any
+	FinishReason    FinishReason  // Normalized reason the generation stopped
+	RawFinishReason string        // Finish reason exactly as reported by the provider
+	Usage           dto.Usage     // Token usage reported by the provider
+	ID              string        // Provider response/request identifier
+	Model           string        // Model that produced the response
+	Provider        string        // Provider that served the request
+	Backend         string        // Router backend that served the request, if routed
+	Latency         time.Duration // Duration of the successful provider call
+	Attempts        int           // Number of attempts made, including retries
+	Cached          bool          // Whether the response was served from the response cache
+}
+
+// Truncated reports whether the output was cut off by the token limit.
+func (r *GenerateResult) Truncated() bool {
+	return r != nil && r.FinishReason == FinishReasonLength
+}
+
+// HasToolCalls reports whether the model requested any tool calls.
+func (r *GenerateResult) HasToolCalls() bool {
+	return r != nil && len(r.ToolCalls) > 0
+}
+
+// AssistantMessage returns the assistant turn that produced this result,
+// ready to be appended to Prompt.Messages before sending tool results back.
+func (r *GenerateResult) AssistantMessage() PromptMessage {
+	return PromptMessage{
+		Role:      "assistant",
+		Content:   r.Content,
+		ToolCalls: r.ToolCalls,
+	}
+}
+
+// newGenerateResult extracts the first choice of a chat response.
+func newGenerateResult(response *dto.ChatResponse) (*GenerateResult, error) {
+	if response == nil || len(response.Choices) == 0 {
+		return nil, fmt.Errorf("empty response choices")
+	}
+	choice := response.Choices[0]
+	result := &GenerateResult{
+		Content:         contentText(choice.Message.Content),
+		ToolCalls:       fromDTOToolCalls(choice.Message.ToolCalls),
+		RawFinishReason: choice.FinishReason,
+		FinishReason:    NormalizeFinishReason(choice.FinishReason),
+		Usage:           response.Usage,
+		ID:              response.ID,
+		Model:           response.Model,
+	}
+	// Gemini and DashScope report a plain stop when the model asks for tools.
+	if len(result.ToolCalls) > 0 && (result.FinishReason == FinishReasonStop || result.FinishReason == "") {
+		result.FinishReason = FinishReasonToolCalls
+	}
+	if result.Content == "" && len(result.ToolCalls) == 0 {
+		return nil, fmt.Errorf("empty response content")
+	}
+	return result, nil
+}
+
+// contentText flattens a message content value, joining the text parts of
+// OpenAI-style content arrays.
+func contentText(content interface{}) string {
+	switch value := content.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case []interface{}:
+		var builder strings.Builder
+		for _, item := range value {
+			part, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if text, ok := part["text"].(string); ok {
+				builder.WriteString(text)
+			}
+		}
+		return builder.String()
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func fromDTOToolCalls(calls []dto.ToolCall) []ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	converted := make([]ToolCall, 0, len(calls))
+	for _, call := range calls {
+		var tc ToolCall
+		tc.ID = call.ID
+		tc.Type = call.Type
+		if tc.Type == "" {
+			tc.Type = "function"
+		}
+		tc.Function.Name = call.Function.Name
+		tc.Function.Arguments = json.RawMessage(call.Function.Arguments)
+		if len(tc.Function.Arguments) == 0 || !json.Valid(tc.Function.Arguments) {
+			tc.Function.Arguments = json.RawMessage("{}")
+		}
+		converted = append(converted, tc)
+	}
+	return converted
+}
+
+func toDTOToolCalls(calls []ToolCall) []dto.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	converted := make([]dto.ToolCall, 0, len(calls))
+	for _, call := range calls {
+		callType := call.Type
+		if callType == "" {
+			callType = "function"
+		}
+		arguments := string(call.Function.Arguments)
+		if arguments == "" {
+			arguments = "{}"
+		}
+		converted = append(converted, dto.ToolCall{
+			ID:   call.ID,
+			Type: callType,
+			Function: dto.ToolCallFunction{
+				Name:      call.Function.Name,
+				Arguments: arguments,
+			},
+		})
+	}
+	return converted
+}
@@ -0,0 +1,481 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/YspCoder/omnigo/catalog"
+	"github.com/YspCoder/omnigo/dto"
+	"github.com/YspCoder/omnigo/retry"
+	"github.com/YspCoder/omnigo/utils"
+)
+
+var _ LLM = (*Router)(nil)
+
+// Default circuit breaker settings for router backends.
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// routerTaskTTL bounds how long the backend of a media task is remembered when its
+// status is never polled until the task finishes.
+const routerTaskTTL = 24 * time.Hour
+
+// finishedTaskStatuses are the lower-cased terminal task statuses of the supported
+// providers, e.g. DashScope SUCCEEDED, Jimeng done and Google completed.
+var finishedTaskStatuses = map[string]bool{
+	"succeeded": true,
+	"completed": true,
+	"done":      true,
+	"failed":    true,
+	"canceled":  true,
+	"cancelled": true,
+	"expired":   true,
+	"not_found": true,
+	"unknown":   true,
+}
+
+// Backend is a named LLM served by a Router.
+type Backend struct {
+	Name   string // Identifies the backend in results, stream metadata and logs
+	LLM    LLM    // The configured LLM instance
+	Weight int    // Relative share of traffic; when all weights are 0 backends are tried in order
+}
+
+// MediaSupporter is implemented by LLMs that report which media types they can generate.
+// Router backends that do not implement it are assumed to support every media type.
+type MediaSupporter interface {
+	SupportsMedia(mediaType dto.MediaType) bool
+}
+
+// RouterOption configures a Router.
+type RouterOption func(*Router)
+
+// WithCircuitBreaker sets how many consecutive failures open a backend's circuit and
+// how long it stays open before a trial request is allowed. A threshold of 0 disables it.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) RouterOption {
+	return func(r *Router) {
+		r.breakerThreshold = threshold
+		r.breakerCooldown = cooldown
+	}
+}
+
+// WithRouterLogger sets the logger used to report failovers.
+func WithRouterLogger(logger utils.Logger) RouterOption {
+	return func(r *Router) {
+		r.logger = logger
+	}
+}
+
+// Router implements LLM over an ordered list of backends. Each call goes to the first
+// eligible backend (or a weighted random pick when weights are set) and fails over to
+// the next one on retryable or backend-specific errors such as rate limits, overload,
+// server errors and authentication failures. Backends are skipped when their circuit
+// is open or they lack the capability the call needs (streaming, JSON schema, media type).
+// The serving backend is reported in GenerateResult.Backend, in the "backend" metadata
+// of stream tokens and in MediaResponse.Backend.
+type Router struct {
+	backends         []*routerBackend
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	logger           utils.Logger
+
+	tasks sync.Map // task ID -> routerTask; removed once the task finishes or expires
+	mu    sync.Mutex
+	rand  *rand.Rand
+}
+
+// routerTask remembers the backend that created a media task.
+type routerTask struct {
+	backend *routerBackend
+	expires time.Time
+}
+
+type routerBackend struct {
+	Backend
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// NewRouter creates a Router over the given backends.
+//
+// Parameters:
+//   - backends: Backends in priority order; each needs an LLM and a unique name
+//   - opts: Router options such as WithCircuitBreaker
+//
+// Returns:
+//   - The router, or ErrorTypeInvalidInput when no usable backend is given
+func NewRouter(backends []Backend, opts ...RouterOption) (*Router, error) {
+	if len(backends) == 0 {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "router requires at least one backend", nil)
+	}
+	router := &Router{
+		breakerThreshold: DefaultBreakerThreshold,
+		breakerCooldown:  DefaultBreakerCooldown,
+		logger:           utils.NewLogger(utils.LogLevelWarn),
+		rand:             rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	names := make(map[string]bool, len(backends))
+	for i, backend := range backends {
+		if backend.LLM == nil {
+			return nil, NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("router backend %d has no LLM", i), nil)
+		}
+		if backend.Name == "" {
+			backend.Name = fmt.Sprintf("backend-%d", i)
+		}
+		if names[backend.Name] {
+			return nil, NewLLMError(ErrorTypeInvalidInput, "duplicate router backend name: "+backend.Name, nil)
+		}
+		names[backend.Name] = true
+		router.backends = append(router.backends, &routerBackend{Backend: backend})
+	}
+	for _, opt := range opts {
+		opt(router)
+	}
+	return router, nil
+}
+
+// Generate produces text using the first backend that succeeds.
+func (r *Router) Generate(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (string, error) {
+	result, err := r.GenerateResult(ctx, prompt, opts...)
+	if err != nil {
+		return "", err
+	}
+	return result.Content, nil
+}
+
+// GenerateResult produces a structured result using the first backend that succeeds.
+// Result.Backend names the backend that answered.
+func (r *Router) GenerateResult(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (*GenerateResult, error) {
+	var result *GenerateResult
+	err := r.route(ctx, "generate", nil, nil, func(backend *routerBackend) error {
+		var err error
+		result, err = backend.LLM.GenerateResult(ctx, prompt, opts...)
+		if err == nil {
+			result.Backend = backend.Name
+		}
+		return err
+	})
+	return result, err
+}
+
+// GenerateWithSchema generates schema-conforming text on backends that support JSON schema.
+func (r *Router) GenerateWithSchema(ctx context.Context, prompt *Prompt, schema interface{}, opts ...GenerateOption) (string, error) {
+	var result string
+	eligible := func(backend *routerBackend) bool { return backend.LLM.SupportsJSONSchema() }
+	err := r.route(ctx, "generate with schema", eligible, nil, func(backend *routerBackend) error {
+		var err error
+		result, err = backend.LLM.GenerateWithSchema(ctx, prompt, schema, opts...)
+		return err
+	})
+	return result, err
+}
+
+// Stream opens a stream on a streaming-capable backend. Failover happens while opening
+// the stream; tokens carry the serving backend in their "backend" metadata.
+func (r *Router) Stream(ctx context.Context, prompt *Prompt, opts ...StreamOption) (TokenStream, error) {
+	var stream TokenStream
+	eligible := func(backend *routerBackend) bool { return backend.LLM.SupportsStreaming() }
+	err := r.route(ctx, "stream", eligible, nil, func(backend *routerBackend) error {
+		opened, err := backend.LLM.Stream(ctx, prompt, opts...)
+		if err != nil {
+			return err
+		}
+		stream = TeeStream(opened, func(token *StreamToken) error {
+			if token.Metadata == nil {
+				token.Metadata = make(map[string]interface{})
+			}
+			token.Metadata["backend"] = backend.Name
+			return nil
+		})
+		return nil
+	})
+	return stream, err
+}
+
+// Media runs a media request on a backend that supports the media type. Task IDs are
+// remembered so TaskStatus is sent to the backend that created the task. A request that
+// timed out is not failed over: the provider may already have accepted the job, and
+// another backend would submit it a second time.
+func (r *Router) Media(ctx context.Context, request *dto.MediaRequest) (*dto.MediaResponse, error) {
+	if request == nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "media request is nil", nil)
+	}
+	var response *dto.MediaResponse
+	eligible := func(backend *routerBackend) bool {
+		supporter, ok := backend.LLM.(MediaSupporter)
+		return !ok || supporter.SupportsMedia(request.Type)
+	}
+	failover := func(err error) bool { return !timedOut(err) }
+	err := r.route(ctx, "media", eligible, failover, func(backend *routerBackend) error {
+		// Each backend fills in its own default model.
+		attempt := *request
+		var err error
+		response, err = backend.LLM.Media(ctx, &attempt)
+		if err == nil {
+			response.Backend = backend.Name
+			if response.TaskID != "" {
+				r.rememberTask(response.TaskID, backend)
+			}
+		}
+		return err
+	})
+	return response, err
+}
+
+// TaskStatus queries the backend that created the task, or tries the backends in
+// order when the task was not created through this router. The task is forgotten
+// once it has finished.
+func (r *Router) TaskStatus(ctx context.Context, taskID string) (*dto.TaskStatusResponse, error) {
+	if value, ok := r.tasks.Load(taskID); ok {
+		response, err := value.(routerTask).backend.LLM.TaskStatus(ctx, taskID)
+		if err == nil && finishedTaskStatuses[strings.ToLower(response.Output.TaskStatus)] {
+			r.tasks.Delete(taskID)
+		}
+		return response, err
+	}
+	var response *dto.TaskStatusResponse
+	err := r.route(ctx, "task status", nil, nil, func(backend *routerBackend) error {
+		var err error
+		response, err = backend.LLM.TaskStatus(ctx, taskID)
+		return err
+	})
+	return response, err
+}
+
+// rememberTask records the backend of a new task and drops expired tasks.
+func (r *Router) rememberTask(taskID string, backend *routerBackend) {
+	now := time.Now()
+	r.tasks.Range(func(key, value interface{}) bool {
+		if now.After(value.(routerTask).expires) {
+			r.tasks.Delete(key)
+		}
+		return true
+	})
+	r.tasks.Store(taskID, routerTask{backend: backend, expires: now.Add(routerTaskTTL)})
+}
+
+// SupportsStreaming reports whether any backend supports streaming.
+func (r *Router) SupportsStreaming() bool {
+	for _, backend := range r.backends {
+		if backend.LLM.SupportsStreaming() {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsJSONSchema reports whether any backend supports JSON schema validation.
+func (r *Router) SupportsJSONSchema() bool {
+	for _, backend := range r.backends {
+		if backend.LLM.SupportsJSONSchema() {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsMedia reports whether any backend can generate the given media type.
+func (r *Router) SupportsMedia(mediaType dto.MediaType) bool {
+	for _, backend := range r.backends {
+		supporter, ok := backend.LLM.(MediaSupporter)
+		if !ok || supporter.SupportsMedia(mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetOption sets the option on every backend.
+func (r *Router) SetOption(key string, value interface{}) {
+	for _, backend := range r.backends {
+		backend.LLM.SetOption(key, value)
+	}
+}
+
+// SetLogLevel adjusts the logging verbosity of the router and every backend.
+func (r *Router) SetLogLevel(level utils.LogLevel) {
+	r.logger.SetLevel(level)
+	for _, backend := range r.backends {
+		backend.LLM.SetLogLevel(level)
+	}
+}
+
+// NewPrompt creates a new prompt instance.
+func (r *Router) NewPrompt(input string) *Prompt {
+	return r.backends[0].LLM.NewPrompt(input)
+}
+
+// CountTokens counts the prompt's tokens for the first backend in configuration order.
+func (r *Router) CountTokens(prompt *Prompt) int {
+	return r.backends[0].LLM.CountTokens(prompt)
+}
+
+// ModelInfo returns the catalog entry of the first backend's model.
+func (r *Router) ModelInfo() (catalog.Model, bool) {
+	if provider, ok := r.backends[0].LLM.(ModelInfoProvider); ok {
+		return provider.ModelInfo()
+	}
+	return catalog.Model{}, false
+}
+
+// GetLogger returns the router's logger.
+func (r *Router) GetLogger() utils.Logger {
+	return r.logger
+}
+
+// route runs call on eligible backends in routing order until one succeeds or an error
+// is not worth failing over for. When set, failover may stop the failover after a
+// backend failure, which still counts towards the backend's circuit breaker.
+func (r *Router) route(ctx context.Context, operation string, eligible func(*routerBackend) bool, failover func(error) bool, call func(*routerBackend) error) error {
+	var lastErr error
+	tried := 0
+	for _, backend := range r.order() {
+		if eligible != nil && !eligible(backend) {
+			continue
+		}
+		if !backend.allow(time.Now()) {
+			r.logger.Debug("Skipping backend with open circuit", "backend", backend.Name, "operation", operation)
+			continue
+		}
+		tried++
+		err := call(backend)
+		if err == nil {
+			backend.recordSuccess()
+			return nil
+		}
+		lastErr = err
+		if ctx.Err() != nil || !shouldFailover(err) {
+			// The request itself is at fault, so the backend stays healthy.
+			backend.release()
+			return err
+		}
+		if backend.recordFailure(time.Now(), r.breakerThreshold, r.breakerCooldown) {
+			r.logger.Warn("Backend circuit opened", "backend", backend.Name, "cooldown", r.breakerCooldown)
+		}
+		if failover != nil && !failover(err) {
+			r.logger.Warn("Backend failed, not failing over", "backend", backend.Name, "operation", operation, "error", err)
+			return err
+		}
+		r.logger.Warn("Backend failed, failing over", "backend", backend.Name, "operation", operation, "error", err)
+	}
+	if tried == 0 {
+		return NewLLMError(ErrorTypeUnsupported, "no available backend for "+operation, nil)
+	}
+	return NewLLMError(ErrorTypeProvider, fmt.Sprintf("all backends failed for %s", operation), lastErr)
+}
+
+// shouldFailover reports whether another backend may succeed where this one failed.
+func shouldFailover(err error) bool {
+	if retry.IsRetryable(err) {
+		return true
+	}
+	var llmErr *LLMError
+	if errors.As(err, &llmErr) {
+		switch llmErr.Type {
+		case ErrorTypeAuthentication, ErrorTypeUnsupported, ErrorTypeProvider:
+			return true
+		}
+	}
+	return false
+}
+
+// timedOut reports whether err is a client-side timeout, after which the provider
+// may still process the request.
+func timedOut(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// order returns the backends in the order they should be tried: list order, or a
+// weighted random order when any backend has a weight.
+func (r *Router) order() []*routerBackend {
+	weighted := false
+	for _, backend := range r.backends {
+		if backend.Weight > 0 {
+			weighted = true
+			break
+		}
+	}
+	if !weighted {
+		return r.backends
+	}
+
+	// Weighted shuffle: sort by u^(1/w), which draws without replacement in proportion to weight.
+	type keyed struct {
+		backend *routerBackend
+		key     float64
+	}
+	keys := make([]keyed, len(r.backends))
+	r.mu.Lock()
+	for i, backend := range r.backends {
+		weight := float64(backend.Weight)
+		if weight <= 0 {
+			weight = 1e-9
+		}
+		keys[i] = keyed{backend: backend, key: math.Pow(r.rand.Float64(), 1/weight)}
+	}
+	r.mu.Unlock()
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].key > keys[j].key })
+	ordered := make([]*routerBackend, len(keys))
+	for i, k := range keys {
+		ordered[i] = k.backend
+	}
+	return ordered
+}
+
+// allow reports whether the backend may take a request. After the cooldown of an open
+// circuit a single trial request is let through (half-open).
+func (b *routerBackend) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		return true
+	}
+	if now.Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *routerBackend) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.probing = false
+}
+
+// release ends a half-open trial without judging the backend.
+func (b *routerBackend) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// recordFailure counts a failure and reports whether it opened the circuit.
+func (b *routerBackend) recordFailure(now time.Time, threshold int, cooldown time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	wasProbing := b.probing
+	b.probing = false
+	if threshold <= 0 || (b.failures < threshold && !wasProbing) {
+		return false
+	}
+	b.openUntil = now.Add(cooldown)
+	return true
+}
@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/YspCoder/omnigo/config"
+	"github.com/YspCoder/omnigo/dto"
+	"github.com/YspCoder/omnigo/utils"
+)
+
+func newTestBackend(t *testing.T, provider string, status int, calls *int32) LLM {
+	t.Helper()
+	return newTestLLM(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"from ` + provider + `"},"finish_reason":"stop"}]}`))
+	}, config.SetProvider(provider))
+}
+
+func TestRouterFailsOverAndOpensCircuit(t *testing.T) {
+	var primaryCalls, secondaryCalls int32
+	router, err := NewRouter([]Backend{
+		{Name: "primary", LLM: newTestBackend(t, "openai", http.StatusServiceUnavailable, &primaryCalls)},
+		{Name: "secondary", LLM: newTestBackend(t, "groq", http.StatusOK, &secondaryCalls)},
+	}, WithCircuitBreaker(1, time.Minute), WithRouterLogger(utils.NewLogger(utils.LogLevelOff)))
+	if err != nil {
+		t.Fatalf("NewRouter returned error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		result, err := router.GenerateResult(context.Background(), NewPrompt("hello"))
+		if err != nil {
+			t.Fatalf("GenerateResult returned error: %v", err)
+		}
+		if result.Backend != "secondary" || result.Content != "from groq" {
+			t.Fatalf("expected the secondary backend to answer, got %q from %q", result.Content, result.Backend)
+		}
+	}
+	if primaryCalls != 1 || secondaryCalls != 2 {
+		t.Fatalf("expected the open circuit to skip the primary, got %d/%d calls", primaryCalls, secondaryCalls)
+	}
+
+	if _, err := router.Media(context.Background(), &dto.MediaRequest{Type: dto.MediaTypeVideo}); ClassifyError(err) != ErrorTypeUnsupported {
+		t.Fatalf("expected no available backend for video generation, got %v", err)
+	}
+}
+
+func TestRouterMediaTasks(t *testing.T) {
+	var status atomic.Value
+	status.Store("RUNNING")
+	newBackend := func(handler http.HandlerFunc, timeout time.Duration) LLM {
+		return newTestLLM(t, handler,
+			config.SetProvider("ali"),
+			config.SetModel("wanx2.1-t2v-turbo"),
+			config.SetTimeout(timeout),
+		)
+	}
+	video := newBackend(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/tasks/") {
+			_, _ = w.Write([]byte(`{"output":{"task_id":"task-1","task_status":"` + status.Load().(string) + `"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"output":{"task_id":"task-1","task_status":"PENDING"},"request_id":"req-1"}`))
+	}, time.Minute)
+	slow := newBackend(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}, 20*time.Millisecond)
+
+	// A timed-out submission may have been accepted, so it is not sent to another backend.
+	var secondaryCalls int32
+	router, err := NewRouter([]Backend{
+		{Name: "slow", LLM: slow},
+		{Name: "secondary", LLM: newTestBackend(t, "ali", http.StatusOK, &secondaryCalls)},
+	}, WithRouterLogger(utils.NewLogger(utils.LogLevelOff)))
+	if err != nil {
+		t.Fatalf("NewRouter returned error: %v", err)
+	}
+	if _, err := router.Media(context.Background(), &dto.MediaRequest{Type: dto.MediaTypeVideo, Prompt: "a cat"}); err == nil || secondaryCalls != 0 {
+		t.Fatalf("expected the timeout without failover, got %v after %d secondary calls", err, secondaryCalls)
+	}
+
+	router, err = NewRouter([]Backend{{Name: "video", LLM: video}})
+	if err != nil {
+		t.Fatalf("NewRouter returned error: %v", err)
+	}
+	response, err := router.Media(context.Background(), &dto.MediaRequest{Type: dto.MediaTypeVideo, Prompt: "a cat"})
+	if err != nil || response.TaskID != "task-1" || response.Backend != "video" {
+		t.Fatalf("unexpected media response %+v, %v", response, err)
+	}
+	for _, taskStatus := range []string{"RUNNING", "SUCCEEDED"} {
+		status.Store(taskStatus)
+		if _, err := router.TaskStatus(context.Background(), "task-1"); err != nil {
+			t.Fatalf("TaskStatus returned error: %v", err)
+		}
+		_, remembered := router.tasks.Load("task-1")
+		if remembered != (taskStatus == "RUNNING") {
+			t.Fatalf("task remembered = %v after status %s", remembered, taskStatus)
+		}
+	}
+}
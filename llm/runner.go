@@ -0,0 +1,270 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/YspCoder/omnigo/utils"
+)
+
+// ErrMaxStepsExceeded is returned (wrapped in an LLMError) when the tool loop
+// does not produce a final answer within the configured number of steps.
+var ErrMaxStepsExceeded = errors.New("max steps exceeded")
+
+// ToolHandler executes a single tool call. It receives the raw JSON arguments
+// produced by the model and returns the content sent back as the tool result.
+type ToolHandler func(ctx context.Context, arguments json.RawMessage) (string, error)
+
+// ToolApprovalFunc decides whether a tool call may run.
+// Returning false sends a denial to the model as the tool result;
+// returning an error aborts the run.
+type ToolApprovalFunc func(ctx context.Context, call ToolCall) (bool, error)
+
+// Runner drives the generate / execute tools / respond loop on top of an LLM.
+// It calls GenerateResult, runs any tool calls the model requests with the
+// registered handlers, appends the results to the conversation and repeats
+// until the model answers with plain content or MaxSteps is reached.
+type Runner struct {
+	llm         LLM
+	tools       []utils.Tool
+	handlers    map[string]ToolHandler
+	maxSteps    int
+	parallel    bool
+	toolTimeout time.Duration
+	approve     ToolApprovalFunc
+}
+
+// RunnerOption is a function type for configuring a Runner.
+type RunnerOption func(*Runner)
+
+// RunResult holds the outcome of a Runner execution.
+type RunResult struct {
+	Final    *GenerateResult // Last result returned by the model
+	Messages []PromptMessage // Full conversation including tool calls and results
+	Steps    int             // Number of generation calls made
+}
+
+// NewRunner creates a tool execution loop for the given LLM.
+//
+// Example:
+//
+//	runner := NewRunner(model,
+//	    WithRunnerTool(weatherTool, weatherHandler),
+//	    WithMaxSteps(5),
+//	    WithParallelToolCalls(true),
+//	)
+//	result, err := runner.Run(ctx, NewPrompt("What's the weather in Paris?"))
+func NewRunner(model LLM, opts ...RunnerOption) *Runner {
+	r := &Runner{
+		llm:      model,
+		handlers: make(map[string]ToolHandler),
+		maxSteps: 10,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// WithRunnerTool registers a tool definition together with its handler.
+// The definition is added to the prompt's tools if it is not already present.
+func WithRunnerTool(tool utils.Tool, handler ToolHandler) RunnerOption {
+	return func(r *Runner) {
+		if tool.Type == "" {
+			tool.Type = "function"
+		}
+		r.tools = append(r.tools, tool)
+		r.handlers[tool.Function.Name] = handler
+	}
+}
+
+// WithToolHandlers registers handlers keyed by function name for tools that are
+// already declared on the prompt via WithTools.
+func WithToolHandlers(handlers map[string]ToolHandler) RunnerOption {
+	return func(r *Runner) {
+		for name, handler := range handlers {
+			r.handlers[name] = handler
+		}
+	}
+}
+
+// WithMaxSteps limits the number of generation calls made by the runner.
+func WithMaxSteps(steps int) RunnerOption {
+	return func(r *Runner) {
+		if steps > 0 {
+			r.maxSteps = steps
+		}
+	}
+}
+
+// WithParallelToolCalls executes the tool calls of a single step concurrently.
+func WithParallelToolCalls(parallel bool) RunnerOption {
+	return func(r *Runner) {
+		r.parallel = parallel
+	}
+}
+
+// WithToolTimeout bounds the execution time of each individual tool call.
+func WithToolTimeout(timeout time.Duration) RunnerOption {
+	return func(r *Runner) {
+		r.toolTimeout = timeout
+	}
+}
+
+// WithToolApproval installs a hook that approves or denies each tool call before it runs.
+func WithToolApproval(approve ToolApprovalFunc) RunnerOption {
+	return func(r *Runner) {
+		r.approve = approve
+	}
+}
+
+// Run executes the tool loop for the given prompt. The prompt itself is not
+// modified; the full conversation is returned in RunResult.Messages.
+//
+// Returns:
+//   - Run result with the final model answer and the conversation
+//   - ErrorTypeResponse wrapping ErrMaxStepsExceeded if the loop does not finish
+//   - Error types as per GenerateResult for generation failures
+func (r *Runner) Run(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (*RunResult, error) {
+	if prompt == nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "prompt is nil", nil)
+	}
+
+	working := *prompt
+	working.Messages = append([]PromptMessage(nil), prompt.Messages...)
+	working.Tools = mergeTools(prompt.Tools, r.tools)
+
+	logger := r.llm.GetLogger()
+	run := &RunResult{}
+	for run.Steps < r.maxSteps {
+		run.Steps++
+		result, err := r.llm.GenerateResult(ctx, &working, opts...)
+		if err != nil {
+			run.Messages = working.Messages
+			return run, err
+		}
+		run.Final = result
+		working.Messages = append(working.Messages, result.AssistantMessage())
+		if !result.HasToolCalls() {
+			run.Messages = working.Messages
+			return run, nil
+		}
+
+		logger.Debug("Executing tool calls", "step", run.Steps, "count", len(result.ToolCalls))
+		outputs, err := r.executeAll(ctx, result.ToolCalls)
+		if err != nil {
+			run.Messages = working.Messages
+			return run, err
+		}
+		for i, call := range result.ToolCalls {
+			working.Messages = append(working.Messages, PromptMessage{
+				Role:       "tool",
+				Content:    outputs[i],
+				Name:       call.Function.Name,
+				ToolCallID: call.ID,
+			})
+		}
+	}
+
+	run.Messages = working.Messages
+	return run, NewLLMError(ErrorTypeResponse, fmt.Sprintf("tool loop did not finish within %d steps", r.maxSteps), ErrMaxStepsExceeded)
+}
+
+// executeAll runs the tool calls of one step, preserving their order in the output.
+func (r *Runner) executeAll(ctx context.Context, calls []ToolCall) ([]string, error) {
+	outputs := make([]string, len(calls))
+	errs := make([]error, len(calls))
+
+	if !r.parallel || len(calls) == 1 {
+		for i, call := range calls {
+			outputs[i], errs[i] = r.execute(ctx, call)
+			if errs[i] != nil {
+				return nil, errs[i]
+			}
+		}
+		return outputs, nil
+	}
+
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func(i int, call ToolCall) {
+			defer wg.Done()
+			outputs[i], errs[i] = r.execute(ctx, call)
+		}(i, call)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return outputs, nil
+}
+
+// execute runs a single tool call. Failures of the tool itself are reported to the
+// model as the tool result; only approval errors and cancellation abort the run.
+func (r *Runner) execute(ctx context.Context, call ToolCall) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	logger := r.llm.GetLogger()
+	if r.approve != nil {
+		approved, err := r.approve(ctx, call)
+		if err != nil {
+			return "", NewLLMError(ErrorTypeInvalidInput, "tool approval failed", err)
+		}
+		if !approved {
+			logger.Info("Tool call denied", "tool", call.Function.Name, "id", call.ID)
+			return fmt.Sprintf("error: tool call %q was denied", call.Function.Name), nil
+		}
+	}
+
+	handler, ok := r.handlers[call.Function.Name]
+	if !ok {
+		logger.Warn("No handler registered for tool", "tool", call.Function.Name)
+		return fmt.Sprintf("error: unknown tool %q", call.Function.Name), nil
+	}
+
+	toolCtx := ctx
+	if r.toolTimeout > 0 {
+		var cancel context.CancelFunc
+		toolCtx, cancel = context.WithTimeout(ctx, r.toolTimeout)
+		defer cancel()
+	}
+
+	output, err := handler(toolCtx, call.Function.Arguments)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		logger.Warn("Tool call failed", "tool", call.Function.Name, "error", err)
+		return fmt.Sprintf("error: %v", err), nil
+	}
+	return output, nil
+}
+
+// mergeTools returns the prompt tools followed by any registered tools not already declared.
+func mergeTools(declared, registered []utils.Tool) []utils.Tool {
+	if len(registered) == 0 {
+		return declared
+	}
+	merged := append([]utils.Tool(nil), declared...)
+	seen := make(map[string]struct{}, len(declared))
+	for _, tool := range declared {
+		seen[tool.Function.Name] = struct{}{}
+	}
+	for _, tool := range registered {
+		if _, ok := seen[tool.Function.Name]; ok {
+			continue
+		}
+		merged = append(merged, tool)
+	}
+	return merged
+}
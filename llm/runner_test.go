@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/YspCoder/omnigo/retry"
+	"github.com/YspCoder/omnigo/utils"
+)
+
+// scriptedLLM returns queued results from GenerateResult and records the prompts it saw.
+type scriptedLLM struct {
+	LLM
+	results []*GenerateResult
+	prompts []*Prompt
+}
+
+func (s *scriptedLLM) GenerateResult(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (*GenerateResult, error) {
+	copied := *prompt
+	copied.Messages = append([]PromptMessage(nil), prompt.Messages...)
+	s.prompts = append(s.prompts, &copied)
+	result := s.results[0]
+	s.results = s.results[1:]
+	return result, nil
+}
+
+func (s *scriptedLLM) GetLogger() utils.Logger {
+	return utils.NewLogger(utils.LogLevelOff)
+}
+
+func toolCall(id, name, args string) ToolCall {
+	var call ToolCall
+	call.ID = id
+	call.Type = "function"
+	call.Function.Name = name
+	call.Function.Arguments = json.RawMessage(args)
+	return call
+}
+
+func TestRunnerExecutesToolsUntilFinalAnswer(t *testing.T) {
+	model := &scriptedLLM{results: []*GenerateResult{
+		{ToolCalls: []ToolCall{toolCall("1", "echo", `{"v":"a"}`), toolCall("2", "secret", `{}`)}},
+		{Content: "done"},
+	}}
+
+	echo := func(ctx context.Context, args json.RawMessage) (string, error) {
+		return string(args), nil
+	}
+	runner := NewRunner(model,
+		WithRunnerTool(utils.Tool{Function: utils.Function{Name: "echo"}}, echo),
+		WithRunnerTool(utils.Tool{Function: utils.Function{Name: "secret"}}, echo),
+		WithParallelToolCalls(true),
+		WithToolApproval(func(ctx context.Context, call ToolCall) (bool, error) {
+			return call.Function.Name != "secret", nil
+		}),
+	)
+
+	prompt := NewPrompt("hi")
+	result, err := runner.Run(context.Background(), prompt)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if result.Final.Content != "done" || result.Steps != 2 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if len(prompt.Messages) != 1 {
+		t.Fatalf("expected caller prompt to be left untouched, got %d messages", len(prompt.Messages))
+	}
+
+	second := model.prompts[1]
+	if len(second.Tools) != 2 {
+		t.Fatalf("expected registered tools to be declared, got %d", len(second.Tools))
+	}
+	msgs := second.Messages
+	if len(msgs) != 4 || msgs[2].ToolCallID != "1" || msgs[2].Content != `{"v":"a"}` {
+		t.Fatalf("unexpected conversation: %+v", msgs)
+	}
+	if msgs[3].ToolCallID != "2" || msgs[3].Content != `error: tool call "secret" was denied` {
+		t.Fatalf("expected denied tool result, got %+v", msgs[3])
+	}
+}
+
+func TestRunnerStopsAtMaxSteps(t *testing.T) {
+	loop := &GenerateResult{ToolCalls: []ToolCall{toolCall("1", "missing", `{}`)}}
+	model := &scriptedLLM{results: []*GenerateResult{loop, loop}}
+
+	_, err := NewRunner(model, WithMaxSteps(2)).Run(context.Background(), NewPrompt("hi"))
+	if !errors.Is(err, ErrMaxStepsExceeded) {
+		t.Fatalf("expected ErrMaxStepsExceeded, got %v", err)
+	}
+	// Running the same loop again, here or on another backend, would not help.
+	if retry.IsRetryable(err) || shouldFailover(err) {
+		t.Fatalf("expected ErrMaxStepsExceeded not to be retried, got %v", err)
+	}
+}
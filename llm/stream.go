@@ -6,6 +6,18 @@ import (
 	"context"
 	"io"
 	"time"
+
+	"github.com/YspCoder/omnigo/dto"
+	"github.com/YspCoder/omnigo/retry"
+)
+
+// Token types reported in StreamToken.Type.
+const (
+	TokenTypeText     = string(dto.StreamEventText)
+	TokenTypeToolCall = string(dto.StreamEventToolCall)
+	TokenTypeUsage    = string(dto.StreamEventUsage)
+	TokenTypeFinish   = string(dto.StreamEventFinish)
+	TokenTypeError    = string(dto.StreamEventError)
 )
 
 // StreamToken represents a single token from the streaming response.
@@ -13,13 +25,27 @@ type StreamToken struct {
 	// Text is the actual token text
 	Text string
 
-	// Type indicates the type of token (e.g., "text", "function_call", "error")
+	// Type indicates the type of token (see the TokenType constants)
 	Type string
 
 	// Index is the position of this token in the sequence
 	Index int
 
-	// Metadata contains provider-specific metadata
+	// ToolCall is the accumulated state of a streamed tool call; its arguments
+	// hold everything received so far for that call (tool_call tokens only)
+	ToolCall *ToolCall
+
+	// ArgumentsDelta is the argument fragment carried by this token (tool_call tokens only)
+	ArgumentsDelta string
+
+	// Usage is the running token usage reported by the provider (usage tokens only)
+	Usage *dto.Usage
+
+	// FinishReason is the normalized stop reason (finish tokens only)
+	FinishReason FinishReason
+
+	// Metadata contains provider-specific metadata, including the response ID
+	// ("response_id"), model ("model") and creation time ("created", Unix seconds)
 	Metadata map[string]interface{}
 }
 
@@ -42,8 +68,31 @@ type StreamConfig struct {
 	// BufferSize is the size of the token buffer
 	BufferSize int
 
-	// RetryStrategy defines how to handle stream interruptions
+	// RetryStrategy defines how to handle interruptions of an opened stream.
+	// Requests that fail before the first byte follow the LLM's retry policy.
 	RetryStrategy RetryStrategy
+
+	// ResumePartial allows recovering from an interruption after tokens have been
+	// emitted by re-requesting with the partial assistant text as a prefill.
+	// Without it, only interruptions before the first token are recovered.
+	ResumePartial bool
+}
+
+// WithRetryStrategy sets the strategy used to recover from stream interruptions.
+func WithRetryStrategy(strategy RetryStrategy) StreamOption {
+	return func(c *StreamConfig) {
+		c.RetryStrategy = strategy
+	}
+}
+
+// WithResumePartial enables resuming an interrupted stream from the text already
+// received. The provider is asked to continue the partial assistant message, which
+// works best with providers that support assistant prefill (e.g. Anthropic).
+// Streams interrupted during a tool call are not resumed.
+func WithResumePartial() StreamOption {
+	return func(c *StreamConfig) {
+		c.ResumePartial = true
+	}
 }
 
 // RetryStrategy defines how to handle stream interruptions.
@@ -66,8 +115,9 @@ type DefaultRetryStrategy struct {
 	attempts    int
 }
 
+// ShouldRetry allows up to MaxRetries recoveries from retryable errors.
 func (s *DefaultRetryStrategy) ShouldRetry(err error) bool {
-	return s.attempts < s.MaxRetries
+	return s.attempts < s.MaxRetries && retry.IsRetryable(err)
 }
 
 func (s *DefaultRetryStrategy) NextDelay() time.Duration {
@@ -0,0 +1,393 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/YspCoder/omnigo/adapter"
+	"github.com/YspCoder/omnigo/config"
+	"github.com/YspCoder/omnigo/ratelimit"
+	"github.com/YspCoder/omnigo/utils"
+)
+
+func collectTokens(t *testing.T, stream TokenStream) []*StreamToken {
+	t.Helper()
+	var tokens []*StreamToken
+	for {
+		token, err := stream.Next(context.Background())
+		if errors.Is(err, io.EOF) {
+			return tokens
+		}
+		if err != nil {
+			t.Fatalf("unexpected stream error: %v", err)
+		}
+		tokens = append(tokens, token)
+	}
+}
+
+func newTestStream(body string, parser adapter.StreamAdaptor) TokenStream {
+	return newProviderStream(io.NopCloser(strings.NewReader(body)), parser, "test", &StreamConfig{
+		RetryStrategy: &DefaultRetryStrategy{},
+	})
+}
+
+func TestProviderStreamOpenAIToolCallDeltas(t *testing.T) {
+	body := strings.Join([]string{
+		`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+		`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}`,
+		`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]}}]}`,
+		`data: {"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		`data: {"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`,
+		`data: [DONE]`,
+		``,
+	}, "\n\n")
+
+	tokens := collectTokens(t, newTestStream(body, &adapter.OpenAIAdaptor{}))
+	if len(tokens) != 5 {
+		t.Fatalf("expected 5 tokens, got %d", len(tokens))
+	}
+
+	last := tokens[2]
+	if last.Type != TokenTypeToolCall || last.ToolCall == nil {
+		t.Fatalf("expected tool_call token, got %+v", last)
+	}
+	if last.ToolCall.ID != "call_1" || last.ToolCall.Function.Name != "get_weather" {
+		t.Fatalf("unexpected tool call: %+v", last.ToolCall)
+	}
+	if string(last.ToolCall.Function.Arguments) != `{"city":"Paris"}` {
+		t.Fatalf("unexpected accumulated arguments: %s", last.ToolCall.Function.Arguments)
+	}
+	if last.ArgumentsDelta != `"Paris"}` {
+		t.Fatalf("unexpected arguments delta: %q", last.ArgumentsDelta)
+	}
+	if last.Metadata["provider"] != "test" || last.Metadata["tool_call_index"] != 0 {
+		t.Fatalf("unexpected metadata: %+v", last.Metadata)
+	}
+
+	if tokens[3].Type != TokenTypeFinish || tokens[3].FinishReason != FinishReasonToolCalls {
+		t.Fatalf("unexpected finish token: %+v", tokens[3])
+	}
+	if tokens[4].Type != TokenTypeUsage || tokens[4].Usage.TotalTokens != 15 {
+		t.Fatalf("unexpected usage token: %+v", tokens[4])
+	}
+	for i, token := range tokens {
+		if token.Index != i {
+			t.Fatalf("token %d has index %d", i, token.Index)
+		}
+	}
+}
+
+func TestProviderStreamToolCallSlots(t *testing.T) {
+	// DashScope repeats the name and ID of a call on each delta, including a
+	// trailing one after the arguments are complete.
+	dashscope := strings.Join([]string{
+		`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_a","type":"function","function":{"name":"get_weather","arguments":"{\"city\":"}}]}}]}`,
+		`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_a","type":"function","function":{"name":"get_weather","arguments":"\"Paris\"}"}}]}}]}`,
+		`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_a","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+		`data: [DONE]`,
+		``,
+	}, "\n\n")
+	tokens := collectTokens(t, newTestStream(dashscope, &adapter.OpenAIAdaptor{}))
+	for _, token := range tokens {
+		if token.Metadata["tool_call_index"] != 0 {
+			t.Fatalf("expected a single call, got %+v", token.Metadata)
+		}
+	}
+	if last := tokens[len(tokens)-1].ToolCall; last.ID != "call_a" || string(last.Function.Arguments) != `{"city":"Paris"}` {
+		t.Fatalf("unexpected accumulated call %+v", last)
+	}
+
+	// Gemini sends each call whole and reuses part index 0 for the next one.
+	gemini := strings.Join([]string{
+		`data: {"candidates":[{"content":{"parts":[{"functionCall":{"name":"get_weather","args":{"city":"Paris"}}}]}}]}`,
+		`data: {"candidates":[{"content":{"parts":[{"functionCall":{"name":"get_time","args":{"zone":"CET"}}}]}}]}`,
+		``,
+	}, "\n\n")
+	tokens = collectTokens(t, newTestStream(gemini, &adapter.GoogleAdaptor{}))
+	var calls []*ToolCall
+	for _, token := range tokens {
+		if token.Type == TokenTypeToolCall {
+			calls = append(calls, token.ToolCall)
+		}
+	}
+	if len(calls) != 2 || calls[0].ID == calls[1].ID || calls[1].Function.Name != "get_time" || string(calls[1].Function.Arguments) != `{"zone":"CET"}` {
+		t.Fatalf("expected two separate calls, got %+v", calls)
+	}
+}
+
+func TestProviderStreamAnthropicEvents(t *testing.T) {
+	body := strings.Join([]string{
+		"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"model\":\"claude-sonnet-4\",\"usage\":{\"input_tokens\":12}}}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Checking\"}}",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"tool_use\",\"id\":\"toolu_1\",\"name\":\"lookup\"}}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{\\\"q\\\":\\\"go\\\"}\"}}",
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"tool_use\"},\"usage\":{\"output_tokens\":7}}",
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}",
+		"",
+	}, "\n\n")
+
+	tokens := collectTokens(t, newTestStream(body, &adapter.AnthropicAdaptor{}))
+	if len(tokens) != 6 {
+		t.Fatalf("expected 6 tokens, got %d", len(tokens))
+	}
+	if tokens[1].Text != "Checking" || tokens[1].Metadata["event"] != "content_block_delta" ||
+		tokens[1].Metadata["response_id"] != "msg_1" || tokens[1].Metadata["model"] != "claude-sonnet-4" {
+		t.Fatalf("unexpected text token: %+v", tokens[1])
+	}
+	call := tokens[3].ToolCall
+	if call == nil || call.ID != "toolu_1" || call.Function.Name != "lookup" || string(call.Function.Arguments) != `{"q":"go"}` {
+		t.Fatalf("unexpected tool call: %+v", call)
+	}
+	if tokens[4].FinishReason != FinishReasonToolCalls {
+		t.Fatalf("unexpected finish reason: %s", tokens[4].FinishReason)
+	}
+	usage := tokens[5].Usage
+	if usage.PromptTokens != 12 || usage.CompletionTokens != 7 || usage.TotalTokens != 19 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestProviderStreamErrorEvent(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		parser adapter.StreamAdaptor
+		data   string
+		want   error
+		code   string
+	}{
+		{"openai without code", &adapter.OpenAIAdaptor{}, `{"error":{"message":"overloaded"}}`, nil, ""},
+		{"openai", &adapter.OpenAIAdaptor{}, `{"error":{"message":"too long","type":"invalid_request_error","code":"context_length_exceeded"}}`, ErrContextLengthExceeded, "context_length_exceeded"},
+		{"anthropic", &adapter.AnthropicAdaptor{}, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, ErrOverloaded, "overloaded_error"},
+		{"gemini", &adapter.GoogleAdaptor{}, `{"candidates":[{"content":{"parts":[]},"finishReason":"SAFETY"}]}`, ErrContentFiltered, "SAFETY"},
+		{"dashscope", &adapter.AliAdaptor{}, `{"code":"Throttling.RateQuota","message":"Requests rate limit exceeded"}`, ErrRateLimit, "Throttling.RateQuota"},
+	} {
+		stream := newTestStream("data: "+tc.data+"\n\n", tc.parser)
+		var err error
+		for err == nil {
+			_, err = stream.Next(context.Background())
+		}
+		var llmErr *LLMError
+		if !errors.As(err, &llmErr) || llmErr.Code != tc.code || llmErr.Provider != "test" {
+			t.Fatalf("%s: expected an LLMError with code %q, got %v", tc.name, tc.code, err)
+		}
+		if (tc.want == nil && llmErr.Type != ErrorTypeAPI) || (tc.want != nil && !errors.Is(err, tc.want)) {
+			t.Fatalf("%s: unexpected error type %s", tc.name, llmErr.TypeString())
+		}
+	}
+}
+
+func TestCollectStreamRebuildsResponse(t *testing.T) {
+	body := strings.Join([]string{
+		`data: {"id":"chatcmpl-1","model":"gpt-4o-mini-2024","created":1700000000,"choices":[{"index":0,"delta":{"role":"assistant"}}]}`,
+		`data: {"choices":[{"index":0,"delta":{"content":"Hello"}}]}`,
+		`data: {"choices":[{"index":0,"delta":{"content":" world"}}]}`,
+		`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"echo","arguments":"{\"x\":1}"}}]}}]}`,
+		`data: {"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		`data: {"choices":[],"usage":{"prompt_tokens":3,"completion_tokens":4,"total_tokens":7}}`,
+		`data: [DONE]`,
+		``,
+	}, "\n\n")
+
+	var forwarded int
+	response, err := CollectStream(context.Background(), newTestStream(body, &adapter.OpenAIAdaptor{}), func(*StreamToken) error {
+		forwarded++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("CollectStream returned error: %v", err)
+	}
+	if forwarded != 5 {
+		t.Fatalf("expected 5 forwarded tokens, got %d", forwarded)
+	}
+
+	choice := response.Choices[0]
+	if choice.Message.Content != "Hello world" || choice.FinishReason != "tool_calls" {
+		t.Fatalf("unexpected choice: %+v", choice)
+	}
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].Function.Arguments != `{"x":1}` {
+		t.Fatalf("unexpected tool calls: %+v", choice.Message.ToolCalls)
+	}
+	if response.Usage.TotalTokens != 7 {
+		t.Fatalf("unexpected usage: %+v", response.Usage)
+	}
+	if response.ID != "chatcmpl-1" || response.Model != "gpt-4o-mini-2024" || response.Created != 1700000000 {
+		t.Fatalf("unexpected response metadata: %q %q %d", response.ID, response.Model, response.Created)
+	}
+}
+
+func TestCollectStreamCallbackError(t *testing.T) {
+	body := "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hi\"}}]}\n\ndata: [DONE]\n\n"
+	stop := errors.New("client gone")
+	response, err := CollectStream(context.Background(), newTestStream(body, &adapter.OpenAIAdaptor{}), func(*StreamToken) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected callback error, got %v", err)
+	}
+	if response == nil {
+		t.Fatal("expected partial response on error")
+	}
+}
+
+// brokenReader returns its data and then fails like a dropped connection.
+func brokenReader(data string) io.ReadCloser {
+	return io.NopCloser(io.MultiReader(strings.NewReader(data), iotest.ErrReader(io.ErrUnexpectedEOF)))
+}
+
+func TestProviderStreamResumesAfterInterruption(t *testing.T) {
+	chunk := func(text string) string {
+		return `data: {"choices":[{"index":0,"delta":{"content":"` + text + `"}}]}` + "\n\n"
+	}
+	var partials []string
+	stream := newProviderStream(brokenReader(chunk("Hel")), &adapter.OpenAIAdaptor{}, "test", &StreamConfig{
+		RetryStrategy: &DefaultRetryStrategy{MaxRetries: 2},
+		ResumePartial: true,
+	})
+	stream.reconnect = func(ctx context.Context, partial string) (io.ReadCloser, error) {
+		partials = append(partials, partial)
+		if len(partials) == 1 {
+			return brokenReader(""), nil
+		}
+		return io.NopCloser(strings.NewReader(chunk("lo") + "data: [DONE]\n\n")), nil
+	}
+
+	var text strings.Builder
+	for _, token := range collectTokens(t, stream) {
+		text.WriteString(token.Text)
+	}
+	if text.String() != "Hello" {
+		t.Fatalf("expected resumed text %q, got %q", "Hello", text.String())
+	}
+	if len(partials) != 2 || partials[0] != "Hel" || partials[1] != "Hel" {
+		t.Fatalf("expected resumes from the partial text, got %q", partials)
+	}
+}
+
+func TestProviderStreamWithoutResumeSurfacesInterruption(t *testing.T) {
+	stream := newProviderStream(brokenReader(`data: {"choices":[{"index":0,"delta":{"content":"Hel"}}]}`+"\n\n"), &adapter.OpenAIAdaptor{}, "test", &StreamConfig{
+		RetryStrategy: &DefaultRetryStrategy{MaxRetries: 2},
+	})
+	stream.reconnect = func(ctx context.Context, partial string) (io.ReadCloser, error) {
+		t.Fatal("stream should not be re-requested after tokens were emitted")
+		return nil, nil
+	}
+
+	if _, err := stream.Next(context.Background()); err != nil {
+		t.Fatalf("unexpected error for the first token: %v", err)
+	}
+	if _, err := stream.Next(context.Background()); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected the transport error, got %v", err)
+	}
+}
+
+func TestStreamRetriesFailedRequest(t *testing.T) {
+	var calls int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			// Drop the connection before any byte is sent.
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			_ = conn.Close()
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error":{"message":"busy","type":"server_error"}}`))
+		default:
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"ok\"}}]}\n\ndata: [DONE]\n\n"))
+		}
+	}
+	newModel := func(retries int, delay time.Duration) LLM {
+		return newTestLLM(t, handler, config.SetMaxRetries(retries), config.SetRetryDelay(delay))
+	}
+
+	// Failed requests follow the retry policy; the stream strategy is left untouched.
+	strategy := &DefaultRetryStrategy{MaxRetries: 1}
+	stream, err := newModel(2, time.Millisecond).Stream(context.Background(), NewPrompt("hello"), WithRetryStrategy(strategy))
+	if err != nil {
+		t.Fatalf("expected the stream request to be retried, got %v after %d calls", err, atomic.LoadInt32(&calls))
+	}
+	defer stream.Close()
+	if tokens := collectTokens(t, stream); len(tokens) != 1 || tokens[0].Text != "ok" || atomic.LoadInt32(&calls) != 3 {
+		t.Fatalf("unexpected tokens %+v after %d calls", tokens, atomic.LoadInt32(&calls))
+	}
+	if !strategy.ShouldRetry(io.ErrUnexpectedEOF) {
+		t.Fatal("expected failed requests not to use up the interruption retries")
+	}
+
+	// Without retries the first failure is returned.
+	atomic.StoreInt32(&calls, 0)
+	if _, err := newModel(0, time.Millisecond).Stream(context.Background(), NewPrompt("hello")); err == nil || atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("expected the dropped connection to be returned, got %v after %d calls", err, atomic.LoadInt32(&calls))
+	}
+
+	// The context bounds the wait for the next attempt.
+	atomic.StoreInt32(&calls, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = newModel(5, time.Hour).Stream(ctx, NewPrompt("hello"))
+	if !errors.Is(err, context.DeadlineExceeded) || atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("expected the wait to end with the context, got %v after %d calls", err, atomic.LoadInt32(&calls))
+	}
+}
+
+func TestStreamUsageCorrectsRateLimiter(t *testing.T) {
+	limiter := ratelimit.New(ratelimit.Limits{TokensPerMinute: 1000})
+	model := newTestLLM(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"ok\"},\"finish_reason\":\"stop\"}]," +
+			"\"usage\":{\"prompt_tokens\":4000,\"completion_tokens\":1000,\"total_tokens\":5000}}\n\ndata: [DONE]\n\n"))
+	}, config.SetRateLimiter(limiter))
+	stream, err := model.Stream(context.Background(), NewPrompt("hello"))
+	if err != nil {
+		t.Fatalf("Stream returned error: %v", err)
+	}
+	collectTokens(t, stream)
+	_ = stream.Close()
+
+	// The 5000 tokens used exceed the per-minute budget, so the next request waits.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the stream usage to be charged to the limiter, got %v", err)
+	}
+}
+
+func TestSharedRateLimiterKeyedByCredential(t *testing.T) {
+	newLimited := func(apiKey string, rpm int) (LLM, error) {
+		cfg := config.NewConfig()
+		config.ApplyOptions(cfg,
+			config.SetProvider("openai"),
+			config.SetModel("gpt-4o-mini"),
+			config.SetAPIKey(apiKey),
+			config.SetEndpoint("http://127.0.0.1:1"),
+			config.SetRateLimit(rpm, 0),
+		)
+		return NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), adapter.NewRegistry())
+	}
+	first, err := newLimited("key-shared-a", 10)
+	if err != nil {
+		t.Fatalf("NewLLM returned error: %v", err)
+	}
+	same, err := newLimited("key-shared-a", 10)
+	if err != nil {
+		t.Fatalf("NewLLM returned error: %v", err)
+	}
+	other, err := newLimited("key-shared-b", 10)
+	if err != nil {
+		t.Fatalf("NewLLM returned error: %v", err)
+	}
+	limiter := func(model LLM) *ratelimit.Limiter { return model.(*LLMImpl).adaptorCfg.Limiter }
+	if limiter(first) != limiter(same) || limiter(first) == limiter(other) {
+		t.Fatal("expected the limiter to be shared per credential")
+	}
+	if _, err := newLimited("key-shared-a", 20); err == nil {
+		t.Fatal("expected conflicting limits for the same quota to be rejected")
+	}
+}
@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/YspCoder/omnigo/config"
+	"github.com/YspCoder/omnigo/telemetry"
+)
+
+func TestTelemetryRecordsSpansAndMetrics(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	model := newTestLLM(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `"stream":true`) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"hi\"}}]}\n\n" +
+				"data: {\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":1,\"total_tokens\":4}}\n\n" +
+				"data: [DONE]\n\n"))
+			return
+		}
+		_, _ = w.Write([]byte(`{"model":"gpt-4o-mini-2024","choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":2,"total_tokens":7}}`))
+	}, config.SetTelemetry(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)), sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+
+	if _, err := model.Generate(context.Background(), NewPrompt("hello")); err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	stream, err := model.Stream(context.Background(), NewPrompt("hello"))
+	if err != nil {
+		t.Fatalf("Stream returned error: %v", err)
+	}
+	for {
+		if _, err := stream.Next(context.Background()); err != nil {
+			break
+		}
+	}
+	// The span already ended on EOF; Close must not end it again.
+	_ = stream.Close()
+	abandoned, err := model.Stream(context.Background(), NewPrompt("hello"))
+	if err != nil {
+		t.Fatalf("Stream returned error: %v", err)
+	}
+	_ = abandoned.Close()
+	_ = abandoned.Close()
+
+	ended := spans.Ended()
+	if len(ended) != 6 {
+		t.Fatalf("expected 3 operation and 3 HTTP spans, got %d", len(ended))
+	}
+	httpSpan, chatSpan := ended[0], ended[1]
+	if chatSpan.Name() != "chat gpt-4o-mini" || httpSpan.Name() != http.MethodPost {
+		t.Fatalf("unexpected span names %q and %q", chatSpan.Name(), httpSpan.Name())
+	}
+	if httpSpan.Parent().SpanID() != chatSpan.SpanContext().SpanID() {
+		t.Fatal("expected the HTTP span to be a child of the operation span")
+	}
+	attrs := attribute.NewSet(chatSpan.Attributes()...)
+	for key, want := range map[attribute.Key]attribute.Value{
+		telemetry.AttrProviderName:  attribute.StringValue("openai"),
+		telemetry.AttrResponseModel: attribute.StringValue("gpt-4o-mini-2024"),
+		telemetry.AttrInputTokens:   attribute.IntValue(5),
+		telemetry.AttrOutputTokens:  attribute.IntValue(2),
+		telemetry.AttrFinishReasons: attribute.StringSliceValue([]string{"stop"}),
+	} {
+		if got, ok := attrs.Value(key); !ok || got != want {
+			t.Fatalf("attribute %s = %v, want %v", key, got.Emit(), want.Emit())
+		}
+	}
+	streamAttrs := attribute.NewSet(ended[3].Attributes()...)
+	if got, _ := streamAttrs.Value(telemetry.AttrOutputTokens); got.AsInt64() != 1 {
+		t.Fatalf("expected stream output tokens on the span, got %v", got.Emit())
+	}
+	if ended[5].Name() != "stream gpt-4o-mini" || ended[5].Status().Code == codes.Error {
+		t.Fatalf("expected the closed stream to end its span without error, got %q %+v", ended[5].Name(), ended[5].Status())
+	}
+
+	var metrics metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &metrics); err != nil {
+		t.Fatalf("Collect returned error: %v", err)
+	}
+	recorded := make(map[string]bool)
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			recorded[m.Name] = true
+		}
+	}
+	for _, name := range []string{telemetry.MetricOperationDuration, telemetry.MetricTokenUsage, telemetry.MetricTimeToFirstChunk} {
+		if !recorded[name] {
+			t.Fatalf("expected metric %s to be recorded", name)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/YspCoder/omnigo/config"
+	"github.com/YspCoder/omnigo/tokenizer"
+)
+
+// Chat formats wrap every message in a few control tokens and prime the reply with
+// the assistant role. The values follow OpenAI's published counting guide and are a
+// close enough overhead for other providers.
+const (
+	messageTokenOverhead = 3
+	replyTokenOverhead   = 3
+	// mediaPartTokens is charged for every non-text content part, matching a
+	// low-detail image.
+	mediaPartTokens = 85
+)
+
+// CountMessageTokens counts the tokens occupied by messages, including the
+// per-message overhead of the chat format.
+func CountMessageTokens(t tokenizer.Tokenizer, messages []PromptMessage) int {
+	total := 0
+	for _, msg := range messages {
+		total += messageTokenOverhead + t.Count(msg.Role) + t.Count(msg.Content)
+		if msg.Name != "" {
+			total += t.Count(msg.Name) + 1
+		}
+		for _, call := range msg.ToolCalls {
+			total += t.Count(call.Function.Name) + t.Count(string(call.Function.Arguments))
+		}
+		for _, part := range msg.Parts {
+			if part.Type == ContentPartText {
+				total += t.Count(part.Text)
+			} else {
+				total += mediaPartTokens
+			}
+		}
+	}
+	return total
+}
+
+// CountPromptTokens counts the tokens prompt occupies in the context window: the
+// system prompt, the messages (or the rendered prompt when it has none) and the
+// tool definitions. Output tokens are not included.
+//
+// Parameters:
+//   - t: The tokenizer of the target model
+//   - prompt: The prompt to measure
+//
+// Returns:
+//   - The number of input tokens
+func CountPromptTokens(t tokenizer.Tokenizer, prompt *Prompt) int {
+	messages := prompt.Messages
+	if len(messages) == 0 {
+		messages = []PromptMessage{{Role: "user", Content: prompt.String()}}
+	}
+	total := CountMessageTokens(t, messages) + replyTokenOverhead
+	if prompt.SystemPrompt != "" {
+		total += CountMessageTokens(t, []PromptMessage{{Role: "system", Content: prompt.SystemPrompt}})
+	}
+	if len(prompt.Tools) > 0 {
+		if definitions, err := json.Marshal(prompt.Tools); err == nil {
+			total += t.Count(string(definitions))
+		}
+	}
+	return total
+}
+
+// newTokenizer returns the configured tokenizer, a BPE tokenizer loaded from
+// TokenizerFile, or the approximate counter.
+func newTokenizer(cfg *config.Config) (tokenizer.Tokenizer, error) {
+	if cfg.Tokenizer != nil {
+		return cfg.Tokenizer, nil
+	}
+	if cfg.TokenizerFile == "" {
+		return tokenizer.Approximate{}, nil
+	}
+	encoding := tokenizer.EncodingForModel(cfg.Model)
+	if encoding == "" {
+		return nil, fmt.Errorf("no BPE encoding known for model %q; use SetTokenizer instead", cfg.Model)
+	}
+	return tokenizer.LoadBPEFile(cfg.TokenizerFile, encoding)
+}
+
+// CountTokens returns the number of input tokens the prompt occupies for this
+// model, as counted by the configured tokenizer.
+func (l *LLMImpl) CountTokens(prompt *Prompt) int {
+	return CountPromptTokens(l.tokenizer, prompt)
+}
+
+// fitContext checks the prompt against the context window, leaving room for the
+// output limit sent with the request (see maxOutputTokens). A prompt that does not fit is shortened with its
+// truncation strategy, if any; the caller's prompt is never modified.
+//
+// Returns:
+//   - The prompt to send
+//   - ErrorTypeContextLength if the prompt does not fit
+//   - ErrorTypeRequest if the truncation strategy fails
+func (l *LLMImpl) fitContext(ctx context.Context, prompt *Prompt) (*Prompt, error) {
+	window := l.contextWindow()
+	if window <= 0 {
+		return prompt, nil
+	}
+	reserved := l.maxOutputTokens()
+	budget := window - reserved
+	withMessages := func(messages []PromptMessage) *Prompt {
+		candidate := *prompt
+		candidate.Messages = messages
+		return &candidate
+	}
+	fits := func(messages []PromptMessage) bool {
+		return CountPromptTokens(l.tokenizer, withMessages(messages)) <= budget
+	}
+
+	tokens := l.CountTokens(prompt)
+	if tokens <= budget {
+		return prompt, nil
+	}
+	if prompt.Truncation != nil && len(prompt.Messages) > 0 {
+		messages, err := prompt.Truncation.Truncate(ctx, append([]PromptMessage(nil), prompt.Messages...), fits)
+		if err != nil {
+			return nil, NewLLMError(ErrorTypeRequest, "failed to truncate prompt", err)
+		}
+		truncated := withMessages(messages)
+		if count := l.CountTokens(truncated); count <= budget {
+			l.log(ctx).Debug("Truncated prompt to fit the context window",
+				"tokens", tokens, "truncated_tokens", count, "messages", len(prompt.Messages), "kept_messages", len(messages))
+			return truncated, nil
+		}
+	}
+	return nil, NewLLMError(ErrorTypeContextLength, fmt.Sprintf(
+		"prompt needs %d tokens plus %d for output, but the context window of %s is %d tokens",
+		tokens, reserved, l.config.Model, window), nil)
+}
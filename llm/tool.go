@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/YspCoder/omnigo/utils"
+	"github.com/invopop/jsonschema"
+)
+
+// FunctionTool pairs a tool declaration with the handler that executes it.
+// Instances are usually created with NewTool and registered on a Runner.
+type FunctionTool struct {
+	Tool    utils.Tool  // Declaration sent to the LLM
+	Handler ToolHandler // Executes calls to the tool
+}
+
+// NewTool creates a typed tool whose parameter schema is derived from T.
+// The model's arguments are decoded into T and validated with the package
+// validator (see Validate) before fn is called. The value returned by fn is
+// sent back to the model as-is when it is a string and JSON-encoded otherwise.
+//
+// Parameters:
+//   - name: Function name exposed to the LLM
+//   - description: Human-readable description of what the tool does
+//   - fn: Implementation receiving the decoded arguments
+//
+// Returns:
+//   - Function tool ready to be registered with WithFunctionTools
+//   - Error if T is not a struct or its schema cannot be generated
+//
+// Example:
+//
+//	type WeatherArgs struct {
+//	    City string `json:"city" validate:"required"`
+//	}
+//
+//	weather, err := NewTool("get_weather", "Get the current weather",
+//	    func(ctx context.Context, args WeatherArgs) (string, error) {
+//	        return "sunny in " + args.City, nil
+//	    })
+func NewTool[T any, R any](name, description string, fn func(context.Context, T) (R, error)) (*FunctionTool, error) {
+	if name == "" {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "tool name is required", nil)
+	}
+	if fn == nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "tool function is required", nil)
+	}
+
+	schema, err := toolSchema(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("failed to generate schema for tool %s", name), err)
+	}
+
+	handler := func(ctx context.Context, arguments json.RawMessage) (string, error) {
+		var args T
+		if len(arguments) > 0 && string(arguments) != "null" {
+			if err := json.Unmarshal(arguments, &args); err != nil {
+				return "", fmt.Errorf("invalid arguments: %w", err)
+			}
+		}
+		if err := validateToolArguments(args); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+
+		result, err := fn(ctx, args)
+		if err != nil {
+			return "", err
+		}
+		if text, ok := any(result).(string); ok {
+			return text, nil
+		}
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode tool result: %w", err)
+		}
+		return string(encoded), nil
+	}
+
+	return &FunctionTool{
+		Tool: utils.Tool{
+			Type: "function",
+			Function: utils.Function{
+				Name:        name,
+				Description: description,
+				Parameters:  schema,
+			},
+		},
+		Handler: handler,
+	}, nil
+}
+
+// WithFunctionTools registers typed tools created with NewTool on a Runner.
+func WithFunctionTools(tools ...*FunctionTool) RunnerOption {
+	return func(r *Runner) {
+		for _, tool := range tools {
+			if tool == nil {
+				continue
+			}
+			WithRunnerTool(tool.Tool, tool.Handler)(r)
+		}
+	}
+}
+
+// toolSchema builds the parameter schema for an argument struct. The package's own
+// GenerateJSONSchema is preferred because it honours validate tags; types it cannot
+// describe (maps, pointers, interfaces) fall back to the jsonschema reflector.
+func toolSchema(t reflect.Type) (map[string]interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("tool arguments must be a struct, got %v", t)
+	}
+
+	raw, err := GenerateJSONSchema(reflect.New(t).Elem().Interface())
+	if err != nil {
+		reflector := &jsonschema.Reflector{DoNotReference: true, ExpandedStruct: true}
+		raw, err = json.Marshal(reflector.ReflectFromType(t))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil, err
+	}
+	delete(schema, "$schema")
+	delete(schema, "$id")
+	return schema, nil
+}
+
+// validateToolArguments runs struct validation on decoded arguments.
+func validateToolArguments(args interface{}) error {
+	value := reflect.ValueOf(args)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	return validate.Struct(value.Interface())
+}
@@ -0,0 +1,44 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type weatherArgs struct {
+	City  string `json:"city" validate:"required"`
+	Units string `json:"units,omitempty"`
+}
+
+func TestNewToolDerivesSchemaAndValidatesArguments(t *testing.T) {
+	tool, err := NewTool("get_weather", "Get the weather",
+		func(ctx context.Context, args weatherArgs) (map[string]string, error) {
+			return map[string]string{"city": args.City, "sky": "clear"}, nil
+		})
+	if err != nil {
+		t.Fatalf("NewTool returned error: %v", err)
+	}
+
+	params := tool.Tool.Function.Parameters
+	if params["type"] != "object" {
+		t.Fatalf("expected object schema, got %+v", params)
+	}
+	required, _ := params["required"].([]interface{})
+	if len(required) != 1 || required[0] != "city" {
+		t.Fatalf("expected city to be required, got %+v", params["required"])
+	}
+
+	output, err := tool.Handler(context.Background(), json.RawMessage(`{"city":"Paris"}`))
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if output != `{"city":"Paris","sky":"clear"}` {
+		t.Fatalf("unexpected output: %s", output)
+	}
+
+	if _, err := tool.Handler(context.Background(), json.RawMessage(`{}`)); err == nil || !strings.Contains(err.Error(), "invalid arguments") {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+}
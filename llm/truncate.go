@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// TruncationStrategy shortens Prompt.Messages when a prompt does not fit the
+// model's context window. Strategies keep system messages and never start the
+// remaining history with tool results whose assistant message was removed.
+type TruncationStrategy interface {
+	// Truncate returns the messages to send. fits reports whether a candidate
+	// message list fits the context window together with the rest of the prompt.
+	Truncate(ctx context.Context, messages []PromptMessage, fits func([]PromptMessage) bool) ([]PromptMessage, error)
+}
+
+// TruncationFunc adapts a function to the TruncationStrategy interface.
+type TruncationFunc func(ctx context.Context, messages []PromptMessage, fits func([]PromptMessage) bool) ([]PromptMessage, error)
+
+// Truncate calls f.
+func (f TruncationFunc) Truncate(ctx context.Context, messages []PromptMessage, fits func([]PromptMessage) bool) ([]PromptMessage, error) {
+	return f(ctx, messages, fits)
+}
+
+// WithTruncation sets the strategy applied when the prompt exceeds the context
+// window configured with SetContextWindow.
+func WithTruncation(strategy TruncationStrategy) PromptOption {
+	return func(p *Prompt) {
+		p.Truncation = strategy
+	}
+}
+
+// DropOldest removes the oldest non-system messages until the prompt fits.
+// The latest message is always kept.
+func DropOldest() TruncationStrategy {
+	return TruncationFunc(func(_ context.Context, messages []PromptMessage, fits func([]PromptMessage) bool) ([]PromptMessage, error) {
+		system, history := splitSystemMessages(messages)
+		var kept []PromptMessage
+		for start := 1; start < len(history); start++ {
+			if history[start].Role == "tool" {
+				continue
+			}
+			kept = append(append([]PromptMessage(nil), system...), history[start:]...)
+			if fits(kept) {
+				break
+			}
+		}
+		if kept == nil {
+			return messages, nil
+		}
+		return kept, nil
+	})
+}
+
+// KeepSystemAndLastN keeps the system messages and the last n other messages.
+// The window is extended backwards when it would begin with tool results, so they
+// keep the assistant message that requested them.
+func KeepSystemAndLastN(n int) TruncationStrategy {
+	return TruncationFunc(func(_ context.Context, messages []PromptMessage, _ func([]PromptMessage) bool) ([]PromptMessage, error) {
+		system, history := splitSystemMessages(messages)
+		start := historyStart(history, n)
+		return append(system, history[start:]...), nil
+	})
+}
+
+// summarySystemPrompt instructs the model used by SummarizeOldest.
+const summarySystemPrompt = "Summarize the following conversation in a few sentences. " +
+	"Keep names, numbers, decisions and open questions. Reply with the summary only."
+
+// SummarizeOldest replaces all but the last keepLast non-system messages with a
+// summary written by model, typically a smaller and cheaper one. The summary is
+// sent as a system message.
+//
+// Parameters:
+//   - model: The LLM that writes the summary
+//   - keepLast: Number of recent messages kept verbatim
+//
+// Returns:
+//   - A strategy whose errors are those of model.Generate
+func SummarizeOldest(model LLM, keepLast int) TruncationStrategy {
+	return TruncationFunc(func(ctx context.Context, messages []PromptMessage, _ func([]PromptMessage) bool) ([]PromptMessage, error) {
+		system, history := splitSystemMessages(messages)
+		start := historyStart(history, keepLast)
+		if start == 0 {
+			return messages, nil
+		}
+		summary, err := model.Generate(ctx, NewPrompt(transcript(history[:start]),
+			WithSystemPrompt(summarySystemPrompt, "")))
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize conversation: %w", err)
+		}
+		summarized := append(system, PromptMessage{
+			Role:    "system",
+			Content: "Summary of the earlier conversation:\n" + strings.TrimSpace(summary),
+		})
+		return append(summarized, history[start:]...), nil
+	})
+}
+
+// splitSystemMessages separates system messages from the rest of the history,
+// preserving the order of both.
+func splitSystemMessages(messages []PromptMessage) ([]PromptMessage, []PromptMessage) {
+	var system, history []PromptMessage
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			system = append(system, msg)
+		} else {
+			history = append(history, msg)
+		}
+	}
+	return system, history
+}
+
+// historyStart returns the index of the first of the last n messages, moved back
+// past tool results to the assistant message that requested them. It returns
+// len(history) when n is 0, so that no message is kept.
+func historyStart(history []PromptMessage, n int) int {
+	start := len(history) - n
+	if start <= 0 {
+		return 0
+	}
+	if start >= len(history) {
+		return len(history)
+	}
+	for start > 0 && history[start].Role == "tool" {
+		start--
+	}
+	return start
+}
+
+// transcript renders messages as plain text for summarization.
+func transcript(messages []PromptMessage) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		content := msg.Content
+		for _, call := range msg.ToolCalls {
+			content += fmt.Sprintf(" [called %s(%s)]", call.Function.Name, call.Function.Arguments)
+		}
+		fmt.Fprintf(&b, "%s: %s\n", msg.Role, strings.TrimSpace(content))
+	}
+	return b.String()
+}
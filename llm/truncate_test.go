@@ -0,0 +1,107 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/YspCoder/omnigo/config"
+)
+
+func TestContextWindowTruncatesOrRejects(t *testing.T) {
+	var sent []string
+	model := newTestLLM(t, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		for _, msg := range req.Messages {
+			sent = append(sent, msg.Content)
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	},
+		config.SetMaxTokens(10),
+		config.SetContextWindow(60),
+	)
+
+	long := strings.Repeat("word ", 20) // 25 tokens with the approximate counter
+	history := []PromptMessage{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: long},
+		{Role: "assistant", Content: long},
+		{Role: "user", Content: "and now?"},
+	}
+
+	_, err := model.Generate(context.Background(), NewPrompt("", WithMessages(history)))
+	if !errors.Is(err, ErrContextLengthExceeded) {
+		t.Fatalf("expected a context length error before sending, got %v", err)
+	}
+	if len(sent) != 0 {
+		t.Fatalf("oversized prompt was sent: %q", sent)
+	}
+
+	if _, err := model.Generate(context.Background(), NewPrompt("", WithMessages(history), WithTruncation(DropOldest()))); err != nil {
+		t.Fatalf("Generate with DropOldest returned error: %v", err)
+	}
+	if want := []string{"be brief", long, "and now?"}; strings.Join(sent, "|") != strings.Join(want, "|") {
+		t.Fatalf("sent messages = %q, want %q", sent, want)
+	}
+
+	sent = nil
+	if _, err := model.GenerateWithSchema(context.Background(), NewPrompt("", WithMessages(history)), map[string]interface{}{"type": "object"}); !errors.Is(err, ErrContextLengthExceeded) {
+		t.Fatalf("expected a context length error from GenerateWithSchema, got %v", err)
+	}
+	if len(sent) != 0 {
+		t.Fatalf("oversized schema prompt was sent: %q", sent)
+	}
+
+	// The output reserve follows the output limit set as an option.
+	model.SetOption("max_completion_tokens", 55)
+	if _, err := model.Generate(context.Background(), NewPrompt("and now?")); !errors.Is(err, ErrContextLengthExceeded) {
+		t.Fatalf("expected max_completion_tokens to be reserved, got %v", err)
+	}
+	model.SetOption("max_completion_tokens", 10)
+	if _, err := model.Generate(context.Background(), NewPrompt("and now?")); err != nil {
+		t.Fatalf("expected the short prompt to fit, got %v", err)
+	}
+
+	tools := []PromptMessage{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "weather?"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1"}}},
+		{Role: "tool", ToolCallID: "call_1", Content: "sunny"},
+	}
+	for _, tc := range []struct {
+		name  string
+		n     int
+		roles string
+	}{
+		{"none", 0, "system"},
+		// Tool results keep the assistant message that requested them.
+		{"tool result", 1, "system,assistant,tool"},
+		{"more than history", 5, "system,user,assistant,tool"},
+	} {
+		kept, err := KeepSystemAndLastN(tc.n).Truncate(context.Background(), tools, nil)
+		var roles []string
+		for _, msg := range kept {
+			roles = append(roles, msg.Role)
+		}
+		if err != nil || strings.Join(roles, ",") != tc.roles {
+			t.Fatalf("%s: KeepSystemAndLastN(%d) kept %q, %v; want %q", tc.name, tc.n, roles, err, tc.roles)
+		}
+	}
+
+	// Keeping nothing summarizes the whole history.
+	summarizer := newTestLLM(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	})
+	summarized, err := SummarizeOldest(summarizer, 0).Truncate(context.Background(), tools, nil)
+	if err != nil || len(summarized) != 2 || summarized[1].Content != "Summary of the earlier conversation:\nok" {
+		t.Fatalf("SummarizeOldest(0) = %+v, %v", summarized, err)
+	}
+}
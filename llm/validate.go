@@ -122,9 +122,17 @@ func RegisterCustomValidation(tag string, fn validator.Func) error {
 //
 //	schema, err := GenerateJSONSchema(&Prompt{})
 func GenerateJSONSchema(v interface{}) ([]byte, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("unsupported type: %v", t)
+	}
+
 	schema := make(map[string]interface{})
 	schema["type"] = "object"
-	properties, required, err := getStructProperties(reflect.TypeOf(v))
+	properties, required, err := getStructProperties(t)
 	if err != nil {
 		return nil, err
 	}
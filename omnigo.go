@@ -9,6 +9,7 @@ import (
 
 	"github.com/YspCoder/omnigo/adapter"
 	"github.com/YspCoder/omnigo/config"
+	"github.com/YspCoder/omnigo/dto"
 	"github.com/YspCoder/omnigo/llm"
 	"github.com/YspCoder/omnigo/utils"
 )
@@ -34,6 +35,10 @@ type LLM interface {
 	// SetSystemPrompt updates the system prompt with caching configuration.
 	// The cacheType parameter determines how the prompt should be cached.
 	SetSystemPrompt(prompt string, cacheType CacheType)
+	// ModelInfo returns the catalog entry of the current model and whether it is known.
+	ModelInfo() (ModelInfo, bool)
+	// CostTracker returns the tracker recording the spend and budget of this LLM.
+	CostTracker() *CostTracker
 }
 
 // llmImpl is the concrete implementation of the LLM interface.
@@ -75,11 +80,36 @@ func (l *llmImpl) GetLogLevel() LogLevel {
 
 // SetOption sets an option for the LLM with the given key and value.
 func (l *llmImpl) SetOption(key string, value interface{}) {
-	l.logger.Debug("Setting option", "key", key, "value", value)
+	l.logger.Debug("Setting option", key, value)
 	l.LLM.SetOption(key, value)
 	l.logger.Debug("Option set successfully")
 }
 
+// SupportsMedia reports whether the provider can generate the given media type.
+func (l *llmImpl) SupportsMedia(mediaType dto.MediaType) bool {
+	if supporter, ok := l.LLM.(llm.MediaSupporter); ok {
+		return supporter.SupportsMedia(mediaType)
+	}
+	return true
+}
+
+// ModelInfo returns the catalog entry of the current model and whether it is known.
+func (l *llmImpl) ModelInfo() (ModelInfo, bool) {
+	if provider, ok := l.LLM.(llm.ModelInfoProvider); ok {
+		return provider.ModelInfo()
+	}
+	return ModelInfo{}, false
+}
+
+// CostTracker returns the tracker recording the spend and budget of this LLM, or
+// nil if it does not account for costs.
+func (l *llmImpl) CostTracker() *CostTracker {
+	if provider, ok := l.LLM.(llm.CostTrackerProvider); ok {
+		return provider.CostTracker()
+	}
+	return nil
+}
+
 // GetPromptJSONSchema generates and returns the JSON schema for the Prompt.
 func (l *llmImpl) GetPromptJSONSchema(opts ...SchemaOption) ([]byte, error) {
 	p := &Prompt{}
@@ -119,6 +149,29 @@ func (l *llmImpl) Generate(ctx context.Context, prompt *llm.Prompt, opts ...llm.
 	return response, nil
 }
 
+// GenerateResult produces a structured result, including tool calls, for the given prompt.
+func (l *llmImpl) GenerateResult(ctx context.Context, prompt *llm.Prompt, opts ...llm.GenerateOption) (*llm.GenerateResult, error) {
+	l.logger.Debug("Starting GenerateResult method", "prompt_length", len(prompt.String()), "tools", len(prompt.Tools))
+
+	config := &llm.GenerateConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if config.UseJSONSchema {
+		if err := prompt.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid prompt: %w", err)
+		}
+	}
+
+	result, err := l.LLM.GenerateResult(ctx, prompt, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("LLM.GenerateResult error: %w", err)
+	}
+
+	return result, nil
+}
+
 // NewLLM creates a new LLM instance with the specified configuration options.
 // It supports caching and provider-specific optimizations.
 //
@@ -156,7 +209,7 @@ func NewLLM(opts ...ConfigOption) (LLM, error) {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	logger := utils.NewLogger(cfg.LogLevel)
+	logger := config.NewLogger(cfg)
 
 	baseLLM, err := llm.NewLLM(cfg, logger, adapter.NewRegistry())
 	if err != nil {
@@ -46,6 +46,16 @@ type (
 	// ToolCall represents a request from the LLM to use a specific tool.
 	// It includes the tool name and any arguments needed for execution.
 	ToolCall = llm.ToolCall
+
+	// GenerateResult holds the structured outcome of a generation call,
+	// including any tool calls requested by the LLM.
+	GenerateResult = llm.GenerateResult
+
+	// FinishReason is the provider-independent reason a generation stopped.
+	FinishReason = llm.FinishReason
+
+	// ContentPart is a single piece of multimodal message content (text, image, audio or file).
+	ContentPart = llm.ContentPart
 )
 
 // Cache type constants define the available caching strategies.
@@ -55,6 +65,23 @@ const (
 	CacheTypeEphemeral = llm.CacheTypeEphemeral
 )
 
+// Finish reason constants reported in GenerateResult.FinishReason.
+const (
+	FinishReasonStop          = llm.FinishReasonStop          // Natural end or stop sequence
+	FinishReasonLength        = llm.FinishReasonLength        // Truncated by the token limit
+	FinishReasonToolCalls     = llm.FinishReasonToolCalls     // Stopped to request tool calls
+	FinishReasonContentFilter = llm.FinishReasonContentFilter // Blocked by a safety filter
+	FinishReasonOther         = llm.FinishReasonOther         // Any other provider reason
+)
+
+// Content part type constants used in ContentPart.Type.
+const (
+	ContentPartText  = llm.ContentPartText
+	ContentPartImage = llm.ContentPartImage
+	ContentPartAudio = llm.ContentPartAudio
+	ContentPartFile  = llm.ContentPartFile
+)
+
 // The following variables are re-exported functions from the llm package.
 // They provide the primary means of constructing and customizing prompts.
 var (
@@ -73,6 +100,9 @@ var (
 	// WithTools configures available tools for the prompt.
 	WithTools = llm.WithTools
 
+	// WithToolResult appends the output of a tool call to the conversation.
+	WithToolResult = llm.WithToolResult
+
 	// WithToolChoice specifies how tools should be selected.
 	WithToolChoice = llm.WithToolChoice
 
@@ -102,6 +132,33 @@ var (
 
 	// WithStream enables or disables streaming responses.
 	WithStream = config.WithStream
+
+	// WithContentParts attaches multimodal parts to the last user message.
+	WithContentParts = llm.WithContentParts
+
+	// WithImageURL attaches an image URL to the last user message.
+	WithImageURL = llm.WithImageURL
+
+	// WithImageData attaches an inline image to the last user message.
+	WithImageData = llm.WithImageData
+
+	// WithAudioData attaches inline audio to the last user message.
+	WithAudioData = llm.WithAudioData
+
+	// WithFile attaches a file URL (e.g. a PDF) to the last user message.
+	WithFile = llm.WithFile
+
+	// WithFileData attaches an inline file to the last user message.
+	WithFileData = llm.WithFileData
+
+	// Content part constructors.
+	TextPart      = llm.TextPart
+	ImageURLPart  = llm.ImageURLPart
+	ImageDataPart = llm.ImageDataPart
+	AudioDataPart = llm.AudioDataPart
+	AudioURLPart  = llm.AudioURLPart
+	FileURLPart   = llm.FileURLPart
+	FileDataPart  = llm.FileDataPart
 )
 
 // CleanResponse processes and cleans up LLM responses by removing markdown formatting
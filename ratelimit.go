@@ -0,0 +1,26 @@
+// Package omnigo provides client-side rate limiting for Language Learning Model requests.
+// This file re-exports the limiter types and constructors from the ratelimit package.
+package omnigo
+
+import (
+	"github.com/YspCoder/omnigo/ratelimit"
+)
+
+// Re-export rate limiting types
+type (
+	// RateLimiter enforces requests-per-minute, tokens-per-minute and in-flight limits.
+	// Install one with SetRateLimiter to share a quota across LLM instances.
+	RateLimiter = ratelimit.Limiter
+
+	// RateLimits configures a RateLimiter.
+	RateLimits = ratelimit.Limits
+)
+
+var (
+	// NewRateLimiter creates a rate limiter.
+	NewRateLimiter = ratelimit.New
+
+	// SharedRateLimiter returns the process-wide rate limiter registered under a key,
+	// or an error if it was registered with different limits.
+	SharedRateLimiter = ratelimit.Shared
+)
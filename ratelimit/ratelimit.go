@@ -0,0 +1,237 @@
+// Package ratelimit provides client-side rate limiting for provider requests:
+// token buckets for requests and tokens per minute plus a cap on in-flight requests.
+// A Limiter can be shared by several LLM instances so that they draw from one quota.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limits configures a Limiter. Zero values disable the corresponding limit.
+type Limits struct {
+	RequestsPerMinute int // Maximum requests started per minute
+	TokensPerMinute   int // Maximum tokens (prompt and completion) per minute
+	MaxInFlight       int // Maximum concurrent requests
+}
+
+// Enabled reports whether any limit is set.
+func (l Limits) Enabled() bool {
+	return l.RequestsPerMinute > 0 || l.TokensPerMinute > 0 || l.MaxInFlight > 0
+}
+
+// Limiter enforces Limits. It is safe for concurrent use.
+type Limiter struct {
+	limits   Limits
+	requests *bucket
+	tokens   *bucket
+	slots    chan struct{}
+}
+
+// New creates a Limiter for the given limits.
+func New(limits Limits) *Limiter {
+	limiter := &Limiter{limits: limits}
+	if limits.RequestsPerMinute > 0 {
+		limiter.requests = newBucket(limits.RequestsPerMinute)
+	}
+	if limits.TokensPerMinute > 0 {
+		limiter.tokens = newBucket(limits.TokensPerMinute)
+	}
+	if limits.MaxInFlight > 0 {
+		limiter.slots = make(chan struct{}, limits.MaxInFlight)
+	}
+	return limiter
+}
+
+var (
+	sharedMu sync.Mutex
+	shared   = make(map[string]*Limiter)
+)
+
+// Shared returns the process-wide Limiter registered under key, creating it with
+// limits on first use. Later calls with the same key share the first Limiter, so
+// LLM instances using the same quota draw from one bucket.
+//
+// Returns:
+//   - The Limiter registered under key
+//   - An error if the Limiter was registered with different limits; the existing
+//     Limiter is still returned
+func Shared(key string, limits Limits) (*Limiter, error) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if limiter, ok := shared[key]; ok {
+		if limiter.limits != limits {
+			return limiter, fmt.Errorf("rate limiter %q already registered with limits %+v, got %+v", key, limiter.limits, limits)
+		}
+		return limiter, nil
+	}
+	limiter := New(limits)
+	shared[key] = limiter
+	return limiter, nil
+}
+
+// Limits returns the limits the Limiter enforces.
+func (l *Limiter) Limits() Limits {
+	return l.limits
+}
+
+// Acquire waits until a request costing the estimated number of tokens may start.
+// It takes an in-flight slot, one request and the tokens from their buckets; the
+// returned reservation records what was taken and must be released when the request ends.
+// Waiting stops with the context error when ctx is done.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - tokens: Estimated token cost of the request; see EstimateTokens
+//
+// Returns:
+//   - The reservation holding the in-flight slot and the reserved tokens
+//   - The context error if ctx ended while waiting
+func (l *Limiter) Acquire(ctx context.Context, tokens int) (*Reservation, error) {
+	if l == nil {
+		return nil, nil
+	}
+	reservation := &Reservation{limiter: l}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		reservation.slot = true
+	}
+	if err := l.requests.take(ctx, 1); err != nil {
+		reservation.Release()
+		return nil, err
+	}
+	if err := l.tokens.take(ctx, tokens); err != nil {
+		// The request never starts, so it does not count against the request budget.
+		if l.requests != nil {
+			l.requests.adjust(-1)
+		}
+		reservation.Release()
+		return nil, err
+	}
+	if l.tokens != nil && tokens > 0 {
+		reservation.tokens = tokens
+	}
+	return reservation, nil
+}
+
+// Reservation is the capacity taken from a Limiter by Acquire. A nil Reservation,
+// returned by a nil Limiter, is valid and does nothing.
+type Reservation struct {
+	limiter     *Limiter
+	tokens      int
+	slot        bool
+	releaseOnce sync.Once
+	adjustOnce  sync.Once
+}
+
+// Tokens returns the number of tokens reserved.
+func (r *Reservation) Tokens() int {
+	if r == nil {
+		return 0
+	}
+	return r.tokens
+}
+
+// Release frees the in-flight slot. Calling it more than once has no effect.
+func (r *Reservation) Release() {
+	if r == nil || !r.slot {
+		return
+	}
+	r.releaseOnce.Do(func() { <-r.limiter.slots })
+}
+
+// Adjust replaces the reserved tokens by the actual usage of the request,
+// charging or refunding the difference. Only the first call counts.
+func (r *Reservation) Adjust(actual int) {
+	if r == nil || actual <= 0 {
+		return
+	}
+	r.adjustOnce.Do(func() {
+		r.limiter.Adjust(actual - r.tokens)
+	})
+}
+
+// Adjust corrects the token bucket once the actual usage of a request is known:
+// a positive delta charges extra tokens, a negative one refunds an overestimate.
+func (l *Limiter) Adjust(delta int) {
+	if l == nil || l.tokens == nil || delta == 0 {
+		return
+	}
+	l.tokens.adjust(float64(delta))
+}
+
+// EstimateTokens approximates the token cost of a request body at about four bytes
+// per token. It is used to reserve tokens before the actual usage is known.
+func EstimateTokens(body []byte) int {
+	return len(body)/4 + 1
+}
+
+// bucket is a token bucket refilled continuously up to its per-minute capacity.
+type bucket struct {
+	mu       sync.Mutex
+	capacity float64
+	rate     float64 // tokens per second
+	level    float64
+	updated  time.Time
+}
+
+func newBucket(perMinute int) *bucket {
+	return &bucket{
+		capacity: float64(perMinute),
+		rate:     float64(perMinute) / 60,
+		level:    float64(perMinute),
+		updated:  time.Now(),
+	}
+}
+
+// take waits until n tokens are available and removes them. Requests larger than
+// the capacity wait for a full bucket instead of blocking forever, and are then
+// charged in full so that the excess delays the next requests.
+func (b *bucket) take(ctx context.Context, n int) error {
+	if b == nil || n <= 0 {
+		return nil
+	}
+	need := math.Min(float64(n), b.capacity)
+	for {
+		b.mu.Lock()
+		b.refill(time.Now())
+		if b.level >= need {
+			b.level -= float64(n)
+			b.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((need - b.level) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (b *bucket) adjust(delta float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	// The level may go negative so that an underestimated request delays the next ones.
+	b.level = math.Min(b.level-delta, b.capacity)
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.level = math.Min(b.capacity, b.level+elapsed*b.rate)
+		b.updated = now
+	}
+}
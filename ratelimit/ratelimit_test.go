@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiterWaitsRespectContext(t *testing.T) {
+	limiter := New(Limits{RequestsPerMinute: 1, TokensPerMinute: 100})
+	if _, err := limiter.Acquire(context.Background(), 10); err != nil {
+		t.Fatalf("first request should pass: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, 10); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the request bucket to block until the deadline, got %v", err)
+	}
+
+	limiter = New(Limits{TokensPerMinute: 100})
+	if _, err := limiter.Acquire(context.Background(), 100); err != nil {
+		t.Fatalf("request within the token budget should pass: %v", err)
+	}
+	limiter.Adjust(-60)
+	if _, err := limiter.Acquire(ctx, 50); err != nil {
+		t.Fatalf("refunded tokens should be available: %v", err)
+	}
+
+	// A request cancelled while waiting for tokens returns its request token.
+	limiter = New(Limits{RequestsPerMinute: 2, TokensPerMinute: 10})
+	if _, err := limiter.Acquire(context.Background(), 10); err != nil {
+		t.Fatalf("first request should pass: %v", err)
+	}
+	waiting, cancelWaiting := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelWaiting()
+	if _, err := limiter.Acquire(waiting, 10); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the token bucket to block until the deadline, got %v", err)
+	}
+	limiter.Adjust(-10)
+	next, cancelNext := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelNext()
+	if _, err := limiter.Acquire(next, 1); err != nil {
+		t.Fatalf("the cancelled request should not use up the request budget: %v", err)
+	}
+}
+
+func TestLimiterCapsInFlightRequests(t *testing.T) {
+	limiter, err := Shared("test/in-flight", Limits{MaxInFlight: 1})
+	if err != nil {
+		t.Fatalf("Shared returned error: %v", err)
+	}
+	if again, err := Shared("test/in-flight", Limits{MaxInFlight: 1}); err != nil || again != limiter {
+		t.Fatalf("expected the same limiter for the same key, got error %v", err)
+	}
+	if again, err := Shared("test/in-flight", Limits{MaxInFlight: 5}); err == nil || again != limiter {
+		t.Fatalf("expected conflicting limits to be reported, got error %v", err)
+	}
+
+	reservation, err := limiter.Acquire(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("first request should pass: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the second request to wait for a slot, got %v", err)
+	}
+
+	reservation.Release()
+	reservation.Release()
+	if _, err := limiter.Acquire(context.Background(), 1); err != nil {
+		t.Fatalf("released slot should be reusable: %v", err)
+	}
+}
+
+func TestReservationChargesActualUsage(t *testing.T) {
+	// A request larger than the bucket is let through once the bucket is full, but
+	// the excess is still charged.
+	limiter := New(Limits{TokensPerMinute: 100})
+	reservation, err := limiter.Acquire(context.Background(), 250)
+	if err != nil {
+		t.Fatalf("oversized request should pass on a full bucket: %v", err)
+	}
+	if reservation.Tokens() != 250 {
+		t.Fatalf("expected 250 reserved tokens, got %d", reservation.Tokens())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the excess to delay the next request, got %v", err)
+	}
+
+	// The actual usage replaces the reservation; later adjustments are ignored.
+	reservation.Adjust(40)
+	reservation.Adjust(1000)
+	next, cancelNext := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelNext()
+	if _, err := limiter.Acquire(next, 60); err != nil {
+		t.Fatalf("the refunded reservation should be available: %v", err)
+	}
+
+	var none *Limiter
+	reservation, err = none.Acquire(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("nil limiter should not block: %v", err)
+	}
+	reservation.Adjust(5)
+	reservation.Release()
+}
@@ -0,0 +1,28 @@
+// Package omnigo provides request middleware for Language Learning Model providers.
+// This file re-exports the middleware types and built-in middlewares from the relay package.
+package omnigo
+
+import (
+	"github.com/YspCoder/omnigo/relay"
+)
+
+// Re-export middleware types
+type (
+	// Middleware wraps the execution of provider HTTP requests.
+	// Install middlewares with SetMiddleware.
+	Middleware = relay.Middleware
+
+	// RelayHandler executes a provider request and returns the raw HTTP response.
+	RelayHandler = relay.Handler
+
+	// RelayRequest is a provider request passing through the middleware chain.
+	RelayRequest = relay.Request
+)
+
+var (
+	// LoggingMiddleware logs provider requests with their status and duration.
+	LoggingMiddleware = relay.LoggingMiddleware
+
+	// HeaderMiddleware sets headers on every provider request.
+	HeaderMiddleware = relay.HeaderMiddleware
+)
@@ -0,0 +1,85 @@
+package relay
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/YspCoder/omnigo/adapter"
+	"github.com/YspCoder/omnigo/utils"
+)
+
+// Request is a provider request passing through the middleware chain.
+type Request struct {
+	Mode   string                  // adapter.ModeChat, ModeImage, ModeVideo or ModeTask
+	Config *adapter.ProviderConfig // Provider the request is sent to
+	Body   []byte                  // Serialized request body
+	HTTP   *http.Request           // Prepared HTTP request, headers included
+}
+
+// SetBody replaces the request body, keeping Body and the HTTP request in sync.
+func (r *Request) SetBody(body []byte) {
+	r.Body = body
+	r.HTTP.Body = io.NopCloser(bytes.NewReader(body))
+	r.HTTP.ContentLength = int64(len(body))
+	r.HTTP.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+}
+
+// Handler executes a provider request and returns the raw HTTP response.
+type Handler func(req *Request) (*http.Response, error)
+
+// Middleware wraps a Handler. It may inspect or modify the request before calling
+// next, inspect or replace the response afterwards, or answer without calling next.
+type Middleware func(next Handler) Handler
+
+// Chain wraps handler with middlewares; the first middleware is the outermost.
+func Chain(handler Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] != nil {
+			handler = middlewares[i](handler)
+		}
+	}
+	return handler
+}
+
+// LoggingMiddleware logs every provider request with its status and duration.
+// Requests are logged at debug level and transport failures at warn level. API keys
+// in the URL and in errors are masked here, so any logger may receive the fields.
+func LoggingMiddleware(logger utils.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(req *Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			fields := []interface{}{
+				"provider", req.Config.Name,
+				"mode", req.Mode,
+				"method", req.HTTP.Method,
+				"url", utils.RedactURL(req.HTTP.URL),
+				"duration", time.Since(start),
+			}
+			if err != nil {
+				// Transport errors such as *url.Error quote the request URL.
+				logger.Warn("Provider request failed", append(fields, "error", utils.RedactString(err.Error()))...)
+				return nil, err
+			}
+			logger.Debug("Provider request completed", append(fields, "status", resp.StatusCode)...)
+			return resp, nil
+		}
+	}
+}
+
+// HeaderMiddleware sets the given headers on every provider request, overriding
+// headers set by the adaptor or the provider config.
+func HeaderMiddleware(headers map[string]string) Middleware {
+	return func(next Handler) Handler {
+		return func(req *Request) (*http.Response, error) {
+			for key, value := range headers {
+				req.HTTP.Header.Set(key, value)
+			}
+			return next(req)
+		}
+	}
+}
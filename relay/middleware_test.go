@@ -0,0 +1,152 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YspCoder/omnigo/adapter"
+	"github.com/YspCoder/omnigo/dto"
+	"github.com/YspCoder/omnigo/ratelimit"
+	"github.com/YspCoder/omnigo/utils"
+)
+
+func TestMiddlewareChainWrapsRequests(t *testing.T) {
+	var gotHeader, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("X-Audit")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(req *Request) (*http.Response, error) {
+				order = append(order, name+":"+req.Mode)
+				resp, err := next(req)
+				if err == nil {
+					order = append(order, name+":"+resp.Status)
+				}
+				return resp, err
+			}
+		}
+	}
+	rewrite := func(next Handler) Handler {
+		return func(req *Request) (*http.Response, error) {
+			req.SetBody([]byte(`{"model":"rewritten"}`))
+			return next(req)
+		}
+	}
+
+	r := NewRelay()
+	r.Use(trace("outer"), HeaderMiddleware(map[string]string{"X-Audit": "on"}), rewrite, trace("inner"))
+	config := &adapter.ProviderConfig{Name: "openai", BaseURL: server.URL, Model: "gpt-4o-mini"}
+	request := &dto.ChatRequest{Model: "gpt-4o-mini", Messages: []dto.Message{{Role: "user", Content: "hi"}}}
+
+	response, err := r.Chat(context.Background(), &adapter.OpenAIAdaptor{}, config, request)
+	if err != nil {
+		t.Fatalf("Chat returned error: %v", err)
+	}
+	if len(response.Choices) == 0 {
+		t.Fatal("expected a converted response")
+	}
+	if gotHeader != "on" || gotBody != `{"model":"rewritten"}` {
+		t.Fatalf("middleware changes not sent: header %q, body %q", gotHeader, gotBody)
+	}
+	want := []string{"outer:chat", "inner:chat", "inner:200 OK", "outer:200 OK"}
+	if len(order) != len(want) {
+		t.Fatalf("unexpected middleware order %v", order)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("unexpected middleware order %v", order)
+		}
+	}
+}
+
+func TestChatUsageReplacesRewrittenReservation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],` +
+			`"usage":{"prompt_tokens":8,"completion_tokens":2,"total_tokens":10}}`))
+	}))
+	defer server.Close()
+
+	// The middleware grows the body past the bucket capacity; the reservation is
+	// made for the rewritten body and replaced by the reported usage afterwards.
+	limiter := ratelimit.New(ratelimit.Limits{TokensPerMinute: 100})
+	r := NewRelay()
+	r.Use(func(next Handler) Handler {
+		return func(req *Request) (*http.Response, error) {
+			req.SetBody([]byte(`{"model":"gpt-4o-mini","user":"` + strings.Repeat("x", 2000) + `"}`))
+			return next(req)
+		}
+	})
+	config := &adapter.ProviderConfig{Name: "openai", BaseURL: server.URL, Model: "gpt-4o-mini", Limiter: limiter}
+	request := &dto.ChatRequest{Model: "gpt-4o-mini", Messages: []dto.Message{{Role: "user", Content: "hi"}}}
+	if _, err := r.Chat(context.Background(), &adapter.OpenAIAdaptor{}, config, request); err != nil {
+		t.Fatalf("Chat returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, 80); err != nil {
+		t.Fatalf("expected only the reported usage to be charged, got %v", err)
+	}
+}
+
+// recordingLogger keeps every log line without redacting it.
+type recordingLogger struct {
+	lines []string
+}
+
+func (l *recordingLogger) record(msg string, keysAndValues []interface{}) {
+	l.lines = append(l.lines, fmt.Sprint(append([]interface{}{msg}, keysAndValues...)...))
+}
+
+func (l *recordingLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.record(msg, keysAndValues)
+}
+func (l *recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.record(msg, keysAndValues)
+}
+func (l *recordingLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.record(msg, keysAndValues)
+}
+func (l *recordingLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.record(msg, keysAndValues)
+}
+func (l *recordingLogger) SetLevel(utils.LogLevel) {}
+
+func TestLoggingMiddlewareRedactsQueryKeys(t *testing.T) {
+	const key = "AIzaSyTestKey1234567890abcdef"
+	logger := &recordingLogger{}
+	handler := Chain(func(req *Request) (*http.Response, error) {
+		if req.Mode == adapter.ModeChat {
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}
+		return nil, &url.Error{Op: "Post", URL: req.HTTP.URL.String(), Err: errors.New("connection reset by peer")}
+	}, LoggingMiddleware(logger))
+
+	for _, mode := range []string{adapter.ModeChat, adapter.ModeImage} {
+		httpReq, _ := http.NewRequest(http.MethodPost, "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:generateContent?key="+key, nil)
+		_, _ = handler(&Request{Mode: mode, Config: &adapter.ProviderConfig{Name: "google"}, HTTP: httpReq})
+	}
+	if len(logger.lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %v", logger.lines)
+	}
+	for _, line := range logger.lines {
+		if strings.Contains(line, key) || !strings.Contains(line, "key="+utils.Redacted) {
+			t.Fatalf("expected the key to be masked, got %q", line)
+		}
+	}
+}
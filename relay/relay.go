@@ -7,16 +7,19 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/YspCoder/omnigo/adapter"
 	"github.com/YspCoder/omnigo/dto"
+	"github.com/YspCoder/omnigo/ratelimit"
 )
 
 // Relay executes provider requests using a unified flow.
 type Relay struct {
-	Client *http.Client
+	Client      *http.Client
+	Middlewares []Middleware // Applied around every provider request, outermost first
 }
 
 // NewRelay creates a relay with default settings.
@@ -24,6 +27,11 @@ func NewRelay() *Relay {
 	return &Relay{}
 }
 
+// Use appends middlewares to the chain wrapped around provider requests.
+func (r *Relay) Use(middlewares ...Middleware) {
+	r.Middlewares = append(r.Middlewares, middlewares...)
+}
+
 // Chat executes a chat completion request.
 func (r *Relay) Chat(ctx context.Context, adp adapter.Adaptor, config *adapter.ProviderConfig, request *dto.ChatRequest) (*dto.ChatResponse, error) {
 	if config == nil {
@@ -31,7 +39,7 @@ func (r *Relay) Chat(ctx context.Context, adp adapter.Adaptor, config *adapter.P
 	}
 
 	convertAdaptor := adp
-	if strings.EqualFold(config.ChatProtocol, "openai") {
+	if strings.EqualFold(config.ChatProtocol, adapter.ChatProtocolOpenAI) {
 		convertAdaptor = &adapter.OpenAIAdaptor{}
 	}
 
@@ -39,11 +47,17 @@ func (r *Relay) Chat(ctx context.Context, adp adapter.Adaptor, config *adapter.P
 	if err != nil {
 		return nil, err
 	}
-	respBody, err := r.doRequest(ctx, adp, config, adapter.ModeChat, body)
+	respBody, reservation, err := r.doRequest(ctx, adp, config, adapter.ModeChat, body)
 	if err != nil {
 		return nil, err
 	}
-	return convertAdaptor.ConvertChatResponse(ctx, config, respBody)
+	response, err := convertAdaptor.ConvertChatResponse(ctx, config, respBody)
+	if err != nil {
+		return nil, err
+	}
+	// Replace the tokens reserved before the request with the actual usage.
+	reservation.Adjust(response.Usage.TotalTokens)
+	return response, nil
 }
 
 // Media executes an image/video generation request.
@@ -69,7 +83,7 @@ func (r *Relay) Media(ctx context.Context, adp adapter.Adaptor, config *adapter.
 	if err != nil {
 		return nil, err
 	}
-	respBody, err := r.doRequest(ctx, adp, config, mode, body)
+	respBody, _, err := r.doRequest(ctx, adp, config, mode, body)
 	if err != nil {
 		return nil, err
 	}
@@ -108,32 +122,7 @@ func (r *Relay) TaskStatus(ctx context.Context, adp adapter.Adaptor, config *ada
 		body = b
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
-	if err != nil {
-		return nil, err
-	}
-
-	if err := adp.SetupHeaders(req, config, adapter.ModeTask); err != nil {
-		return nil, err
-	}
-	for key, value := range config.Headers {
-		req.Header.Set(key, value)
-	}
-
-	client := config.HTTPClient
-	if client == nil {
-		client = r.Client
-	}
-	if client == nil {
-		client = &http.Client{}
-	}
-	if config.Timeout > 0 {
-		client.Timeout = config.Timeout
-	} else if client.Timeout == 0 {
-		client.Timeout = 60 * time.Second
-	}
-
-	resp, err := client.Do(req)
+	resp, _, err := r.send(ctx, adp, config, adapter.ModeTask, method, url, body)
 	if err != nil {
 		return nil, err
 	}
@@ -144,13 +133,21 @@ func (r *Relay) TaskStatus(ctx context.Context, adp adapter.Adaptor, config *ada
 		return nil, err
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("task status request failed with status %d", resp.StatusCode)
+		return nil, newResponseError(resp, respBody, config.Name)
 	}
 
 	return taskAdaptor.ConvertTaskStatusResponse(ctx, config, respBody)
 }
 
-// Stream executes a streaming chat request and returns the response body.
+// UsageReporter is implemented by the stream bodies returned by Relay.Stream.
+// Reporting the final usage of the stream replaces the token estimate reserved
+// with the rate limiter by the actual usage; only the first report counts.
+type UsageReporter interface {
+	ReportUsage(usage dto.Usage)
+}
+
+// Stream executes a streaming chat request and returns the response body, which
+// implements UsageReporter.
 func (r *Relay) Stream(ctx context.Context, adp adapter.Adaptor, streamAdaptor adapter.StreamAdaptor, config *adapter.ProviderConfig, request *dto.ChatRequest) (io.ReadCloser, error) {
 	if config == nil {
 		return nil, fmt.Errorf("provider config is required")
@@ -164,7 +161,12 @@ func (r *Relay) Stream(ctx context.Context, adp adapter.Adaptor, streamAdaptor a
 		return nil, err
 	}
 
-	url, err := adp.GetRequestURL(adapter.ModeChat, config)
+	var url string
+	if urlProvider, ok := adp.(adapter.StreamURLProvider); ok {
+		url, err = urlProvider.GetStreamRequestURL(config)
+	} else {
+		url, err = adp.GetRequestURL(adapter.ModeChat, config)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -172,69 +174,101 @@ func (r *Relay) Stream(ctx context.Context, adp adapter.Adaptor, streamAdaptor a
 		return nil, fmt.Errorf("request url is empty")
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
-	if err != nil {
-		return nil, err
-	}
-
-	if err := adp.SetupHeaders(req, config, adapter.ModeChat); err != nil {
-		return nil, err
-	}
-	for key, value := range config.Headers {
-		req.Header.Set(key, value)
-	}
-
-	client := config.HTTPClient
-	if client == nil {
-		client = r.Client
-	}
-	if client == nil {
-		client = &http.Client{}
-	}
-	if config.Timeout > 0 {
-		client.Timeout = config.Timeout
-	} else if client.Timeout == 0 {
-		client.Timeout = 60 * time.Second
-	}
-
-	resp, err := client.Do(req)
+	resp, reservation, err := r.send(ctx, adp, config, adapter.ModeChat, http.MethodPost, url, body)
 	if err != nil {
 		return nil, err
 	}
 	if resp.StatusCode != http.StatusOK {
 		defer resp.Body.Close()
 		respBody, _ := io.ReadAll(resp.Body)
-		return nil, &dto.LLMError{
-			Code:     resp.StatusCode,
-			Message:  string(respBody),
-			Provider: config.Name,
-		}
+		return nil, newResponseError(resp, respBody, config.Name)
 	}
 
-	return resp.Body, nil
+	// The rate limiter slot is held until the caller closes the stream.
+	return &streamBody{ReadCloser: resp.Body, reservation: reservation}, nil
 }
 
-func (r *Relay) doRequest(ctx context.Context, adp adapter.Adaptor, config *adapter.ProviderConfig, mode string, body []byte) ([]byte, error) {
+func (r *Relay) doRequest(ctx context.Context, adp adapter.Adaptor, config *adapter.ProviderConfig, mode string, body []byte) ([]byte, *ratelimit.Reservation, error) {
 	url, err := adp.GetRequestURL(mode, config)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if url == "" {
-		return nil, fmt.Errorf("request url is empty")
+		return nil, nil, fmt.Errorf("request url is empty")
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	resp, reservation, err := r.send(ctx, adp, config, mode, http.MethodPost, url, body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return nil, nil, newResponseError(resp, respBody, config.Name)
+	}
+	return respBody, reservation, nil
+}
+
+// send builds the provider request and runs it through the middleware chain.
+// The innermost handler waits for the rate limiter and performs the HTTP call;
+// the limiter slot is released when the response body is closed. The returned
+// reservation holds the tokens reserved for the body as finally sent, after
+// middlewares had the chance to rewrite it.
+func (r *Relay) send(ctx context.Context, adp adapter.Adaptor, config *adapter.ProviderConfig, mode, method, url string, body []byte) (*http.Response, *ratelimit.Reservation, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
 	}
 
 	if err := adp.SetupHeaders(req, config, mode); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	for key, value := range config.Headers {
 		req.Header.Set(key, value)
 	}
 
+	var reservation *ratelimit.Reservation
+	handler := Chain(r.do(adp, r.httpClient(config), &reservation), r.Middlewares...)
+	resp, err := handler(&Request{Mode: mode, Config: config, Body: body, HTTP: req})
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, reservation, nil
+}
+
+// do returns the terminal handler of the middleware chain. Adaptors implementing
+// adapter.RequestSigner sign the request once the rate limiter lets it through.
+// The rate limiter reservation is stored in reserved for the caller of the chain.
+func (r *Relay) do(adp adapter.Adaptor, client *http.Client, reserved **ratelimit.Reservation) Handler {
+	return func(req *Request) (*http.Response, error) {
+		reservation, err := req.Config.Limiter.Acquire(req.HTTP.Context(), ratelimit.EstimateTokens(req.Body))
+		if err != nil {
+			return nil, err
+		}
+		if signer, ok := adp.(adapter.RequestSigner); ok {
+			if err := signer.SignRequest(req.HTTP, req.Config, req.Body); err != nil {
+				reservation.Release()
+				return nil, err
+			}
+		}
+		resp, err := client.Do(req.HTTP)
+		if err != nil {
+			reservation.Release()
+			return nil, err
+		}
+		*reserved = reservation
+		resp.Body = &releasingBody{ReadCloser: resp.Body, reservation: reservation}
+		return resp, nil
+	}
+}
+
+// httpClient returns the client for the provider with the configured timeout applied.
+func (r *Relay) httpClient(config *adapter.ProviderConfig) *http.Client {
 	client := config.HTTPClient
 	if client == nil {
 		client = r.Client
@@ -242,29 +276,81 @@ func (r *Relay) doRequest(ctx context.Context, adp adapter.Adaptor, config *adap
 	if client == nil {
 		client = &http.Client{}
 	}
+	timeout := client.Timeout
 	if config.Timeout > 0 {
-		client.Timeout = config.Timeout
-	} else if client.Timeout == 0 {
-		client.Timeout = 60 * time.Second
+		timeout = config.Timeout
+	} else if timeout == 0 {
+		timeout = 60 * time.Second
 	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+	if timeout != client.Timeout {
+		// Shallow-copy rather than mutate: the client is shared by concurrent calls.
+		copied := *client
+		copied.Timeout = timeout
+		client = &copied
 	}
-	defer resp.Body.Close()
+	return client
+}
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+// releasingBody releases a rate limiter slot when the response body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	reservation *ratelimit.Reservation
+}
+
+func (b *releasingBody) Close() error {
+	defer b.reservation.Release()
+	return b.ReadCloser.Close()
+}
+
+// streamBody corrects the rate limiter's token estimate once the stream's usage is known.
+type streamBody struct {
+	io.ReadCloser
+	reservation *ratelimit.Reservation
+}
+
+func (b *streamBody) ReportUsage(usage dto.Usage) {
+	b.reservation.Adjust(usage.TotalTokens)
+}
+
+// newResponseError builds the error for a non-success provider response, decoding
+// the provider's error code and message and keeping the Retry-After hint.
+func newResponseError(resp *http.Response, body []byte, provider string) *dto.LLMError {
+	code, message := adapter.ParseErrorBody(body)
+	if message == "" {
+		message = strings.TrimSpace(string(body))
+	}
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+	return &dto.LLMError{
+		Code:       resp.StatusCode,
+		Message:    message,
+		Provider:   provider,
+		Type:       code,
+		RetryAfter: retryAfter(resp.Header, time.Now()),
 	}
+}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
-		return nil, &dto.LLMError{
-			Code:     resp.StatusCode,
-			Message:  string(respBody),
-			Provider: config.Name,
+// retryAfter reads the Retry-After header (seconds or HTTP date) or the
+// millisecond variant sent by some OpenAI-compatible gateways.
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	if value := header.Get("Retry-After-Ms"); value != "" {
+		if ms, err := strconv.ParseFloat(value, 64); err == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds <= 0 {
+			return 0
 		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
 	}
-	return respBody, nil
+	return 0
 }
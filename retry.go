@@ -0,0 +1,25 @@
+// Package omnigo provides retry policies for Language Learning Model requests.
+// This file re-exports the retry types and helpers from the retry package.
+package omnigo
+
+import (
+	"github.com/YspCoder/omnigo/retry"
+)
+
+// Re-export retry types
+type (
+	// RetryPolicy decides whether a failed provider call is retried and how long to wait.
+	// Install a custom policy with SetRetryPolicy.
+	RetryPolicy = retry.Policy
+
+	// Backoff is the default exponential backoff policy with jitter.
+	Backoff = retry.Backoff
+)
+
+var (
+	// NewBackoff creates an exponential backoff policy.
+	NewBackoff = retry.NewBackoff
+
+	// IsRetryable reports whether an error is worth retrying.
+	IsRetryable = retry.IsRetryable
+)
@@ -0,0 +1,209 @@
+// Package retry provides the retry policy shared by provider calls.
+// A Policy decides, per failed attempt, whether to try again and how long to wait;
+// Do runs an operation under a policy with context-aware waits.
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/YspCoder/omnigo/dto"
+)
+
+// Policy decides whether a failed attempt is retried.
+type Policy interface {
+	// Next reports whether to retry after the given number of failed attempts and
+	// how long to wait first. elapsed is the time since the first attempt started.
+	Next(attempts int, elapsed time.Duration, err error) (delay time.Duration, retry bool)
+}
+
+// Retryable is implemented by errors that know whether retrying can succeed.
+type Retryable interface {
+	Retryable() bool
+}
+
+// Backoff is an exponential backoff policy with jitter. Only retryable errors
+// (see IsRetryable) are retried, a provider Retry-After hint is honored when it
+// is longer than the computed delay, and MaxElapsed bounds the total time spent.
+type Backoff struct {
+	MaxRetries   int           // Maximum number of retries after the first attempt
+	InitialDelay time.Duration // Delay before the first retry
+	MaxDelay     time.Duration // Upper bound for the computed delay; 0 means no bound
+	Multiplier   float64       // Growth factor between retries; values below 1 use 2
+	Jitter       float64       // Fraction of the delay randomized away, between 0 and 1
+	MaxElapsed   time.Duration // Total deadline budget across attempts; 0 means no budget
+
+	// ShouldRetry overrides IsRetryable when set.
+	ShouldRetry func(error) bool
+}
+
+// DefaultJitter is the jitter fraction used by NewBackoff.
+const DefaultJitter = 0.2
+
+// NewBackoff creates a Backoff policy doubling from initialDelay up to maxDelay.
+//
+// Parameters:
+//   - maxRetries: Maximum number of retries after the first attempt
+//   - initialDelay: Delay before the first retry
+//   - maxDelay: Upper bound for a single delay; 0 means no bound
+//
+// Returns:
+//   - A Backoff with DefaultJitter and no total budget
+func NewBackoff(maxRetries int, initialDelay, maxDelay time.Duration) *Backoff {
+	return &Backoff{
+		MaxRetries:   maxRetries,
+		InitialDelay: initialDelay,
+		MaxDelay:     maxDelay,
+		Multiplier:   2,
+		Jitter:       DefaultJitter,
+	}
+}
+
+// Next implements Policy.
+func (b *Backoff) Next(attempts int, elapsed time.Duration, err error) (time.Duration, bool) {
+	if attempts > b.MaxRetries {
+		return 0, false
+	}
+	shouldRetry := b.ShouldRetry
+	if shouldRetry == nil {
+		shouldRetry = IsRetryable
+	}
+	if !shouldRetry(err) {
+		return 0, false
+	}
+
+	delay := b.delay(attempts)
+	if hint := RetryAfter(err); hint > delay {
+		delay = hint
+	}
+	if b.MaxElapsed > 0 && elapsed+delay >= b.MaxElapsed {
+		return 0, false
+	}
+	return delay, true
+}
+
+// delay returns the jittered backoff before retry number attempts.
+func (b *Backoff) delay(attempts int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	delay := float64(b.InitialDelay)
+	for i := 1; i < attempts; i++ {
+		delay *= multiplier
+		if b.MaxDelay > 0 && delay >= float64(b.MaxDelay) {
+			break
+		}
+	}
+	if b.MaxDelay > 0 && delay > float64(b.MaxDelay) {
+		delay = float64(b.MaxDelay)
+	}
+	if b.Jitter > 0 {
+		jitter := b.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay -= delay * jitter * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// IsRetryable reports whether an error is worth retrying: rate limits, overload,
+// request timeouts (408), server errors (5xx) and network failures are; client errors
+// such as invalid requests or bad credentials are not, and neither is cancellation.
+// Errors implementing Retryable decide for themselves.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var retryable Retryable
+	if errors.As(err, &retryable) {
+		return retryable.Retryable()
+	}
+	var providerErr *dto.LLMError
+	if errors.As(err, &providerErr) {
+		return RetryableStatus(providerErr.Code)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// RetryableStatus reports whether an HTTP status code indicates a transient failure.
+func RetryableStatus(status int) bool {
+	switch {
+	case status == http.StatusRequestTimeout, status == http.StatusTooManyRequests:
+		return true
+	case status >= 500:
+		return status != http.StatusNotImplemented
+	default:
+		return false
+	}
+}
+
+// RetryAfter returns the Retry-After delay carried by a provider error, or 0.
+func RetryAfter(err error) time.Duration {
+	var providerErr *dto.LLMError
+	if errors.As(err, &providerErr) {
+		return providerErr.RetryAfter
+	}
+	return 0
+}
+
+// Do runs operation until it succeeds, the policy stops retrying or ctx is done.
+// Waits between attempts return early when ctx is cancelled. onRetry, when non-nil,
+// is called before each wait with the failed attempt number, the error and the delay.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - policy: Retry policy; nil runs the operation once
+//   - operation: The call to run; it receives the 1-based attempt number
+//   - onRetry: Optional hook invoked before each retry
+//
+// Returns:
+//   - The number of attempts made
+//   - The last error, or nil on success. A context error is returned when ctx ends during a wait.
+func Do(ctx context.Context, policy Policy, operation func(attempt int) error, onRetry func(attempt int, err error, delay time.Duration)) (int, error) {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := operation(attempt)
+		if err == nil {
+			return attempt, nil
+		}
+		if ctx.Err() != nil || policy == nil {
+			return attempt, err
+		}
+		delay, retry := policy.Next(attempt, time.Since(start), err)
+		if !retry {
+			return attempt, err
+		}
+		if onRetry != nil {
+			onRetry(attempt, err, delay)
+		}
+		if err := Wait(ctx, delay); err != nil {
+			return attempt, err
+		}
+	}
+}
+
+// Wait sleeps for delay or until ctx is done, returning the context error in that case.
+func Wait(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
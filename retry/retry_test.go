@@ -0,0 +1,49 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/YspCoder/omnigo/dto"
+)
+
+func TestBackoffNext(t *testing.T) {
+	policy := &Backoff{MaxRetries: 3, InitialDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond, Multiplier: 2}
+
+	if _, retry := policy.Next(1, 0, &dto.LLMError{Code: 400}); retry {
+		t.Fatal("expected a 400 not to be retried")
+	}
+	if delay, retry := policy.Next(3, 0, &dto.LLMError{Code: 503}); !retry || delay != 300*time.Millisecond {
+		t.Fatalf("expected capped delay of 300ms, got %v (retry=%v)", delay, retry)
+	}
+	if _, retry := policy.Next(4, 0, &dto.LLMError{Code: 503}); retry {
+		t.Fatal("expected retries to stop after MaxRetries")
+	}
+	if delay, _ := policy.Next(1, 0, &dto.LLMError{Code: 429, RetryAfter: 2 * time.Second}); delay != 2*time.Second {
+		t.Fatalf("expected Retry-After to be honored, got %v", delay)
+	}
+
+	policy.MaxElapsed = time.Second
+	if _, retry := policy.Next(1, 950*time.Millisecond, &dto.LLMError{Code: 503}); retry {
+		t.Fatal("expected the deadline budget to stop retries")
+	}
+}
+
+func TestDoStopsWhenContextIsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := NewBackoff(5, time.Hour, 0)
+
+	attempts, err := Do(ctx, policy, func(int) error {
+		cancel()
+		return &dto.LLMError{Code: 503}
+	}, nil)
+	if attempts != 1 {
+		t.Fatalf("expected a single attempt, got %d", attempts)
+	}
+	var providerErr *dto.LLMError
+	if !errors.As(err, &providerErr) {
+		t.Fatalf("expected the provider error, got %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+// Package omnigo provides routing across multiple Language Learning Model backends.
+// This file re-exports the router types and constructors from the llm package.
+package omnigo
+
+import (
+	"github.com/YspCoder/omnigo/llm"
+)
+
+// Re-export router types
+type (
+	// Router implements LLM over an ordered list of backends with failover,
+	// weighted load balancing and per-backend circuit breakers.
+	Router = llm.Router
+
+	// Backend is a named LLM served by a Router.
+	Backend = llm.Backend
+
+	// RouterOption configures a Router.
+	RouterOption = llm.RouterOption
+)
+
+var (
+	// NewRouter creates a Router over the given backends.
+	NewRouter = llm.NewRouter
+
+	// WithCircuitBreaker sets the failure threshold and cooldown of backend circuit breakers.
+	WithCircuitBreaker = llm.WithCircuitBreaker
+
+	// WithRouterLogger sets the logger used to report failovers.
+	WithRouterLogger = llm.WithRouterLogger
+)
@@ -0,0 +1,52 @@
+// Package omnigo provides tool execution functionality for Language Learning Models.
+// This file re-exports the agent runner that executes tool calls returned by the LLM.
+package omnigo
+
+import (
+	"github.com/YspCoder/omnigo/llm"
+)
+
+// Re-export runner types from the llm package
+type (
+	// Runner drives the generate / execute tools / respond loop on top of an LLM.
+	Runner = llm.Runner
+
+	// RunnerOption configures a Runner.
+	RunnerOption = llm.RunnerOption
+
+	// RunResult holds the final answer and full conversation of a Runner execution.
+	RunResult = llm.RunResult
+
+	// ToolHandler executes a single tool call and returns its output.
+	ToolHandler = llm.ToolHandler
+
+	// ToolApprovalFunc approves or denies a tool call before it runs.
+	ToolApprovalFunc = llm.ToolApprovalFunc
+)
+
+// Re-export runner constructors and options
+var (
+	// NewRunner creates a tool execution loop for the given LLM.
+	NewRunner = llm.NewRunner
+
+	// WithRunnerTool registers a tool definition together with its handler.
+	WithRunnerTool = llm.WithRunnerTool
+
+	// WithToolHandlers registers handlers keyed by function name.
+	WithToolHandlers = llm.WithToolHandlers
+
+	// WithMaxSteps limits the number of generation calls made by the runner.
+	WithMaxSteps = llm.WithMaxSteps
+
+	// WithParallelToolCalls executes the tool calls of a step concurrently.
+	WithParallelToolCalls = llm.WithParallelToolCalls
+
+	// WithToolTimeout bounds the execution time of each tool call.
+	WithToolTimeout = llm.WithToolTimeout
+
+	// WithToolApproval installs a hook that approves or denies each tool call.
+	WithToolApproval = llm.WithToolApproval
+
+	// ErrMaxStepsExceeded is wrapped in the error returned when the loop does not finish.
+	ErrMaxStepsExceeded = llm.ErrMaxStepsExceeded
+)
@@ -24,3 +24,40 @@ type (
 
 // StreamOption is a function type that modifies StreamConfig
 type StreamOption = llm.StreamOption
+
+var (
+	// WithRetryStrategy sets the strategy used to recover from stream interruptions.
+	WithRetryStrategy = llm.WithRetryStrategy
+
+	// WithResumePartial resumes interrupted streams from the text already received.
+	WithResumePartial = llm.WithResumePartial
+)
+
+// Token types reported in StreamToken.Type
+const (
+	TokenTypeText     = llm.TokenTypeText
+	TokenTypeToolCall = llm.TokenTypeToolCall
+	TokenTypeUsage    = llm.TokenTypeUsage
+	TokenTypeFinish   = llm.TokenTypeFinish
+	TokenTypeError    = llm.TokenTypeError
+)
+
+// Stream aggregation helpers
+type (
+	// StreamCallback receives every token read from a stream.
+	StreamCallback = llm.StreamCallback
+
+	// StreamAggregator rebuilds a complete chat response from streamed tokens.
+	StreamAggregator = llm.StreamAggregator
+)
+
+var (
+	// NewStreamAggregator creates an empty StreamAggregator.
+	NewStreamAggregator = llm.NewStreamAggregator
+
+	// CollectStream drains a stream and returns the aggregated ChatResponse.
+	CollectStream = llm.CollectStream
+
+	// TeeStream wraps a stream so every token is also passed to a callback.
+	TeeStream = llm.TeeStream
+)
@@ -0,0 +1,266 @@
+// Package telemetry instruments provider calls with OpenTelemetry traces and metrics
+// following the GenAI semantic conventions. It is opt-in: a nil *Telemetry records nothing.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/YspCoder/omnigo/relay"
+	"github.com/YspCoder/omnigo/utils"
+)
+
+// InstrumentationName identifies the tracer and meter used by omnigo.
+const InstrumentationName = "github.com/YspCoder/omnigo"
+
+// Operations reported in gen_ai.operation.name.
+const (
+	OperationChat   = "chat"
+	OperationStream = "stream"
+	OperationMedia  = "media"
+	OperationTask   = "task"
+)
+
+// Attribute keys from the GenAI and HTTP semantic conventions.
+const (
+	AttrOperationName  = attribute.Key("gen_ai.operation.name")
+	AttrProviderName   = attribute.Key("gen_ai.provider.name")
+	AttrRequestModel   = attribute.Key("gen_ai.request.model")
+	AttrResponseModel  = attribute.Key("gen_ai.response.model")
+	AttrInputTokens    = attribute.Key("gen_ai.usage.input_tokens")
+	AttrOutputTokens   = attribute.Key("gen_ai.usage.output_tokens")
+	AttrFinishReasons  = attribute.Key("gen_ai.response.finish_reasons")
+	AttrTokenType      = attribute.Key("gen_ai.token.type")
+	AttrErrorType      = attribute.Key("error.type")
+	AttrRetryAttempt   = attribute.Key("omnigo.retry.attempt")
+	AttrAttempts       = attribute.Key("omnigo.attempts")
+	AttrHTTPMethod     = attribute.Key("http.request.method")
+	AttrHTTPStatusCode = attribute.Key("http.response.status_code")
+	AttrServerAddress  = attribute.Key("server.address")
+	AttrURLFull        = attribute.Key("url.full")
+)
+
+// Metric names from the GenAI semantic conventions.
+const (
+	MetricOperationDuration = "gen_ai.client.operation.duration"
+	MetricTokenUsage        = "gen_ai.client.token.usage"
+	MetricTimeToFirstChunk  = "gen_ai.client.operation.time_to_first_chunk"
+)
+
+const (
+	errorTypeOther  = "_OTHER"
+	tokenTypeInput  = "input"
+	tokenTypeOutput = "output"
+)
+
+// Histogram bucket boundaries recommended by the GenAI semantic conventions.
+var (
+	durationBuckets = []float64{0.01, 0.02, 0.04, 0.08, 0.16, 0.32, 0.64, 1.28, 2.56, 5.12, 10.24, 20.48, 40.96, 81.92}
+	tokenBuckets    = []float64{1, 4, 16, 64, 256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304, 16777216, 67108864}
+)
+
+// Telemetry records spans and metrics for provider calls.
+type Telemetry struct {
+	tracer           trace.Tracer
+	duration         metric.Float64Histogram
+	timeToFirstToken metric.Float64Histogram
+	tokenUsage       metric.Int64Histogram
+}
+
+// New creates a Telemetry from a tracer provider and a meter provider. A nil provider
+// disables the corresponding signal.
+//
+// Parameters:
+//   - tracerProvider: Source of the tracer; nil records no spans
+//   - meterProvider: Source of the meter; nil records no metrics
+//
+// Returns:
+//   - Configured Telemetry
+//   - An error if a histogram cannot be created
+func New(tracerProvider trace.TracerProvider, meterProvider metric.MeterProvider) (*Telemetry, error) {
+	if tracerProvider == nil {
+		tracerProvider = tracenoop.NewTracerProvider()
+	}
+	if meterProvider == nil {
+		meterProvider = metricnoop.NewMeterProvider()
+	}
+	meter := meterProvider.Meter(InstrumentationName)
+
+	t := &Telemetry{tracer: tracerProvider.Tracer(InstrumentationName)}
+	var err error
+	if t.duration, err = meter.Float64Histogram(MetricOperationDuration,
+		metric.WithDescription("Duration of GenAI client operations"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...)); err != nil {
+		return nil, err
+	}
+	if t.timeToFirstToken, err = meter.Float64Histogram(MetricTimeToFirstChunk,
+		metric.WithDescription("Time until the first chunk of a streamed response"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...)); err != nil {
+		return nil, err
+	}
+	if t.tokenUsage, err = meter.Int64Histogram(MetricTokenUsage,
+		metric.WithDescription("Number of input and output tokens used"),
+		metric.WithUnit("{token}"),
+		metric.WithExplicitBucketBoundaries(tokenBuckets...)); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Result describes the outcome of an operation when its span ends.
+type Result struct {
+	ResponseModel string
+	FinishReasons []string
+	InputTokens   int
+	OutputTokens  int
+	Attempts      int
+	ErrorType     string // Error category (error.type); empty on success
+}
+
+// Span tracks one operation. All methods are safe to call on a nil Span.
+type Span struct {
+	telemetry  *Telemetry
+	span       trace.Span
+	ctx        context.Context
+	start      time.Time
+	attrs      []attribute.KeyValue
+	firstToken sync.Once
+	end        sync.Once
+}
+
+// Start begins the span for an operation, named "{operation} {model}".
+// It returns ctx unchanged and a nil Span when t is nil.
+func (t *Telemetry) Start(ctx context.Context, operation, provider, model string) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	attrs := []attribute.KeyValue{
+		AttrOperationName.String(operation),
+		AttrProviderName.String(provider),
+		AttrRequestModel.String(model),
+	}
+	ctx, span := t.tracer.Start(ctx, operation+" "+model,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+	return ctx, &Span{telemetry: t, span: span, ctx: ctx, start: time.Now(), attrs: attrs}
+}
+
+// RecordRetry adds a retry event for a failed attempt.
+func (s *Span) RecordRetry(attempt int, errorType string, delay time.Duration) {
+	if s == nil {
+		return
+	}
+	s.span.AddEvent("retry", trace.WithAttributes(
+		AttrRetryAttempt.Int(attempt),
+		AttrErrorType.String(errorType),
+		attribute.String("omnigo.retry.delay", delay.String()),
+	))
+}
+
+// RecordFirstToken records the time to the first streamed chunk; later calls are ignored.
+func (s *Span) RecordFirstToken() {
+	if s == nil {
+		return
+	}
+	s.firstToken.Do(func() {
+		s.telemetry.timeToFirstToken.Record(s.ctx, time.Since(s.start).Seconds(), metric.WithAttributes(s.attrs...))
+	})
+}
+
+// End finishes the span and records the duration and token metrics. Only the first
+// call has an effect, so streams may end their span from several places.
+func (s *Span) End(result Result) {
+	if s == nil {
+		return
+	}
+	s.end.Do(func() {
+		metricAttrs := s.attrs
+		if result.ResponseModel != "" {
+			s.span.SetAttributes(AttrResponseModel.String(result.ResponseModel))
+		}
+		if len(result.FinishReasons) > 0 {
+			s.span.SetAttributes(AttrFinishReasons.StringSlice(result.FinishReasons))
+		}
+		if result.Attempts > 0 {
+			s.span.SetAttributes(AttrAttempts.Int(result.Attempts))
+		}
+		if result.InputTokens > 0 || result.OutputTokens > 0 {
+			s.span.SetAttributes(AttrInputTokens.Int(result.InputTokens), AttrOutputTokens.Int(result.OutputTokens))
+		}
+		if result.ErrorType != "" {
+			metricAttrs = with(s.attrs, AttrErrorType.String(result.ErrorType))
+			s.span.SetAttributes(AttrErrorType.String(result.ErrorType))
+			s.span.SetStatus(codes.Error, result.ErrorType)
+		}
+
+		t := s.telemetry
+		t.duration.Record(s.ctx, time.Since(s.start).Seconds(), metric.WithAttributes(metricAttrs...))
+		if result.InputTokens > 0 {
+			t.tokenUsage.Record(s.ctx, int64(result.InputTokens),
+				metric.WithAttributes(with(s.attrs, AttrTokenType.String(tokenTypeInput))...))
+		}
+		if result.OutputTokens > 0 {
+			t.tokenUsage.Record(s.ctx, int64(result.OutputTokens),
+				metric.WithAttributes(with(s.attrs, AttrTokenType.String(tokenTypeOutput))...))
+		}
+		s.span.End()
+	})
+}
+
+// Middleware returns a relay middleware that records a client span for every
+// provider HTTP request, as a child of the operation span in the request context.
+// API keys in the URL and in transport errors are masked before they are exported.
+func (t *Telemetry) Middleware() relay.Middleware {
+	return func(next relay.Handler) relay.Handler {
+		return func(req *relay.Request) (*http.Response, error) {
+			if t == nil {
+				return next(req)
+			}
+			ctx, span := t.tracer.Start(req.HTTP.Context(), req.HTTP.Method,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					AttrHTTPMethod.String(req.HTTP.Method),
+					AttrServerAddress.String(req.HTTP.URL.Hostname()),
+					AttrURLFull.String(utils.RedactURL(req.HTTP.URL)),
+					AttrProviderName.String(req.Config.Name),
+					AttrOperationName.String(req.Mode),
+				))
+			defer span.End()
+			req.HTTP = req.HTTP.WithContext(ctx)
+
+			resp, err := next(req)
+			if err != nil {
+				// Transport errors such as *url.Error quote the request URL.
+				message := utils.RedactString(err.Error())
+				span.RecordError(errors.New(message))
+				span.SetAttributes(AttrErrorType.String(errorTypeOther))
+				span.SetStatus(codes.Error, message)
+				return nil, err
+			}
+			span.SetAttributes(AttrHTTPStatusCode.Int(resp.StatusCode))
+			if resp.StatusCode >= 400 {
+				span.SetAttributes(AttrErrorType.String(strconv.Itoa(resp.StatusCode)))
+				span.SetStatus(codes.Error, resp.Status)
+			}
+			return resp, nil
+		}
+	}
+}
+
+// with returns a copy of attrs extended with extra.
+func with(attrs []attribute.KeyValue, extra ...attribute.KeyValue) []attribute.KeyValue {
+	return append(append(make([]attribute.KeyValue, 0, len(attrs)+len(extra)), attrs...), extra...)
+}
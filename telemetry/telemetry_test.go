@@ -0,0 +1,150 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/YspCoder/omnigo/adapter"
+	"github.com/YspCoder/omnigo/relay"
+)
+
+func newTestTelemetry(t *testing.T) (*Telemetry, *tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	t.Helper()
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	tel, err := New(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)), sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	return tel, spans, reader
+}
+
+func TestSpanRecordsAttributesAndMetrics(t *testing.T) {
+	tel, spans, reader := newTestTelemetry(t)
+
+	_, span := tel.Start(context.Background(), OperationStream, "anthropic", "claude-sonnet-4")
+	span.RecordRetry(1, "RateLimitError", time.Second)
+	span.RecordFirstToken()
+	span.RecordFirstToken()
+	span.End(Result{ResponseModel: "claude-sonnet-4-20250514", FinishReasons: []string{"stop"}, InputTokens: 7, OutputTokens: 2, Attempts: 2})
+	// A stream ends its span on EOF and again on Close; only the first call counts.
+	span.End(Result{ErrorType: "APIError"})
+
+	_, failed := tel.Start(context.Background(), OperationChat, "openai", "gpt-4o")
+	failed.End(Result{Attempts: 1, ErrorType: "AuthenticationError"})
+
+	var nilTelemetry *Telemetry
+	_, nilSpan := nilTelemetry.Start(context.Background(), OperationChat, "openai", "gpt-4o")
+	nilSpan.RecordFirstToken()
+	nilSpan.End(Result{})
+
+	ended := spans.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("expected 2 ended spans, got %d", len(ended))
+	}
+	stream, chat := ended[0], ended[1]
+	if stream.Name() != "stream claude-sonnet-4" || chat.Name() != "chat gpt-4o" {
+		t.Fatalf("unexpected span names %q and %q", stream.Name(), chat.Name())
+	}
+	attrs := attribute.NewSet(stream.Attributes()...)
+	for key, want := range map[attribute.Key]attribute.Value{
+		AttrOperationName: attribute.StringValue(OperationStream),
+		AttrProviderName:  attribute.StringValue("anthropic"),
+		AttrRequestModel:  attribute.StringValue("claude-sonnet-4"),
+		AttrResponseModel: attribute.StringValue("claude-sonnet-4-20250514"),
+		AttrFinishReasons: attribute.StringSliceValue([]string{"stop"}),
+		AttrInputTokens:   attribute.IntValue(7),
+		AttrOutputTokens:  attribute.IntValue(2),
+		AttrAttempts:      attribute.IntValue(2),
+	} {
+		if got, ok := attrs.Value(key); !ok || got != want {
+			t.Fatalf("attribute %s = %v, want %v", key, got.Emit(), want.Emit())
+		}
+	}
+	if _, ok := attrs.Value(AttrErrorType); ok || stream.Status().Code == codes.Error {
+		t.Fatal("expected the second End call to be ignored")
+	}
+	if events := stream.Events(); len(events) != 1 || events[0].Name != "retry" {
+		t.Fatalf("expected one retry event, got %+v", events)
+	}
+	if chat.Status().Code != codes.Error || chat.Status().Description != "AuthenticationError" {
+		t.Fatalf("unexpected error status %+v", chat.Status())
+	}
+
+	var metrics metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &metrics); err != nil {
+		t.Fatalf("Collect returned error: %v", err)
+	}
+	counts := make(map[string]uint64)
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Histogram[float64]:
+				for _, point := range data.DataPoints {
+					counts[m.Name] += point.Count
+				}
+			case metricdata.Histogram[int64]:
+				for _, point := range data.DataPoints {
+					counts[m.Name] += point.Count
+				}
+			}
+		}
+	}
+	// Input and output tokens of the stream span are separate data points.
+	if counts[MetricOperationDuration] != 2 || counts[MetricTimeToFirstChunk] != 1 || counts[MetricTokenUsage] != 2 {
+		t.Fatalf("unexpected histogram counts %v", counts)
+	}
+}
+
+func TestMiddlewareRedactsAPIKey(t *testing.T) {
+	tel, spans, _ := newTestTelemetry(t)
+	const key = "AIzaSyTestKey1234567890abcdef"
+	target := "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:streamGenerateContent?alt=sse&key=" + key
+
+	handler := relay.Chain(func(req *relay.Request) (*http.Response, error) {
+		return nil, &url.Error{Op: "Post", URL: req.HTTP.URL.String(), Err: errors.New("connection reset by peer")}
+	}, tel.Middleware())
+	httpReq, _ := http.NewRequest(http.MethodPost, target, nil)
+	if _, err := handler(&relay.Request{Mode: adapter.ModeChat, Config: &adapter.ProviderConfig{Name: "google"}, HTTP: httpReq}); err == nil {
+		t.Fatal("expected the transport error to be returned")
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("expected 1 HTTP span, got %d", len(ended))
+	}
+	span := ended[0]
+	if span.Status().Code != codes.Error {
+		t.Fatalf("expected error status, got %+v", span.Status())
+	}
+	recorded := []string{span.Status().Description}
+	for _, attr := range span.Attributes() {
+		recorded = append(recorded, attr.Value.Emit())
+	}
+	for _, event := range span.Events() {
+		for _, attr := range event.Attributes {
+			recorded = append(recorded, attr.Value.Emit())
+		}
+	}
+	for _, value := range recorded {
+		if strings.Contains(value, key) {
+			t.Fatalf("API key leaked into the span: %q", value)
+		}
+	}
+	attrs := attribute.NewSet(span.Attributes()...)
+	if got, _ := attrs.Value(AttrURLFull); !strings.Contains(got.AsString(), "alt=sse") {
+		t.Fatalf("expected the rest of the URL to be kept, got %q", got.AsString())
+	}
+}
@@ -0,0 +1,53 @@
+// Package omnigo provides context window management for Language Learning Models.
+// This file re-exports token counting and the strategies that truncate long histories.
+package omnigo
+
+import (
+	"github.com/YspCoder/omnigo/llm"
+	"github.com/YspCoder/omnigo/tokenizer"
+)
+
+// Re-export tokenizer and truncation types
+type (
+	// Tokenizer counts the tokens a model sees for a piece of text.
+	Tokenizer = tokenizer.Tokenizer
+
+	// ApproximateTokenizer estimates token counts without a vocabulary.
+	ApproximateTokenizer = tokenizer.Approximate
+
+	// BPETokenizer counts tokens exactly for OpenAI-style encodings.
+	BPETokenizer = tokenizer.BPE
+
+	// TruncationStrategy shortens Prompt.Messages that exceed the context window.
+	TruncationStrategy = llm.TruncationStrategy
+
+	// TruncationFunc adapts a function to the TruncationStrategy interface.
+	TruncationFunc = llm.TruncationFunc
+)
+
+// Re-export token counting and truncation functions
+var (
+	// LoadBPEFile loads a .tiktoken file for the given encoding, e.g. "o200k_base".
+	LoadBPEFile = tokenizer.LoadBPEFile
+
+	// EncodingForModel returns the OpenAI encoding used by a model, or "" if unknown.
+	EncodingForModel = tokenizer.EncodingForModel
+
+	// CountPromptTokens counts the input tokens of a prompt with the given tokenizer.
+	CountPromptTokens = llm.CountPromptTokens
+
+	// CountMessageTokens counts the tokens of messages including chat format overhead.
+	CountMessageTokens = llm.CountMessageTokens
+
+	// WithTruncation sets the strategy applied when the prompt exceeds the context window.
+	WithTruncation = llm.WithTruncation
+
+	// DropOldest removes the oldest non-system messages until the prompt fits.
+	DropOldest = llm.DropOldest
+
+	// KeepSystemAndLastN keeps the system messages and the last n other messages.
+	KeepSystemAndLastN = llm.KeepSystemAndLastN
+
+	// SummarizeOldest replaces older messages with a summary written by another LLM.
+	SummarizeOldest = llm.SummarizeOldest
+)
@@ -0,0 +1,227 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// splitPattern describes how an encoding splits text into pieces before merging.
+//
+// The upstream patterns end in `\s+(?!\S)|\s+`, which RE2 cannot express. The
+// patterns below end in `\s+` instead, and BPE.pieces gives back the last
+// whitespace character of such a run when the run is followed by other text, which
+// is what the lookahead does.
+type splitPattern struct {
+	pattern *regexp.Regexp
+	// newlineRuns is set when an earlier alternative already claims whitespace runs
+	// containing line breaks, so only runs without them need the adjustment.
+	newlineRuns bool
+}
+
+// whitespace is the Unicode White_Space class matched by `\s` in tiktoken's
+// patterns; RE2's `\s` only covers ASCII spaces. It agrees with unicode.IsSpace.
+const whitespace = `\t\n\v\f\r\x{85}\p{Z}`
+
+// compileSplit compiles an upstream split pattern, with `\s` expanded to whitespace
+// both inside and outside character classes.
+func compileSplit(pattern string) *regexp.Regexp {
+	pattern = strings.ReplaceAll(pattern, `[^\s`, `[^`+whitespace)
+	return regexp.MustCompile(strings.ReplaceAll(pattern, `\s`, `[`+whitespace+`]`))
+}
+
+var splitPatterns = map[string]splitPattern{
+	EncodingO200K: {
+		pattern: compileSplit(`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+			`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+			`|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+`),
+		newlineRuns: true,
+	},
+	EncodingCL100K: {
+		pattern:     compileSplit(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`),
+		newlineRuns: true,
+	},
+	EncodingP50K: {
+		pattern: compileSplit(`'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+`),
+	},
+	EncodingR50K: {
+		pattern: compileSplit(`'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+`),
+	},
+}
+
+// BPE is an exact byte-pair encoding tokenizer for OpenAI-style encodings.
+// Special tokens such as <|endoftext|> are encoded as ordinary text.
+type BPE struct {
+	encoding string
+	ranks    map[string]int
+	split    splitPattern
+}
+
+// LoadBPE reads merge ranks in the .tiktoken format: one base64 token and its rank
+// per line.
+//
+// Parameters:
+//   - r: The rank data
+//   - encoding: Encoding name selecting the split pattern, e.g. EncodingO200K
+//
+// Returns:
+//   - The tokenizer
+//   - An error if the encoding is unknown or the data is malformed
+func LoadBPE(r io.Reader, encoding string) (*BPE, error) {
+	split, ok := splitPatterns[encoding]
+	if !ok {
+		return nil, fmt.Errorf("unknown BPE encoding %q", encoding)
+	}
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		token, rank, found := strings.Cut(text, " ")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected token and rank", line)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		value, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		ranks[string(decoded)] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &BPE{encoding: encoding, ranks: ranks, split: split}, nil
+}
+
+var loadedFiles sync.Map // encoding + "\x00" + path -> *BPE
+
+// LoadBPEFile loads a .tiktoken file such as o200k_base.tiktoken. Files are parsed
+// once per process and the tokenizer is shared afterwards.
+func LoadBPEFile(path, encoding string) (*BPE, error) {
+	key := encoding + "\x00" + path
+	if loaded, ok := loadedFiles.Load(key); ok {
+		return loaded.(*BPE), nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	bpe, err := LoadBPE(file, encoding)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	loaded, _ := loadedFiles.LoadOrStore(key, bpe)
+	return loaded.(*BPE), nil
+}
+
+// Encoding returns the encoding name.
+func (b *BPE) Encoding() string {
+	return b.encoding
+}
+
+// Count returns the number of tokens in text.
+func (b *BPE) Count(text string) int {
+	count := 0
+	for _, piece := range b.pieces(text) {
+		if _, ok := b.ranks[piece]; ok {
+			count++
+			continue
+		}
+		count += len(b.merge([]byte(piece)))
+	}
+	return count
+}
+
+// Encode returns the token ranks for text.
+func (b *BPE) Encode(text string) []int {
+	var tokens []int
+	for _, piece := range b.pieces(text) {
+		if rank, ok := b.ranks[piece]; ok {
+			tokens = append(tokens, rank)
+			continue
+		}
+		data := []byte(piece)
+		for _, part := range b.merge(data) {
+			tokens = append(tokens, b.ranks[string(data[part[0]:part[1]])])
+		}
+	}
+	return tokens
+}
+
+// pieces splits text with the encoding's pattern.
+func (b *BPE) pieces(text string) []string {
+	var pieces []string
+	for len(text) > 0 {
+		loc := b.split.pattern.FindStringIndex(text)
+		if loc == nil {
+			break
+		}
+		end := loc[1]
+		if b.trimWhitespace(text[loc[0]:end], text[end:]) {
+			_, size := utf8.DecodeLastRuneInString(text[:end])
+			end -= size
+		}
+		pieces = append(pieces, text[loc[0]:end])
+		text = text[end:]
+	}
+	return pieces
+}
+
+// trimWhitespace reports whether a whitespace run must give back its last character
+// to the following piece, emulating `\s+(?!\S)`.
+func (b *BPE) trimWhitespace(piece, rest string) bool {
+	if rest == "" || utf8.RuneCountInString(piece) < 2 {
+		return false
+	}
+	if next, _ := utf8.DecodeRuneInString(rest); unicode.IsSpace(next) {
+		return false
+	}
+	for _, r := range piece {
+		if !unicode.IsSpace(r) || (b.split.newlineRuns && (r == '\r' || r == '\n')) {
+			return false
+		}
+	}
+	return true
+}
+
+// merge applies byte-pair merges to piece, lowest rank first, and returns the
+// [start, end) byte ranges of the resulting tokens.
+func (b *BPE) merge(piece []byte) [][2]int {
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for len(bounds) > 2 {
+		best, bestIndex := math.MaxInt, -1
+		for i := 0; i+2 < len(bounds); i++ {
+			if rank, ok := b.ranks[string(piece[bounds[i]:bounds[i+2]])]; ok && rank < best {
+				best, bestIndex = rank, i
+			}
+		}
+		if bestIndex < 0 {
+			break
+		}
+		bounds = append(bounds[:bestIndex+1], bounds[bestIndex+2:]...)
+	}
+	parts := make([][2]int, 0, len(bounds)-1)
+	for i := 0; i+1 < len(bounds); i++ {
+		parts = append(parts, [2]int{bounds[i], bounds[i+1]})
+	}
+	return parts
+}
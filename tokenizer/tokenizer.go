@@ -0,0 +1,78 @@
+// Package tokenizer counts tokens so prompts can be checked against a model's context
+// window before they are sent. Approximate works for any model without extra files;
+// BPE gives exact counts for OpenAI-style encodings loaded from local .tiktoken files.
+package tokenizer
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenizer counts the tokens a model sees for a piece of text.
+// Implementations must be safe for concurrent use.
+type Tokenizer interface {
+	Count(text string) int
+}
+
+// Approximate estimates token counts without a vocabulary: one token per CJK
+// character and about four characters per token for other text. It errs on the
+// high side for English, which is the safe direction for context window checks.
+type Approximate struct{}
+
+// charsPerToken is the average number of non-CJK characters in a token.
+const charsPerToken = 4
+
+// Count estimates the number of tokens in text.
+func (Approximate) Count(text string) int {
+	tokens, chars := 0, 0
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			tokens++
+			continue
+		}
+		chars++
+	}
+	return tokens + (chars+charsPerToken-1)/charsPerToken
+}
+
+// OpenAI encoding names.
+const (
+	EncodingO200K  = "o200k_base"
+	EncodingCL100K = "cl100k_base"
+	EncodingP50K   = "p50k_base"
+	EncodingR50K   = "r50k_base"
+)
+
+// modelEncodings maps model name prefixes to encodings; longer prefixes come first.
+var modelEncodings = []struct {
+	prefix   string
+	encoding string
+}{
+	{"chatgpt-4o", EncodingO200K},
+	{"gpt-4o", EncodingO200K},
+	{"gpt-4.1", EncodingO200K},
+	{"gpt-4.5", EncodingO200K},
+	{"gpt-5", EncodingO200K},
+	{"o1", EncodingO200K},
+	{"o3", EncodingO200K},
+	{"o4", EncodingO200K},
+	{"gpt-4", EncodingCL100K},
+	{"gpt-3.5", EncodingCL100K},
+	{"gpt-35", EncodingCL100K},
+	{"text-embedding-3", EncodingCL100K},
+	{"text-embedding-ada-002", EncodingCL100K},
+	{"text-davinci-003", EncodingP50K},
+	{"text-davinci-002", EncodingP50K},
+	{"davinci", EncodingR50K},
+}
+
+// EncodingForModel returns the OpenAI encoding used by model, or "" if unknown.
+func EncodingForModel(model string) string {
+	model = strings.ToLower(model)
+	for _, entry := range modelEncodings {
+		if strings.HasPrefix(model, entry.prefix) {
+			return entry.encoding
+		}
+	}
+	return ""
+}
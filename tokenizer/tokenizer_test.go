@@ -0,0 +1,82 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBPEMergesByRank(t *testing.T) {
+	var ranks strings.Builder
+	for rank, token := range []string{"a", "b", "c", " ", "ab", "abc"} {
+		fmt.Fprintf(&ranks, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+	}
+	bpe, err := LoadBPE(strings.NewReader(ranks.String()), EncodingCL100K)
+	if err != nil {
+		t.Fatalf("LoadBPE returned error: %v", err)
+	}
+
+	// "abc" is a known token; " abc" merges to " " + "abc".
+	if got := bpe.Encode("abc abc"); !reflect.DeepEqual(got, []int{5, 3, 5}) {
+		t.Fatalf("Encode = %v, want [5 3 5]", got)
+	}
+	// The lookahead rule leaves the last space of a run to the following word.
+	if got := bpe.pieces("ab   c"); !reflect.DeepEqual(got, []string{"ab", "  ", " c"}) {
+		t.Fatalf("pieces = %q", got)
+	}
+	if got := bpe.Count("abc abc"); got != 3 {
+		t.Fatalf("Count = %d, want 3", got)
+	}
+
+	if _, err := LoadBPE(strings.NewReader(ranks.String()), "unknown"); err == nil {
+		t.Fatal("expected an error for an unknown encoding")
+	}
+	if got := (Approximate{}).Count("hello world你好"); got != 5 {
+		t.Fatalf("Approximate.Count = %d, want 5", got)
+	}
+	if got := EncodingForModel("gpt-4o-mini"); got != EncodingO200K {
+		t.Fatalf("EncodingForModel = %q", got)
+	}
+}
+
+func TestBPESplitsUnicodeWhitespace(t *testing.T) {
+	bpe, err := LoadBPE(strings.NewReader(""), EncodingCL100K)
+	if err != nil {
+		t.Fatalf("LoadBPE returned error: %v", err)
+	}
+	// No-break and ideographic spaces are whitespace to tiktoken, so they are not
+	// merged into the punctuation that follows.
+	for text, want := range map[string][]string{
+		"a\u00a0!":          {"a", "\u00a0", "!"},
+		"你好\u3000。":         {"你好", "\u3000", "。"},
+		"x\u00a0\u00a0\v y": {"x", "\u00a0\u00a0\v", " y"},
+	} {
+		if got := bpe.pieces(text); !reflect.DeepEqual(got, want) {
+			t.Fatalf("pieces(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+// TestBPEMatchesTiktoken compares against tiktoken's output for cl100k_base. The
+// rank file is not shipped; set OMNIGO_CL100K_FILE to cl100k_base.tiktoken to run it.
+func TestBPEMatchesTiktoken(t *testing.T) {
+	path := os.Getenv("OMNIGO_CL100K_FILE")
+	if path == "" {
+		t.Skip("OMNIGO_CL100K_FILE not set")
+	}
+	bpe, err := LoadBPEFile(path, EncodingCL100K)
+	if err != nil {
+		t.Fatalf("LoadBPEFile returned error: %v", err)
+	}
+	for text, want := range map[string][]int{
+		"hello world":        {15339, 1917},
+		"tiktoken is great!": {83, 1609, 5963, 374, 2294, 0},
+	} {
+		if got := bpe.Encode(text); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Encode(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// Package omnigo provides typed tool definitions for Language Learning Models.
+// This file contains helpers for declaring tools from Go structs.
+package omnigo
+
+import (
+	"context"
+
+	"github.com/YspCoder/omnigo/llm"
+)
+
+// FunctionTool pairs a tool declaration with the handler that executes it.
+type FunctionTool = llm.FunctionTool
+
+// WithFunctionTools registers typed tools created with NewTool on a Runner.
+var WithFunctionTools = llm.WithFunctionTools
+
+// NewTool creates a typed tool whose JSON parameter schema is derived from T.
+// Arguments produced by the LLM are decoded into T and validated using the
+// struct's validate tags before fn is called.
+//
+// Example usage:
+//
+//	type WeatherArgs struct {
+//	    City string `json:"city" validate:"required"`
+//	}
+//
+//	weather, err := omnigo.NewTool("get_weather", "Get the current weather",
+//	    func(ctx context.Context, args WeatherArgs) (string, error) {
+//	        return "sunny in " + args.City, nil
+//	    })
+//	runner := omnigo.NewRunner(llm, omnigo.WithFunctionTools(weather))
+func NewTool[T any, R any](name, description string, fn func(context.Context, T) (R, error)) (*FunctionTool, error) {
+	return llm.NewTool(name, description, fn)
+}
@@ -43,7 +43,7 @@ func (l *DefaultLogger) SetLevel(level LogLevel) {
 
 func (l *DefaultLogger) log(level LogLevel, msg string, keysAndValues ...interface{}) {
 	if level <= l.level {
-		l.logger.Printf("%s: %s %v", level, msg, keysAndValues)
+		l.logger.Printf("%s: %s %v", level, RedactString(msg), RedactKeysAndValues(keysAndValues))
 	}
 }
 
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces secrets in log output.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are log keys and option names whose values are always redacted.
+var sensitiveKeys = map[string]bool{
+	"api_key":           true,
+	"apikey":            true,
+	"x-api-key":         true,
+	"x-goog-api-key":    true,
+	"authorization":     true,
+	"access_token":      true,
+	"token":             true,
+	"secret":            true,
+	"secret_key":        true,
+	"secret_access_key": true,
+	"password":          true,
+}
+
+// secretPatterns match credentials embedded in free-form strings such as URLs,
+// headers and error messages.
+var secretPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`([?&](?:key|api_key|apikey|access_token|token)=)[^&\s"']+`), "${1}" + Redacted},
+	{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=:\-]+`), "${1}" + Redacted},
+	{regexp.MustCompile(`(?i)(credential=)[^/,\s]+`), "${1}" + Redacted},
+	{regexp.MustCompile(`sk-(?:ant-)?[A-Za-z0-9_\-]{8,}`), Redacted},
+	{regexp.MustCompile(`AIza[0-9A-Za-z_\-]{20,}`), Redacted},
+}
+
+// IsSensitiveKey reports whether values logged under key must be redacted.
+func IsSensitiveKey(key string) bool {
+	return sensitiveKeys[strings.ToLower(strings.TrimSpace(key))]
+}
+
+// RedactString masks API keys, bearer tokens and key query parameters in s.
+func RedactString(s string) string {
+	for _, secret := range secretPatterns {
+		s = secret.pattern.ReplaceAllString(s, secret.replacement)
+	}
+	return s
+}
+
+// RedactURL returns u as a string with its userinfo password and key query
+// parameters masked. Gemini, for example, passes the API key as ?key=.
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return RedactString(u.Redacted())
+}
+
+// RedactValue masks value when key is sensitive; otherwise it masks secrets inside
+// strings, errors and fmt.Stringer values and returns other values unchanged.
+func RedactValue(key string, value interface{}) interface{} {
+	if IsSensitiveKey(key) {
+		return Redacted
+	}
+	switch v := value.(type) {
+	case string:
+		return RedactString(v)
+	case error:
+		if redacted := RedactString(v.Error()); redacted != v.Error() {
+			return redacted
+		}
+	case fmt.Stringer:
+		if redacted := RedactString(v.String()); redacted != v.String() {
+			return redacted
+		}
+	}
+	return value
+}
+
+// RedactKeysAndValues returns a copy of alternating key/value pairs with secrets masked.
+func RedactKeysAndValues(keysAndValues []interface{}) []interface{} {
+	redacted := make([]interface{}, len(keysAndValues))
+	for i := 0; i < len(keysAndValues); i++ {
+		if i%2 == 1 {
+			key, _ := keysAndValues[i-1].(string)
+			redacted[i] = RedactValue(key, keysAndValues[i])
+			continue
+		}
+		redacted[i] = keysAndValues[i]
+		if i == len(keysAndValues)-1 {
+			// A dangling value without a key is still checked for embedded secrets.
+			redacted[i] = RedactValue("", keysAndValues[i])
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+)
+
+// SlogLogger adapts a *slog.Logger to the Logger interface. Messages and values are
+// redacted before they reach the handler, and records below the level are dropped.
+type SlogLogger struct {
+	logger *slog.Logger
+	level  *atomic.Int32
+}
+
+// NewSlogLogger wraps an existing *slog.Logger.
+//
+// Parameters:
+//   - logger: The slog logger records are written to
+//   - level: Minimum level passed on; the handler may filter further
+//
+// Returns:
+//   - A Logger writing through logger
+func NewSlogLogger(logger *slog.Logger, level LogLevel) *SlogLogger {
+	l := &SlogLogger{logger: logger, level: new(atomic.Int32)}
+	l.level.Store(int32(level))
+	return l
+}
+
+// NewJSONLogger creates a Logger writing JSON records to w.
+func NewJSONLogger(w io.Writer, level LogLevel) *SlogLogger {
+	return NewSlogLogger(slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug})), level)
+}
+
+// NewTextLogger creates a Logger writing key=value text records to w.
+func NewTextLogger(w io.Writer, level LogLevel) *SlogLogger {
+	return NewSlogLogger(slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug})), level)
+}
+
+// Slog returns the underlying *slog.Logger.
+func (l *SlogLogger) Slog() *slog.Logger {
+	return l.logger
+}
+
+// With returns a logger that adds the given key-value pairs to every record.
+// The returned logger shares the level of l.
+func (l *SlogLogger) With(keysAndValues ...interface{}) Logger {
+	return &SlogLogger{logger: l.logger.With(RedactKeysAndValues(keysAndValues)...), level: l.level}
+}
+
+func (l *SlogLogger) SetLevel(level LogLevel) {
+	l.level.Store(int32(level))
+}
+
+func (l *SlogLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.log(LogLevelDebug, slog.LevelDebug, msg, keysAndValues)
+}
+
+func (l *SlogLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.log(LogLevelInfo, slog.LevelInfo, msg, keysAndValues)
+}
+
+func (l *SlogLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.log(LogLevelWarn, slog.LevelWarn, msg, keysAndValues)
+}
+
+func (l *SlogLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.log(LogLevelError, slog.LevelError, msg, keysAndValues)
+}
+
+func (l *SlogLogger) log(level LogLevel, slogLevel slog.Level, msg string, keysAndValues []interface{}) {
+	if level > LogLevel(l.level.Load()) {
+		return
+	}
+	l.logger.Log(context.Background(), slogLevel, RedactString(msg), RedactKeysAndValues(keysAndValues)...)
+}
+
+// WithFields returns a logger that adds the given key-value pairs to every record.
+// Loggers providing their own With method (such as SlogLogger) are asked to do so;
+// other loggers are wrapped.
+func WithFields(logger Logger, keysAndValues ...interface{}) Logger {
+	if len(keysAndValues) == 0 {
+		return logger
+	}
+	if withLogger, ok := logger.(interface {
+		With(keysAndValues ...interface{}) Logger
+	}); ok {
+		return withLogger.With(keysAndValues...)
+	}
+	return &fieldLogger{Logger: logger, fields: keysAndValues}
+}
+
+// fieldLogger prepends fixed fields to the key-value pairs of every record.
+type fieldLogger struct {
+	Logger
+	fields []interface{}
+}
+
+func (l *fieldLogger) With(keysAndValues ...interface{}) Logger {
+	return &fieldLogger{Logger: l.Logger, fields: append(append([]interface{}(nil), l.fields...), keysAndValues...)}
+}
+
+func (l *fieldLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.Logger.Debug(msg, l.with(keysAndValues)...)
+}
+
+func (l *fieldLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.Logger.Info(msg, l.with(keysAndValues)...)
+}
+
+func (l *fieldLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.Logger.Warn(msg, l.with(keysAndValues)...)
+}
+
+func (l *fieldLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.Logger.Error(msg, l.with(keysAndValues)...)
+}
+
+func (l *fieldLogger) with(keysAndValues []interface{}) []interface{} {
+	return append(append(make([]interface{}, 0, len(l.fields)+len(keysAndValues)), l.fields...), keysAndValues...)
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJSONLoggerTagsAndRedacts(t *testing.T) {
+	var buf bytes.Buffer
+	logger := WithFields(NewJSONLogger(&buf, LogLevelDebug), "provider", "google", "model", "gemini-2.0-flash")
+	logger = WithFields(logger, "request_id", "abc123", "attempt", 2)
+
+	logger.Warn("Request failed",
+		"api_key", "sk-live-0123456789",
+		"url", "https://generativelanguage.googleapis.com/v1beta/models/gemini:generateContent?key=AIzaSyA1234567890abcdefghij&alt=sse",
+		"error", errors.New(`Post "https://example.com": Authorization: Bearer secret-token`),
+		"total_tokens", 42,
+	)
+	logger.Debug("Option set", "temperature", 0.2)
+	logger.SetLevel(LogLevelWarn)
+	logger.Debug("dropped")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 records, got %d: %s", len(lines), buf.String())
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("invalid JSON record: %v", err)
+	}
+	for key, want := range map[string]interface{}{
+		"provider":     "google",
+		"model":        "gemini-2.0-flash",
+		"request_id":   "abc123",
+		"attempt":      float64(2),
+		"api_key":      Redacted,
+		"url":          "https://generativelanguage.googleapis.com/v1beta/models/gemini:generateContent?key=" + Redacted + "&alt=sse",
+		"error":        `Post "https://example.com": Authorization: Bearer ` + Redacted,
+		"total_tokens": float64(42),
+	} {
+		if record[key] != want {
+			t.Fatalf("%s = %v, want %v", key, record[key], want)
+		}
+	}
+	if strings.Contains(buf.String(), "AIzaSy") || strings.Contains(buf.String(), "secret-token") {
+		t.Fatalf("secret leaked into logs: %s", buf.String())
+	}
+}